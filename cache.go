@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// transactionCache is the -cache file: the transactions parsed from a set of
+// input files, valid while the files and the parse settings are unchanged
+type transactionCache struct {
+	Inputs       []cachedInput
+	Settings     string // Flags that affect parsing, formatted by cacheSettings
+	Transactions []fraud.Transaction
+}
+
+// cachedInput identifies the version of an input file that was parsed
+type cachedInput struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+}
+
+// cacheInputs describes the current version of the input files. Inputs that
+// are not local files, such as stdin and URLs, cannot be cached.
+func cacheInputs(paths []string) ([]cachedInput, error) {
+	inputs := make([]cachedInput, len(paths))
+	for i, path := range paths {
+		if path == "-" || isURL(path) {
+			return nil, fmt.Errorf("%s is not a local file", path)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = cachedInput{Path: path, Size: info.Size(), ModTime: info.ModTime()}
+	}
+	return inputs, nil
+}
+
+// cacheSettings formats the flags that change how input is parsed, so a
+// cache written with other settings is not used
+func cacheSettings(fileType string, gzipped bool, opts fraud.ReadOptions) string {
+	return fmt.Sprintf("%s %t %q %t %q %t %v %q %q %t %q", fileType, gzipped, opts.TimeFormat, opts.SkipInvalid, opts.Delimiter, opts.NoHeader, opts.Columns, opts.DecimalSeparator, opts.ThousandsSeparator, opts.FieldsFromHeader, opts.JSONPath)
+}
+
+// readCache returns the cached transactions when the cache file exists and
+// matches the inputs and settings
+func readCache(filePath string, inputs []cachedInput, settings string) ([]fraud.Transaction, bool) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, false
+	}
+	defer file.Close()
+
+	var cache transactionCache
+	if err := gob.NewDecoder(file).Decode(&cache); err != nil {
+		return nil, false
+	}
+	if cache.Settings != settings || len(cache.Inputs) != len(inputs) {
+		return nil, false
+	}
+	for i, input := range inputs {
+		cached := cache.Inputs[i]
+		if cached.Path != input.Path || cached.Size != input.Size || !cached.ModTime.Equal(input.ModTime) {
+			return nil, false
+		}
+	}
+	return cache.Transactions, true
+}
+
+// writeCache saves the parsed transactions of the inputs to the cache file
+func writeCache(filePath string, inputs []cachedInput, settings string, transactions []fraud.Transaction) error {
+	return writeFileAtomic(filePath, func(w io.Writer) error {
+		return gob.NewEncoder(w).Encode(transactionCache{Inputs: inputs, Settings: settings, Transactions: transactions})
+	})
+}
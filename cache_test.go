@@ -0,0 +1,83 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	input := writeTemp(t, "transactions.csv", testCSV)
+	transactions := readInput(t, input, "csv")
+	cacheFile := filepath.Join(t.TempDir(), "transactions.gob")
+
+	inputs, err := cacheInputs([]string{input})
+	if err != nil {
+		t.Fatalf("cacheInputs: %v", err)
+	}
+	if _, hit := readCache(cacheFile, inputs, "csv"); hit {
+		t.Fatal("readCache hit before the cache was written")
+	}
+	if err := writeCache(cacheFile, inputs, "csv", transactions); err != nil {
+		t.Fatalf("writeCache: %v", err)
+	}
+	cached, hit := readCache(cacheFile, inputs, "csv")
+	if !hit || !reflect.DeepEqual(cached, transactions) {
+		t.Errorf("readCache = %v, %t, want the parsed transactions", cached, hit)
+	}
+
+	// Other parse settings or a changed input make the cache stale
+	if _, hit := readCache(cacheFile, inputs, "json"); hit {
+		t.Error("readCache hit with other settings")
+	}
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(input, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if inputs, err = cacheInputs([]string{input}); err != nil {
+		t.Fatal(err)
+	}
+	if _, hit := readCache(cacheFile, inputs, "csv"); hit {
+		t.Error("readCache hit after the input changed")
+	}
+
+	if _, err := cacheInputs([]string{"-"}); err == nil {
+		t.Error("cacheInputs accepted stdin")
+	}
+}
+
+func TestCachedRunMatchesFreshParse(t *testing.T) {
+	dir := t.TempDir()
+	input := writeTemp(t, "transactions.csv", testCSV)
+	run := func(output string, args ...string) (string, string) {
+		t.Helper()
+		_, stderr, status := runCLI(t, dir, append([]string{"-input", input, "-amount", "1000", "-output", output}, args...)...)
+		if status != 0 {
+			t.Fatalf("%v exited %d: %s", args, status, stderr)
+		}
+		data, err := os.ReadFile(filepath.Join(dir, output))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data), stderr
+	}
+
+	fresh, _ := run("fresh.json")
+	first, stderr := run("first.json", "-cache", "transactions.gob")
+	if strings.Contains(stderr, "loaded from cache") {
+		t.Fatal("first -cache run loaded from a cache that did not exist")
+	}
+	replayed, stderr := run("replayed.json", "-cache", "transactions.gob")
+	if !strings.Contains(stderr, "transactions loaded from cache") {
+		t.Fatalf("second -cache run did not use the cache: %s", stderr)
+	}
+	if first != fresh || replayed != fresh {
+		t.Errorf("cached results differ from a fresh parse:\nfresh:\n%s\nfirst:\n%s\nreplayed:\n%s", fresh, first, replayed)
+	}
+	if !strings.Contains(fresh, `"2"`) {
+		t.Errorf("fresh results do not flag transaction 2:\n%s", fresh)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// checkpoint is the state kept between -checkpoint runs. Transactions can
+// share a timestamp, so the IDs of those at the latest one are kept too, and
+// a transaction arriving later with that timestamp is still reported.
+type checkpoint struct {
+	LastTimestamp time.Time `json:"last_timestamp"`     // Latest transaction analyzed
+	LastIDs       []string  `json:"last_ids,omitempty"` // IDs of the transactions analyzed at LastTimestamp
+}
+
+// seen reports whether tx was analyzed by the run that saved the checkpoint.
+// Checkpoints saved without IDs count every transaction at LastTimestamp as
+// seen.
+func (cp checkpoint) seen(tx fraud.Transaction) bool {
+	if cp.LastTimestamp.IsZero() || tx.Timestamp.After(cp.LastTimestamp) {
+		return false
+	}
+	if tx.Timestamp.Before(cp.LastTimestamp) || cp.LastIDs == nil {
+		return true
+	}
+	return slices.Contains(cp.LastIDs, tx.ID)
+}
+
+// advance records tx as analyzed
+func (cp *checkpoint) advance(tx fraud.Transaction) {
+	switch {
+	case tx.Timestamp.After(cp.LastTimestamp):
+		cp.LastTimestamp = tx.Timestamp
+		cp.LastIDs = []string{tx.ID}
+	case tx.Timestamp.Equal(cp.LastTimestamp) && !slices.Contains(cp.LastIDs, tx.ID):
+		cp.LastIDs = append(cp.LastIDs, tx.ID)
+	}
+}
+
+// after reports whether cp records transactions that earlier does not
+func (cp checkpoint) after(earlier checkpoint) bool {
+	return cp.LastTimestamp.After(earlier.LastTimestamp) || len(cp.LastIDs) > len(earlier.LastIDs)
+}
+
+// readCheckpoint reads the checkpoint file, returning the zero checkpoint
+// when it does not exist yet
+func readCheckpoint(filePath string) (checkpoint, error) {
+	var cp checkpoint
+	data, err := os.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	err = json.Unmarshal(data, &cp)
+	return cp, err
+}
+
+// writeCheckpoint replaces the checkpoint file
+func writeCheckpoint(filePath string, cp checkpoint) error {
+	return writeFileAtomic(filePath, func(w io.Writer) error {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cp)
+	})
+}
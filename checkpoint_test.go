@@ -0,0 +1,129 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestCheckpointSeen(t *testing.T) {
+	last := time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)
+	cp := checkpoint{LastTimestamp: last, LastIDs: []string{"2"}}
+
+	tests := []struct {
+		tx   fraud.Transaction
+		want bool
+	}{
+		{fraud.Transaction{ID: "1", Timestamp: last.Add(-time.Hour)}, true},
+		{fraud.Transaction{ID: "2", Timestamp: last}, true},
+		{fraud.Transaction{ID: "3", Timestamp: last}, false},
+		{fraud.Transaction{ID: "4", Timestamp: last.Add(time.Second)}, false},
+	}
+	for _, tt := range tests {
+		if got := cp.seen(tt.tx); got != tt.want {
+			t.Errorf("seen(%s at %s) = %v, want %v", tt.tx.ID, tt.tx.Timestamp, got, tt.want)
+		}
+	}
+
+	if (checkpoint{}).seen(tests[0].tx) {
+		t.Error("an empty checkpoint has seen a transaction")
+	}
+	legacy := checkpoint{LastTimestamp: last}
+	if !legacy.seen(tests[2].tx) {
+		t.Error("a checkpoint without IDs has not seen a transaction at its timestamp")
+	}
+}
+
+func TestCheckpointAdvance(t *testing.T) {
+	last := time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC)
+	start := checkpoint{LastTimestamp: last, LastIDs: []string{"2"}}
+	cp := checkpoint{LastTimestamp: start.LastTimestamp, LastIDs: slices.Clone(start.LastIDs)}
+
+	cp.advance(fraud.Transaction{ID: "1", Timestamp: last.Add(-time.Hour)})
+	cp.advance(fraud.Transaction{ID: "2", Timestamp: last})
+	if cp.after(start) {
+		t.Errorf("checkpoint %+v advanced on transactions already seen", cp)
+	}
+
+	cp.advance(fraud.Transaction{ID: "3", Timestamp: last})
+	if !cp.after(start) || !slices.Equal(cp.LastIDs, []string{"2", "3"}) {
+		t.Errorf("checkpoint %+v, want IDs 2 and 3 at %s", cp, last)
+	}
+
+	cp.advance(fraud.Transaction{ID: "4", Timestamp: last.Add(time.Minute)})
+	if !cp.LastTimestamp.Equal(last.Add(time.Minute)) || !slices.Equal(cp.LastIDs, []string{"4"}) {
+		t.Errorf("checkpoint %+v, want ID 4 a minute later", cp)
+	}
+}
+
+func TestCheckpointFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	cp, err := readCheckpoint(path)
+	if err != nil || !cp.LastTimestamp.IsZero() {
+		t.Fatalf("readCheckpoint of a missing file = %+v, %v", cp, err)
+	}
+
+	want := checkpoint{LastTimestamp: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC), LastIDs: []string{"2", "3"}}
+	if err := writeCheckpoint(path, want); err != nil {
+		t.Fatalf("writeCheckpoint: %v", err)
+	}
+	got, err := readCheckpoint(path)
+	if err != nil {
+		t.Fatalf("readCheckpoint: %v", err)
+	}
+	if !got.LastTimestamp.Equal(want.LastTimestamp) || !slices.Equal(got.LastIDs, want.LastIDs) {
+		t.Errorf("readCheckpoint = %+v, want %+v", got, want)
+	}
+
+	// Checkpoints saved before IDs were recorded still load
+	if err := os.WriteFile(path, []byte(`{"last_timestamp": "2024-01-01T11:00:00Z"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := readCheckpoint(path); err != nil || got.LastIDs != nil {
+		t.Errorf("readCheckpoint of an old file = %+v, %v", got, err)
+	}
+}
+
+func TestCheckpointTwoRuns(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, "transactions.csv")
+	header := "id,amount,timestamp,account_id,merchant\n"
+	first := header +
+		"1,5000,2024-01-01T10:00:00Z,A,Shop\n" +
+		"2,20,2024-01-01T11:00:00Z,B,Shop\n"
+	// The log grows by a transaction that only flags alongside an earlier one
+	second := first +
+		"3,7000,2024-01-01T12:00:00Z,C,Shop\n" +
+		"4,25,2024-01-01T11:01:00Z,B,Shop\n"
+
+	run := func(content, output string) []string {
+		t.Helper()
+		if err := os.WriteFile(input, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, stderr, status := runCLI(t, dir, "-input", input, "-checkpoint", "state.json", "-output", output, "-quiet", "-window", "5")
+		if status != 0 {
+			t.Fatalf("run exited %d: %s", status, stderr)
+		}
+		results, err := readResultsJSON(filepath.Join(dir, output))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resultIDs(results)
+	}
+
+	if got, want := run(first, "first.json"), []string{"1"}; !slices.Equal(got, want) {
+		t.Errorf("first run flagged %v, want %v", got, want)
+	}
+	// Transaction 2 is within the lookback of 4 but was already reported
+	if got, want := run(second, "second.json"), []string{"4", "3"}; !slices.Equal(got, want) {
+		t.Errorf("second run flagged %v, want %v", got, want)
+	}
+	if got := run(second, "third.json"); len(got) != 0 {
+		t.Errorf("rerun without new data flagged %v", got)
+	}
+}
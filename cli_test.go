@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// cliArgsEnv holds the JSON-encoded arguments when the test binary is rerun
+// as the command by runCLI
+const cliArgsEnv = "FRAUD_DETECTOR_CLI_ARGS"
+
+func TestMain(m *testing.M) {
+	if encoded, ok := os.LookupEnv(cliArgsEnv); ok {
+		var args []string
+		if err := json.Unmarshal([]byte(encoded), &args); err != nil {
+			panic(err)
+		}
+		os.Args = append([]string{"go-frauddetector-cli"}, args...)
+		main()
+		exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runCLI runs the command with args in dir, returning its stdout, stderr,
+// and exit status
+func runCLI(t *testing.T, dir string, args ...string) (stdout, stderr string, status int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0])
+	cmd.Dir = dir
+	encoded, err := json.Marshal(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cmd.Env = append(os.Environ(), cliArgsEnv+"="+string(encoded))
+	var out, errOut bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+
+	err = cmd.Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		status = exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("running %v: %v", args, err)
+	}
+	return out.String(), errOut.String(), status
+}
+
+func TestQuiet(t *testing.T) {
+	dir := t.TempDir()
+	input := writeTemp(t, "transactions.csv", testCSV)
+
+	stdout, stderr, status := runCLI(t, dir, "-input", input, "-output", "results.json")
+	if status != 0 || !strings.Contains(stdout, "Summary:") || !strings.Contains(stderr, `msg="results exported"`) {
+		t.Fatalf("without -quiet: status %d, stdout %q, stderr %q", status, stdout, stderr)
+	}
+
+	stdout, stderr, status = runCLI(t, dir, "-input", input, "-output", "quiet.json", "-quiet")
+	if status != 0 {
+		t.Fatalf("-quiet exited %d: %s", status, stderr)
+	}
+	if stdout != "" {
+		t.Errorf("-quiet wrote %q to stdout", stdout)
+	}
+	if stderr != "" {
+		t.Errorf("-quiet wrote %q to stderr", stderr)
+	}
+	if _, err := os.Stat(dir + "/quiet.json"); err != nil {
+		t.Errorf("-quiet did not export: %v", err)
+	}
+
+	// Errors still reach stderr
+	_, stderr, status = runCLI(t, dir, "-input", "missing.csv", "-quiet")
+	if status != 1 || !strings.Contains(stderr, "missing.csv") {
+		t.Errorf("missing input with -quiet: status %d, stderr %q", status, stderr)
+	}
+}
+
+func TestMaxTransactions(t *testing.T) {
+	dir := t.TempDir()
+	input := writeTemp(t, "transactions.csv", testCSV)
+
+	for _, args := range [][]string{{"-input", input}, {"-input", input, "-stream"}} {
+		_, stderr, status := runCLI(t, dir, append(args, "-max-transactions", "2")...)
+		if status != 1 || !strings.Contains(stderr, "too many transactions: more than 2 (-max-transactions)") {
+			t.Errorf("%v over the limit: status %d, stderr %q", args, status, stderr)
+		}
+
+		_, stderr, status = runCLI(t, dir, append(args, "-max-transactions", "3")...)
+		if status != 0 {
+			t.Errorf("%v at the limit: status %d, stderr %q", args, status, stderr)
+		}
+	}
+}
+
+func TestAnyMatch(t *testing.T) {
+	dir := t.TempDir()
+	input := writeTemp(t, "transactions.csv", testCSV)
+
+	for _, args := range [][]string{{"-input", input}, {"-input", input, "-stream"}} {
+		stdout, stderr, status := runCLI(t, dir, append(args, "-any", "-rules", "high-amount", "-amount", "1000")...)
+		want := "Fraud detected: transaction 2 of account A: High amount: $2,500.00\n"
+		if status != exitFraudDetected || stdout != want {
+			t.Errorf("%v -any with a match: status %d, stdout %q, want %d and %q (stderr %q)", args, status, stdout, exitFraudDetected, want, stderr)
+		}
+
+		stdout, stderr, status = runCLI(t, dir, append(args, "-any", "-rules", "high-amount", "-amount", "5000")...)
+		if status != 0 || stdout != "No fraudulent transactions detected.\n" {
+			t.Errorf("%v -any without a match: status %d, stdout %q (stderr %q)", args, status, stdout, stderr)
+		}
+	}
+}
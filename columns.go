@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// outputColumns are the fields -columns can select, in their default order
+var outputColumns = []string{"id", "account_id", "merchant", "amount", "currency", "timestamp", "category", "mcc", "device_id", "card_id", "country", "severity", "score", "reason"}
+
+// Columns shown when -columns is not set. CSV exports start with their
+// original columns, so tools reading them by position keep working, and add
+// later ones after them.
+var (
+	defaultTableColumns = []string{"id", "account_id", "merchant", "amount", "timestamp", "severity", "reason"}
+	defaultCSVColumns   = []string{"id", "account_id", "merchant", "amount", "timestamp", "reason", "currency", "severity"}
+)
+
+// columnTitles are the terminal table headers of the output columns
+var columnTitles = map[string]string{
+	"id":         "ID",
+	"account_id": "Account",
+	"merchant":   "Merchant",
+	"amount":     "Amount",
+	"currency":   "Currency",
+	"timestamp":  "Timestamp",
+	"category":   "Category",
+	"mcc":        "MCC",
+	"device_id":  "Device",
+	"card_id":    "Card",
+	"country":    "Country",
+	"severity":   "Severity",
+	"score":      "Score",
+	"reason":     "Reason",
+}
+
+// parseOutputColumns parses a comma-separated list of output column names
+func parseOutputColumns(value string) ([]string, error) {
+	var columns []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !slices.Contains(outputColumns, name) {
+			return nil, fmt.Errorf("unknown column %q (use %s)", name, strings.Join(outputColumns, ", "))
+		}
+		columns = append(columns, name)
+	}
+	return columns, nil
+}
+
+// columnValue formats one column of a result. Table amounts carry their
+// currency symbol; other amounts are plain numbers with the decimals of
+// their currency.
+func columnValue(column string, result fraud.FraudResult, config fraud.Config, table bool) string {
+	tx := result.Transaction
+	switch column {
+	case "id":
+		return tx.ID
+	case "account_id":
+		return tx.AccountID
+	case "merchant":
+		return tx.Merchant
+	case "amount":
+		currency := config.CurrencyOf(tx)
+		if table {
+			return config.FormatAmount(currency, tx.Amount)
+		}
+		return strconv.FormatFloat(tx.Amount, 'f', currency.Decimals, 64)
+	case "currency":
+		return config.CurrencyOf(tx).Code
+	case "timestamp":
+		return tx.Timestamp.Format(time.RFC3339)
+	case "category":
+		return tx.Category
+	case "mcc":
+		return tx.MCC
+	case "device_id":
+		return tx.DeviceID
+	case "card_id":
+		return tx.CardID
+	case "country":
+		return tx.Country
+	case "severity":
+		return result.Severity.String()
+	case "score":
+		return strconv.FormatFloat(result.Score, 'f', -1, 64)
+	case "reason":
+		return result.Reason
+	}
+	return ""
+}
+
+// writeColumnsJSON writes the fraud results as an indented JSON array of
+// objects holding only the given columns, in that order. Amounts and scores are
+// numbers, as in the default JSON export.
+func writeColumnsJSON(w io.Writer, results []fraud.FraudResult, config fraud.Config, columns []string) error {
+	bw := bufio.NewWriter(w)
+	if len(results) == 0 {
+		bw.WriteString("[]\n")
+		return bw.Flush()
+	}
+
+	bw.WriteString("[\n")
+	for i, result := range results {
+		bw.WriteString("  {")
+		for j, column := range columns {
+			var value any
+			switch column {
+			case "amount":
+				value = result.Transaction.Amount
+			case "score":
+				value = result.Score
+			default:
+				value = columnValue(column, result, config, false)
+			}
+			data, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+			if j > 0 {
+				bw.WriteByte(',')
+			}
+			fmt.Fprintf(bw, "\n    %q: %s", column, data)
+		}
+		bw.WriteString("\n  }")
+		if i < len(results)-1 {
+			bw.WriteByte(',')
+		}
+		bw.WriteByte('\n')
+	}
+	bw.WriteString("]\n")
+	return bw.Flush()
+}
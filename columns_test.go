@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestParseOutputColumns(t *testing.T) {
+	columns, err := parseOutputColumns("ID, amount,reason")
+	if err != nil {
+		t.Fatalf("parseOutputColumns: %v", err)
+	}
+	if want := []string{"id", "amount", "reason"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("columns = %q, want %q", columns, want)
+	}
+
+	if _, err := parseOutputColumns("id,amount,notes"); err == nil || !strings.Contains(err.Error(), `unknown column "notes"`) {
+		t.Errorf("parseOutputColumns error = %v, want one naming the unknown column", err)
+	}
+}
+
+func TestExportReducedColumns(t *testing.T) {
+	results := flaggedResults(2)
+	results[1].Transaction.Currency = "JPY"
+	columns := []string{"id", "amount", "reason"}
+
+	var buf bytes.Buffer
+	if err := writeColumnsJSON(&buf, results, fraud.Config{}, columns); err != nil {
+		t.Fatalf("writeColumnsJSON: %v", err)
+	}
+	want := `[
+  {
+    "id": "tx-0",
+    "amount": 1000,
+    "reason": "High amount: $1000.00"
+  },
+  {
+    "id": "tx-1",
+    "amount": 1001,
+    "reason": "High amount: $1000.00"
+  }
+]
+`
+	if buf.String() != want {
+		t.Errorf("JSON =\n%s\nwant\n%s", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := writeResultsCSV(&buf, results, fraud.Config{}, columns); err != nil {
+		t.Fatalf("writeResultsCSV: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantRecords := [][]string{
+		{"id", "amount", "reason"},
+		{"tx-0", "1000.00", "High amount: $1000.00"},
+		{"tx-1", "1001", "High amount: $1000.00"},
+	}
+	if !reflect.DeepEqual(records, wantRecords) {
+		t.Errorf("CSV records = %q, want %q", records, wantRecords)
+	}
+
+	buf.Reset()
+	if err := writeColumnsJSON(&buf, results, fraud.Config{}, []string{"id", "amount", "score", "severity"}); err != nil {
+		t.Fatalf("writeColumnsJSON: %v", err)
+	}
+	var objects []map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &objects); err != nil {
+		t.Fatal(err)
+	}
+	for _, object := range objects {
+		if _, ok := object["amount"].(float64); !ok {
+			t.Errorf("amount is %T, want a number", object["amount"])
+		}
+		if score, ok := object["score"].(float64); !ok || score != 50 {
+			t.Errorf("score is %T %v, want the number 50", object["score"], object["score"])
+		}
+		if _, ok := object["severity"].(string); !ok {
+			t.Errorf("severity is %T, want a string", object["severity"])
+		}
+	}
+
+	buf.Reset()
+	if err := writeColumnsJSON(&buf, nil, fraud.Config{}, columns); err != nil || buf.String() != "[]\n" {
+		t.Errorf("no results: %q, %v; want an empty array", buf.String(), err)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go-frauddetector-cli/pkg/fraud"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// loadConfigFile applies settings from a YAML, JSON, or TOML file to the
+// command line flags. Keys are flag names, e.g. "amount" or
+// "velocity-window". Flags given explicitly on the command line take
+// precedence over the file.
+func loadConfigFile(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+
+	values := make(map[string]any)
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		err = decoder.Decode(&values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		err = yaml.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return fmt.Errorf("parsing %s: %v", filePath, err)
+	}
+
+	explicit := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for name, value := range values {
+		f := flag.Lookup(name)
+		if f == nil {
+			return fmt.Errorf("unknown setting %q in %s", name, filePath)
+		}
+		// An empty value of a flag without a default leaves it unset, so
+		// repeatable flags such as header can be listed empty
+		arg := configValue(value)
+		if explicit[name] || (arg == "" && f.DefValue == "") {
+			continue
+		}
+		if err := flag.Set(name, arg); err != nil {
+			return fmt.Errorf("invalid value for %q in %s: %v", name, filePath, err)
+		}
+	}
+
+	return nil
+}
+
+// configValue formats a decoded config value as a flag argument. Lists are
+// joined with commas, and maps become comma-separated key=value pairs.
+func configValue(value any) string {
+	switch value := value.(type) {
+	case []any:
+		items := make([]string, len(value))
+		for i, item := range value {
+			items[i] = configValue(item)
+		}
+		return strings.Join(items, ",")
+	case map[string]any:
+		items := make([]string, 0, len(value))
+		for key, item := range value {
+			items = append(items, key+"="+configValue(item))
+		}
+		sort.Strings(items)
+		return strings.Join(items, ",")
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// configFlags are the flags that set each fraud.Config field Validate checks
+var configFlags = map[string]string{
+	"HighAmountThreshold":  "amount",
+	"CategoryThresholds":   "category-thresholds",
+	"AccountThresholds":    "account-thresholds",
+	"TimeWindow":           "window",
+	"RapidEscalation":      "rapid-escalation",
+	"VelocityCount":        "velocity-count",
+	"VelocityWindow":       "velocity-window",
+	"DuplicateWindow":      "duplicate-window",
+	"BatchSize":            "batch-size",
+	"StreamBuffer":         "stream-buffer",
+	"Concurrency":          "concurrency",
+	"OffHoursStart":        "offhours-start",
+	"OffHoursEnd":          "offhours-end",
+	"RoundMultiple":        "round-multiple",
+	"RoundMin":             "round-min",
+	"DailyLimit":           "daily-limit",
+	"MaxSpeedKmh":          "max-speed-kmh",
+	"ZScore":               "zscore",
+	"MinSamples":           "min-samples",
+	"HistoryMin":           "history-min",
+	"StructuringLimit":     "structuring-threshold",
+	"StructuringBand":      "structuring-band",
+	"StructuringCount":     "structuring-count",
+	"NewAccountCount":      "newacct-count",
+	"NewAccountWindow":     "newacct-window",
+	"DistinctMerchants":    "distinct-merchants",
+	"MerchantWindow":       "merchant-window",
+	"MaxCards":             "max-cards",
+	"CardWindow":           "card-window",
+	"MaxAccountsPerDevice": "max-accounts-per-device",
+	"RuleWeights":          "risk-weights",
+	"RiskThreshold":        "risk-threshold",
+	"MinSeverity":          "min-severity",
+	"Currency":             "currency",
+}
+
+// configErrors splits an error from fraud.Config.Validate into one error per
+// invalid field, naming the flag that sets it rather than the Config field
+func configErrors(err error) []error {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+
+	for i, err := range errs {
+		var fieldErr *fraud.FieldError
+		if !errors.As(err, &fieldErr) {
+			continue
+		}
+		name, ok := configFlags[fieldErr.Field]
+		if !ok {
+			continue
+		}
+		if fieldErr.Key != "" {
+			errs[i] = fmt.Errorf("-%s entry %q %s", name, fieldErr.Key, fieldErr.Problem)
+		} else {
+			errs[i] = fmt.Errorf("-%s %s", name, fieldErr.Problem)
+		}
+	}
+	return errs
+}
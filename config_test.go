@@ -0,0 +1,101 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"strings"
+	"testing"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// useFlags replaces the command line flag set with a fresh one for the rest
+// of the test
+func useFlags(t *testing.T) *flag.FlagSet {
+	t.Helper()
+	saved := flag.CommandLine
+	t.Cleanup(func() { flag.CommandLine = saved })
+	flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+	return flag.CommandLine
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	files := map[string]string{
+		"config.yaml": "amount: 5000\nvelocity-count: 7\nvelocity-window: 15m\nblocklist: [casino, crypto]\n",
+		"config.json": `{"amount": 5000, "velocity-count": 7, "velocity-window": "15m", "blocklist": ["casino", "crypto"]}`,
+		"config.toml": "amount = 5000\nvelocity-count = 7\nvelocity-window = \"15m\"\nblocklist = [\"casino\", \"crypto\"]\n",
+	}
+	for name, content := range files {
+		flags := useFlags(t)
+		amount := flags.Float64("amount", 1000, "")
+		velocityCount := flags.Int("velocity-count", 5, "")
+		velocityWindow := flags.Duration("velocity-window", time.Hour, "")
+		blocklist := flags.String("blocklist", "", "")
+		if err := flags.Parse([]string{"-velocity-count", "3"}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := loadConfigFile(writeTemp(t, name, content)); err != nil {
+			t.Fatalf("%s: loadConfigFile: %v", name, err)
+		}
+		if *amount != 5000 {
+			t.Errorf("%s: amount = %v, want 5000", name, *amount)
+		}
+		if *velocityWindow != 15*time.Minute {
+			t.Errorf("%s: velocity-window = %v, want 15m", name, *velocityWindow)
+		}
+		if *blocklist != "casino,crypto" {
+			t.Errorf("%s: blocklist = %q, want %q", name, *blocklist, "casino,crypto")
+		}
+		// The command line takes precedence over the file
+		if *velocityCount != 3 {
+			t.Errorf("%s: velocity-count = %v, want the command line's 3", name, *velocityCount)
+		}
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"unknown.yaml", "amont: 5000\n", `unknown setting "amont"`},
+		{"invalid.yaml", "amount: lots\n", `invalid value for "amount"`},
+		{"malformed.json", `{"amount": `, "parsing"},
+		{"malformed.toml", "amount = = 5000\n", "parsing"},
+	}
+	for _, tt := range tests {
+		flags := useFlags(t)
+		flags.Float64("amount", 1000, "")
+		err := loadConfigFile(writeTemp(t, tt.name, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v, want one containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestConfigErrorsNameFlags(t *testing.T) {
+	config := fraud.Config{
+		HighAmountThreshold: -100,
+		TimeWindow:          -5 * time.Minute,
+		CategoryThresholds:  map[string]float64{"atm": -1},
+	}
+	var got []string
+	for _, err := range configErrors(config.Validate()) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		"-amount must not be negative (got -100)",
+		`-category-thresholds entry "atm" must not be negative (got -1)`,
+		"-window must be positive (got -5m0s)",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("configErrors =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Errors other than a joined list of field errors are kept whole
+	plain := errors.New("bad config")
+	if errs := configErrors(plain); len(errs) != 1 || errs[0] != plain {
+		t.Errorf("configErrors(plain) = %v, want [%v]", errs, plain)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// resultDiff is what changed between the results of two runs, matching
+// results by transaction ID
+type resultDiff struct {
+	Added     []fraud.FraudResult // Flagged only by the new run
+	Removed   []fraud.FraudResult // Flagged only by the old run
+	Changed   []reasonChange      // Flagged by both, for different reasons
+	Unchanged int
+}
+
+// reasonChange is a transaction flagged by both runs for different reasons
+type reasonChange struct {
+	Old, New fraud.FraudResult
+}
+
+// diffResults compares the results of an old and a new run. Each list keeps
+// the order of the results it comes from.
+func diffResults(old, new []fraud.FraudResult) resultDiff {
+	var diff resultDiff
+	before := make(map[string]fraud.FraudResult, len(old))
+	for _, result := range old {
+		before[result.Transaction.ID] = result
+	}
+	after := make(map[string]bool, len(new))
+	for _, result := range new {
+		after[result.Transaction.ID] = true
+	}
+
+	for _, result := range new {
+		prev, ok := before[result.Transaction.ID]
+		switch {
+		case !ok:
+			diff.Added = append(diff.Added, result)
+		case prev.Reason != result.Reason:
+			diff.Changed = append(diff.Changed, reasonChange{Old: prev, New: result})
+		default:
+			diff.Unchanged++
+		}
+	}
+	for _, result := range old {
+		if !after[result.Transaction.ID] {
+			diff.Removed = append(diff.Removed, result)
+		}
+	}
+	return diff
+}
+
+// readDiffResults reads a JSON results file for -diff, which unlike
+// -append requires the file to exist
+func readDiffResults(filePath string) ([]fraud.FraudResult, error) {
+	if _, err := os.Stat(filePath); err != nil {
+		return nil, err
+	}
+	return readResultsJSON(filePath)
+}
+
+// printDiff writes a report of the changes between two runs' results
+func printDiff(w io.Writer, diff resultDiff, config fraud.Config) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	line := func(mark string, result fraud.FraudResult, reason string) {
+		fmt.Fprintf(tw, "  %s %s\t%s\t%s\t%s\n", mark, result.Transaction.ID, result.Transaction.AccountID, columnValue("amount", result, config, true), reason)
+	}
+
+	if len(diff.Added) > 0 {
+		fmt.Fprintf(tw, "Newly flagged (%d):\n", len(diff.Added))
+		for _, result := range diff.Added {
+			line("+", result, result.Reason)
+		}
+	}
+	if len(diff.Removed) > 0 {
+		fmt.Fprintf(tw, "No longer flagged (%d):\n", len(diff.Removed))
+		for _, result := range diff.Removed {
+			line("-", result, result.Reason)
+		}
+	}
+	if len(diff.Changed) > 0 {
+		fmt.Fprintf(tw, "Changed reasons (%d):\n", len(diff.Changed))
+		for _, change := range diff.Changed {
+			line("~", change.New, "was: "+change.Old.Reason)
+			fmt.Fprintf(tw, "  \t\t\tnow: %s\n", change.New.Reason)
+		}
+	}
+	tw.Flush()
+
+	fmt.Fprintf(w, "%d newly flagged, %d no longer flagged, %d changed, %d unchanged\n", len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged)
+}
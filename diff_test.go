@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestDiffResults(t *testing.T) {
+	// The runs share tx-2 and tx-3, and tx-3's reason changed
+	old := flaggedResults(4)
+	new := flaggedResults(6)[2:]
+	new[1].Reason = "High amount: $1000.00; Off-hours transaction at 03:14"
+
+	diff := diffResults(old, new)
+	if got, want := resultIDs(diff.Added), []string{"tx-4", "tx-5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("added %v, want %v", got, want)
+	}
+	if got, want := resultIDs(diff.Removed), []string{"tx-0", "tx-1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("removed %v, want %v", got, want)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Old.Reason != old[3].Reason || diff.Changed[0].New.Reason != new[1].Reason {
+		t.Errorf("changed %+v, want tx-3's reason change", diff.Changed)
+	}
+	if diff.Unchanged != 1 {
+		t.Errorf("unchanged = %d, want 1", diff.Unchanged)
+	}
+}
+
+func TestDiffFiles(t *testing.T) {
+	dir := t.TempDir()
+	old, new := filepath.Join(dir, "old.json"), filepath.Join(dir, "new.json")
+	if err := exportResults(flaggedResults(2), fraud.Config{}, 2, old, "", false, nil, false, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := exportResults(flaggedResults(3)[1:], fraud.Config{}, 3, new, "", false, nil, false, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	stdout, stderr, status := runCLI(t, dir, "-diff", "old.json", "new.json")
+	if status != 0 {
+		t.Fatalf("-diff exited %d: %s", status, stderr)
+	}
+	for _, want := range []string{
+		"Newly flagged (1):\n  + tx-2",
+		"No longer flagged (1):\n  - tx-0",
+		"1 newly flagged, 1 no longer flagged, 0 changed, 1 unchanged\n",
+	} {
+		if !strings.Contains(stdout, want) {
+			t.Errorf("-diff output has no %q:\n%s", want, stdout)
+		}
+	}
+
+	if _, err := readDiffResults(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("readDiffResults of a missing file: error = %v, want not exist", err)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// writeExplanations writes, for each transaction, every rule that was
+// checked and whether it flagged the transaction, with the fields in
+// redactions masked
+func writeExplanations(w io.Writer, explanations []fraud.Explanation, config fraud.Config, redactions *redactor) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, explanation := range explanations {
+		tx := explanation.Transaction
+		fmt.Fprintf(tw, "Transaction %s (account %s, %s at %s, %s)\n",
+			tx.ID, redactions.shownAccount(tx.AccountID), config.CurrencyOf(tx).Format(tx.Amount),
+			redactions.shownMerchant(tx.Merchant), tx.Timestamp.Format(time.RFC3339))
+		if explanation.Whitelisted {
+			fmt.Fprintln(tw, "  whitelisted, no rules checked")
+			continue
+		}
+		for _, check := range explanation.Checks {
+			outcome := "pass"
+			if check.Flagged {
+				outcome = "FLAG"
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%s\n", check.Rule, outcome, redactions.reason(check.Detail, tx.Merchant))
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestWriteExplanations(t *testing.T) {
+	rules, err := fraud.LookupRules([]string{"high-amount", "rapid-succession"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions := []fraud.Transaction{fraud.Transaction{ID: "1", Amount: 1500, Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), AccountID: "A", Merchant: "Shop"}}
+	config := fraud.Config{
+		HighAmountThreshold: 1000,
+		TimeWindow:          5 * time.Minute,
+		Rules:               rules,
+	}
+
+	var buf bytes.Buffer
+	if err := writeExplanations(&buf, fraud.Explain(transactions, config), config, nil); err != nil {
+		t.Fatalf("writeExplanations: %v", err)
+	}
+	want := `Transaction 1 (account A, $1500.00 at Shop, 2024-01-01T10:00:00Z)
+  high-amount       FLAG  High amount: $1500.00
+  rapid-succession  pass  no other transaction within 5m
+`
+	if buf.String() != want {
+		t.Errorf("explanation =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteExplanationsWhitelisted(t *testing.T) {
+	transactions := []fraud.Transaction{fraud.Transaction{ID: "1", Amount: 1500, Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), AccountID: "A", Merchant: "Shop"}}
+	config := fraud.Config{HighAmountThreshold: 1000, Whitelist: map[string]bool{"a": true}}
+
+	var buf bytes.Buffer
+	if err := writeExplanations(&buf, fraud.Explain(transactions, config), config, nil); err != nil {
+		t.Fatalf("writeExplanations: %v", err)
+	}
+	want := `Transaction 1 (account A, $1500.00 at Shop, 2024-01-01T10:00:00Z)
+  whitelisted, no rules checked
+`
+	if buf.String() != want {
+		t.Errorf("explanation =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteExplanationsRedacted(t *testing.T) {
+	rules, err := fraud.LookupRules([]string{"high-amount", "duplicate", "blocklist"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transactions := []fraud.Transaction{
+		{ID: "1", Amount: 1500, Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), AccountID: "A0001234", Merchant: "Corner Shop"},
+	}
+	config := fraud.Config{
+		HighAmountThreshold: 1000,
+		DuplicateWindow:     time.Minute,
+		MerchantBlocklist:   map[string]bool{"casino": true},
+		Rules:               rules,
+	}
+
+	var buf bytes.Buffer
+	redactions := &redactor{account: true, merchant: true}
+	if err := writeExplanations(&buf, fraud.Explain(transactions, config), config, redactions); err != nil {
+		t.Fatalf("writeExplanations: %v", err)
+	}
+	want := `Transaction 1 (account ****1234, $1500.00 at *******Shop, 2024-01-01T10:00:00Z)
+  high-amount  FLAG  High amount: $1500.00
+  duplicate    pass  no same amount at *******Shop within 1m
+  blocklist    pass  merchant *******Shop not blocklisted
+`
+	if buf.String() != want {
+		t.Errorf("explanation =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// exportResults writes the fraud results to a file. An empty format is
+// inferred from the file extension, defaulting to JSON. With appendExisting,
+// results already in a JSON file are kept and the new ones added. Columns,
+// when set, limits JSON and CSV output to those fields. With grouped, JSON
+// output maps each account ID to its results.
+func exportResults(results []fraud.FraudResult, config fraud.Config, scanned int, filePath, format string, appendExisting bool, columns []string, grouped bool, redactions *redactor) error {
+	if format == "" {
+		format = formatForExtension(filePath)
+	}
+
+	// Fields are redacted in what is written, while grouping and the report
+	// summary use the real values
+	shown := redactions.results(results)
+	if appendExisting {
+		if !strings.EqualFold(format, "json") {
+			return fmt.Errorf("appending requires json output, not %s", format)
+		}
+		existing, err := readResultsJSON(filePath)
+		if err != nil {
+			return err
+		}
+		shown = appendResults(existing, shown)
+	}
+
+	var write func(io.Writer, []fraud.FraudResult, fraud.Config) error
+	switch strings.ToLower(format) {
+	case "json":
+		write = writeResultsJSON
+		if grouped {
+			write = func(w io.Writer, _ []fraud.FraudResult, config fraud.Config) error {
+				return writeGroupedJSON(w, results, config, redactions)
+			}
+		} else if len(columns) > 0 {
+			write = func(w io.Writer, results []fraud.FraudResult, config fraud.Config) error {
+				return writeColumnsJSON(w, results, config, columns)
+			}
+		}
+	case "csv":
+		write = func(w io.Writer, results []fraud.FraudResult, config fraud.Config) error {
+			return writeResultsCSV(w, results, config, columns)
+		}
+	case "md", "markdown":
+		write = writeResultsMarkdown
+	case "parquet":
+		write = writeResultsParquet
+	case "html":
+		generated := time.Now()
+		write = func(w io.Writer, _ []fraud.FraudResult, config fraud.Config) error {
+			return writeResultsHTML(w, results, config, scanned, generated, redactions)
+		}
+	default:
+		return fmt.Errorf("unsupported output format: %s", format)
+	}
+
+	return writeFileAtomic(filePath, func(w io.Writer) error {
+		return write(w, shown, config)
+	})
+}
+
+// readResultsJSON reads results previously exported as JSON, returning none
+// when the file does not exist yet
+func readResultsJSON(filePath string) ([]fraud.FraudResult, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var results []fraud.FraudResult
+	if err := json.NewDecoder(file).Decode(&results); err != nil {
+		return nil, fmt.Errorf("reading existing results: %v", err)
+	}
+	return results, nil
+}
+
+// appendResults adds the results not already in existing, matched by
+// transaction ID and reason, after the existing ones
+func appendResults(existing, results []fraud.FraudResult) []fraud.FraudResult {
+	type key struct{ id, reason string }
+	seen := make(map[key]bool, len(existing))
+	for _, result := range existing {
+		seen[key{result.Transaction.ID, result.Reason}] = true
+	}
+
+	for _, result := range results {
+		k := key{result.Transaction.ID, result.Reason}
+		if !seen[k] {
+			seen[k] = true
+			existing = append(existing, result)
+		}
+	}
+	return existing
+}
+
+// formatForExtension infers the export format from the output file
+// extension, defaulting to JSON
+func formatForExtension(filePath string) string {
+	switch strings.ToLower(filepath.Ext(filePath)) {
+	case ".csv":
+		return "csv"
+	case ".md", ".markdown":
+		return "md"
+	case ".html", ".htm":
+		return "html"
+	case ".parquet":
+		return "parquet"
+	default:
+		return "json"
+	}
+}
+
+// writeFileAtomic writes a file by calling write on a temporary file in the
+// same directory and renaming it into place once it is complete, so readers
+// never see a partially written file
+func writeFileAtomic(filePath string, write func(io.Writer) error) error {
+	dir, base := filepath.Split(filePath)
+	if dir == "" {
+		dir = "."
+	}
+
+	file, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := file.Name()
+	defer os.Remove(tmpPath)
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Chmod(0o644); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, filePath)
+}
+
+// writeResultsJSON writes the fraud results as an indented JSON array. The
+// results are complete before export, since merging and sorting need all of
+// them, but they are encoded one at a time, so the encoded document is never
+// held in memory alongside them.
+func writeResultsJSON(w io.Writer, results []fraud.FraudResult, config fraud.Config) error {
+	bw := bufio.NewWriter(w)
+	if len(results) == 0 {
+		bw.WriteString("[]\n")
+		return bw.Flush()
+	}
+
+	bw.WriteString("[\n")
+	for i, result := range results {
+		data, err := json.MarshalIndent(result, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		bw.WriteString("  ")
+		bw.Write(data)
+		if i < len(results)-1 {
+			bw.WriteByte(',')
+		}
+		bw.WriteByte('\n')
+	}
+	bw.WriteString("]\n")
+	return bw.Flush()
+}
+
+// writeResultsCSV writes the fraud results as CSV with a header row, using
+// the default CSV columns when columns is empty. Amounts have the decimals
+// of their currency.
+func writeResultsCSV(w io.Writer, results []fraud.FraudResult, config fraud.Config, columns []string) error {
+	if len(columns) == 0 {
+		columns = defaultCSVColumns
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write(columns)
+
+	for _, result := range results {
+		record := make([]string, len(columns))
+		for i, column := range columns {
+			record[i] = columnValue(column, result, config, false)
+		}
+		writer.Write(record)
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeResultsMarkdown writes the fraud results as a GitHub-flavored Markdown
+// table with the same columns as the terminal table
+func writeResultsMarkdown(w io.Writer, results []fraud.FraudResult, config fraud.Config) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "| ID | Account | Merchant | Amount | Timestamp | Severity | Reason |")
+	fmt.Fprintln(bw, "|---|---|---|---|---|---|---|")
+
+	for _, result := range results {
+		tx := result.Transaction
+		fmt.Fprintf(bw, "| %s | %s | %s | %s | %s | %s | %s |\n",
+			escapeMarkdown(tx.ID),
+			escapeMarkdown(tx.AccountID),
+			escapeMarkdown(tx.Merchant),
+			config.FormatAmount(config.CurrencyOf(tx), tx.Amount),
+			tx.Timestamp.Format(time.RFC3339),
+			result.Severity,
+			escapeMarkdown(result.Reason),
+		)
+	}
+
+	return bw.Flush()
+}
+
+// markdownEscaper escapes characters that would break a Markdown table cell
+var markdownEscaper = strings.NewReplacer("\\", "\\\\", "|", "\\|", "\r\n", " ", "\n", " ")
+
+// escapeMarkdown escapes a value for use inside a Markdown table cell
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// flaggedResults returns n results with distinct transactions
+func flaggedResults(n int) []fraud.FraudResult {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	results := make([]fraud.FraudResult, n)
+	for i := range results {
+		results[i] = fraud.FraudResult{
+			Transaction: fraud.Transaction{
+				ID:        fmt.Sprintf("tx-%d", i),
+				Amount:    float64(1000 + i),
+				Timestamp: start.Add(time.Duration(i) * time.Second),
+				AccountID: fmt.Sprintf("acct-%d", i%500),
+				Merchant:  "Electronics",
+			},
+			Reason:   "High amount: $1000.00",
+			Severity: fraud.SeverityHigh,
+			Score:    50,
+			Rules:    []string{"high-amount"},
+		}
+	}
+	return results
+}
+
+func TestWriteResultsJSON(t *testing.T) {
+	for _, n := range []int{0, 1, 3} {
+		results := flaggedResults(n)
+		var buf bytes.Buffer
+		if err := writeResultsJSON(&buf, results, fraud.Config{}); err != nil {
+			t.Fatalf("writeResultsJSON: %v", err)
+		}
+
+		want, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			want = []byte("[]")
+		}
+		if got := bytes.TrimSpace(buf.Bytes()); !bytes.Equal(got, want) {
+			t.Errorf("%d results: got\n%s\nwant\n%s", n, got, want)
+		}
+	}
+}
+
+// peakHeap runs f and returns the most heap memory it had in use above what
+// was in use before, sampled while it runs
+func peakHeap(f func()) uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	base := stats.HeapAlloc
+
+	var peak atomic.Uint64
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		var stats runtime.MemStats
+		for {
+			runtime.ReadMemStats(&stats)
+			if stats.HeapAlloc > base && stats.HeapAlloc-base > peak.Load() {
+				peak.Store(stats.HeapAlloc - base)
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(100 * time.Microsecond):
+			}
+		}
+	}()
+	f()
+	close(done)
+	<-sampled
+	return peak.Load()
+}
+
+// BenchmarkExportJSON compares the peak heap of exporting 200,000 results
+// with writeResultsJSON against encoding them as one document
+func BenchmarkExportJSON(b *testing.B) {
+	results := flaggedResults(200000)
+
+	b.Run("incremental", func(b *testing.B) {
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			peak = max(peak, peakHeap(func() {
+				writeResultsJSON(io.Discard, results, fraud.Config{})
+			}))
+		}
+		b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+	})
+
+	b.Run("whole-document", func(b *testing.B) {
+		var peak uint64
+		for i := 0; i < b.N; i++ {
+			peak = max(peak, peakHeap(func() {
+				data, _ := json.MarshalIndent(results, "", "  ")
+				io.Discard.Write(data)
+			}))
+		}
+		b.ReportMetric(float64(peak)/(1<<20), "peak-MB")
+	})
+}
+
+func TestWriteResultsCSV(t *testing.T) {
+	results := flaggedResults(2)
+	results[1].Transaction.Merchant = "Smith, Jones & Co"
+	results[1].Reason = "High amount: $1001.00; Off-hours transaction at 03:14"
+
+	var buf bytes.Buffer
+	if err := writeResultsCSV(&buf, results, fraud.Config{}, nil); err != nil {
+		t.Fatalf("writeResultsCSV: %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV back: %v", err)
+	}
+
+	want := [][]string{
+		{"id", "account_id", "merchant", "amount", "timestamp", "reason", "currency", "severity"},
+		{"tx-0", "acct-0", "Electronics", "1000.00", "2024-01-01T00:00:00Z", "High amount: $1000.00", "USD", "high"},
+		{"tx-1", "acct-1", "Smith, Jones & Co", "1001.00", "2024-01-01T00:00:01Z", "High amount: $1001.00; Off-hours transaction at 03:14", "USD", "high"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("CSV records = %q, want %q", records, want)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "results.json")
+	if err := exportResults(flaggedResults(2), fraud.Config{}, 2, path, "", false, nil, false, nil); err != nil {
+		t.Fatalf("exportResults: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []fraud.FraudResult
+	if err := json.Unmarshal(data, &results); err != nil || len(results) != 2 {
+		t.Errorf("exported %d results (%v), want 2", len(results), err)
+	}
+	if !bytes.HasPrefix(data, []byte("[\n  {")) {
+		t.Errorf("export is not indented: %.20q", data)
+	}
+
+	// A failed write leaves the previous file in place
+	failed := errors.New("disk full")
+	err = writeFileAtomic(path, func(w io.Writer) error {
+		io.WriteString(w, "[{")
+		return failed
+	})
+	if !errors.Is(err, failed) {
+		t.Errorf("writeFileAtomic error = %v, want %v", err, failed)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, data) {
+		t.Errorf("failed write changed the file to %q", after)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "results.json" {
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		t.Errorf("directory holds %v, want only results.json", names)
+	}
+}
+
+func TestWriteResultsMarkdown(t *testing.T) {
+	results := flaggedResults(2)
+	results[1].Transaction.Merchant = "Smith | Jones"
+
+	var buf bytes.Buffer
+	if err := writeResultsMarkdown(&buf, results, fraud.Config{}); err != nil {
+		t.Fatalf("writeResultsMarkdown: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []string{
+		"| ID | Account | Merchant | Amount | Timestamp | Severity | Reason |",
+		"|---|---|---|---|---|---|---|",
+		"| tx-0 | acct-0 | Electronics | $1000.00 | 2024-01-01T00:00:00Z | high | High amount: $1000.00 |",
+		`| tx-1 | acct-1 | Smith \| Jones | $1001.00 | 2024-01-01T00:00:01Z | high | High amount: $1000.00 |`,
+	}
+	if !slices.Equal(lines, want) {
+		t.Errorf("Markdown lines =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestExportAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.json")
+	export := func(results []fraud.FraudResult) {
+		t.Helper()
+		if err := exportResults(results, fraud.Config{}, len(results), path, "", true, nil, false, nil); err != nil {
+			t.Fatalf("exportResults: %v", err)
+		}
+	}
+
+	// The first run creates the file, and the second overlaps it
+	first := flaggedResults(2)
+	export(first)
+	second := flaggedResults(3)
+	second[1].Reason = "Rapid: 1 related transaction within 5m"
+	export(second)
+
+	merged, err := readResultsJSON(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, result := range merged {
+		got = append(got, result.Transaction.ID+" "+result.Reason)
+	}
+	want := []string{
+		"tx-0 High amount: $1000.00",
+		"tx-1 High amount: $1000.00",
+		"tx-1 Rapid: 1 related transaction within 5m",
+		"tx-2 High amount: $1000.00",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("merged results = %q, want %q", got, want)
+	}
+
+	if err := exportResults(first, fraud.Config{}, 2, filepath.Join(t.TempDir(), "results.csv"), "", true, nil, false, nil); err == nil {
+		t.Error("appending to CSV output succeeded")
+	}
+}
+
+func TestGroupDigits(t *testing.T) {
+	dir := t.TempDir()
+	input := writeTemp(t, "transactions.csv", "id,amount,timestamp,account_id,merchant\n1,1234567.00,2024-01-01T10:00:00Z,A,Shop\n")
+	run := func(args ...string) (string, [][]string) {
+		t.Helper()
+		stdout, stderr, status := runCLI(t, dir, append([]string{"-input", input, "-rules", "high-amount", "-output", "results.csv"}, args...)...)
+		if status != 0 {
+			t.Fatalf("%v exited %d: %s", args, status, stderr)
+		}
+		file, err := os.Open(filepath.Join(dir, "results.csv"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		records, err := csv.NewReader(file).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stdout, records
+	}
+
+	// Displayed amounts and reasons are grouped by default, but exported
+	// amounts stay plain numbers
+	stdout, records := run()
+	if !strings.Contains(stdout, "$1,234,567.00") || strings.Contains(stdout, "$1234567.00") {
+		t.Errorf("display is not grouped:\n%s", stdout)
+	}
+	if len(records) != 2 || records[1][3] != "1234567.00" || records[1][5] != "High amount: $1,234,567.00" {
+		t.Errorf("exported records = %q, want a plain amount", records)
+	}
+
+	stdout, records = run("-group-digits=false")
+	if !strings.Contains(stdout, "$1234567.00") || strings.Contains(stdout, "1,234") {
+		t.Errorf("-group-digits=false display is grouped:\n%s", stdout)
+	}
+	if len(records) != 2 || records[1][3] != "1234567.00" || records[1][5] != "High amount: $1234567.00" {
+		t.Errorf("-group-digits=false exported records = %q", records)
+	}
+
+	result := fraud.FraudResult{Transaction: fraud.Transaction{Amount: 1234567}}
+	if got := columnValue("amount", result, fraud.Config{GroupDigits: true}, false); got != "1234567.00" {
+		t.Errorf("exported amount = %q, want 1234567.00", got)
+	}
+}
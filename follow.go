@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// followInterval is how often -follow checks its input for new lines
+const followInterval = 500 * time.Millisecond
+
+// followFile reads a growing file like tail -f, waiting at its end for more
+// data. It starts over when the file is truncated and switches to the new
+// file when it is replaced, as by log rotation. Read returns io.EOF once ctx
+// is done.
+type followFile struct {
+	ctx    context.Context
+	path   string
+	file   *os.File
+	offset int64
+}
+
+// openFollow opens a file to follow from its start
+func openFollow(ctx context.Context, path string) (*followFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &followFile{ctx: ctx, path: path, file: file}, nil
+}
+
+func (f *followFile) Read(p []byte) (int, error) {
+	for {
+		n, err := f.file.Read(p)
+		f.offset += int64(n)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		// At the end of the file: check whether it was rotated or truncated
+		// since, otherwise wait for more data
+		switched, err := f.reopen()
+		if err != nil {
+			return 0, err
+		}
+		if switched {
+			continue
+		}
+		select {
+		case <-f.ctx.Done():
+			return 0, io.EOF
+		case <-time.After(followInterval):
+		}
+	}
+}
+
+// reopen switches to a file that replaced the followed one, or seeks to the
+// start of a truncated one, reporting whether it did either
+func (f *followFile) reopen() (bool, error) {
+	info, err := os.Stat(f.path)
+	if err != nil {
+		return false, nil // Mid-rotation; the new file is not there yet
+	}
+
+	current, err := f.file.Stat()
+	if err != nil {
+		return false, err
+	}
+	if !os.SameFile(info, current) {
+		file, err := os.Open(f.path)
+		if err != nil {
+			return false, nil
+		}
+		f.file.Close()
+		f.file = file
+		f.offset = 0
+		slog.Info("input replaced, reading the new file", "file", f.path)
+		return true, nil
+	}
+
+	if info.Size() < f.offset {
+		if _, err := f.file.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+		f.offset = 0
+		slog.Info("input truncated, reading from the start", "file", f.path)
+		return true, nil
+	}
+	return false, nil
+}
+
+// Close closes the file currently followed
+func (f *followFile) Close() error {
+	return f.file.Close()
+}
+
+// followTransactions reads JSON Lines transactions from a growing file until
+// ctx is done, calling fn for each. Invalid lines are passed to onInvalid and
+// skipped, since one bad line must not stop a live feed.
+func followTransactions(ctx context.Context, path string, fn func(fraud.Transaction) error, onInvalid func(error)) error {
+	file, err := openFollow(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		tx, err := fraud.UnmarshalTransaction([]byte(line))
+		if err != nil {
+			onInvalid(fmt.Errorf("invalid transaction line %q: %v", line, err))
+			continue
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+}
+
+// printFlag prints a result as one tab-separated line of the given columns
+func printFlag(w io.Writer, result fraud.FraudResult, config fraud.Config, columns []string) {
+	values := make([]string, len(columns))
+	for i, column := range columns {
+		values[i] = columnValue(column, result, config, true)
+	}
+	fmt.Fprintln(w, strings.Join(values, "\t"))
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestFollowTransactionsSkipsInvalidLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.jsonl")
+	lines := []string{
+		`{"id": "1", "amount": 10, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A", "merchant": "Shop"}`,
+		`{"id": "2", "amount": 10, "merchant": "Shop"}`,
+		`not json`,
+		``,
+		`{"id": "3", "amount": 10, "timestamp": "2024-01-01T10:01:00Z", "account_id": "A", "merchant": "Shop"}`,
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A canceled context stops following at the end of the file
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var ids []string
+	var invalid []error
+	err := followTransactions(ctx, path, func(tx fraud.Transaction) error {
+		ids = append(ids, tx.ID)
+		return nil
+	}, func(err error) { invalid = append(invalid, err) })
+	if err != nil {
+		t.Fatalf("followTransactions: %v", err)
+	}
+
+	if !slices.Equal(ids, []string{"1", "3"}) {
+		t.Errorf("followed IDs = %v, want [1 3]", ids)
+	}
+	if len(invalid) != 2 || !strings.Contains(invalid[0].Error(), "missing timestamp, account_id") {
+		t.Errorf("invalid lines = %v, want the line missing fields and the non-JSON line", invalid)
+	}
+}
+
+func TestFollowTransactionsFlagsAppendedLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "feed.jsonl")
+	line := func(id, timestamp string, amount int) string {
+		return fmt.Sprintf(`{"id": %q, "amount": %d, "timestamp": %q, "account_id": "A", "merchant": "Shop"}`+"\n", id, amount, timestamp)
+	}
+	appendLines := func(lines ...string) {
+		t.Helper()
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := file.WriteString(strings.Join(lines, "")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	appendLines(line("1", "2024-01-01T10:00:00Z", 10))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	config := fraud.Config{HighAmountThreshold: 1000, TimeWindow: 5 * time.Minute}
+	detector := fraud.NewStreamDetector(config)
+	flags := make(chan string, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- followTransactions(ctx, path, func(tx fraud.Transaction) error {
+			for _, result := range fraud.MergeResults(detector.Add(tx)) {
+				flags <- result.Transaction.ID + ": " + result.Reason
+			}
+			return nil
+		}, func(error) {})
+	}()
+
+	expect := func(want ...string) {
+		t.Helper()
+		for _, w := range want {
+			select {
+			case got := <-flags:
+				if got != w {
+					t.Errorf("flag %q, want %q", got, w)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("no flag after 5s, want %q", w)
+			}
+		}
+	}
+
+	// Lines appended while following are flagged as they arrive
+	appendLines(line("2", "2024-01-01T10:01:00Z", 10))
+	expect("1: Rapid: 1 related transaction within 5m", "2: Rapid: 1 related transaction within 5m")
+	appendLines(line("3", "2024-01-01T12:00:00Z", 5000))
+	expect("3: High amount: $5000.00")
+
+	// A truncated file is read again from the start
+	if err := os.WriteFile(path, []byte(line("4", "2024-01-01T13:00:00Z", 9000)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expect("4: High amount: $9000.00")
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("followTransactions: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("followTransactions did not stop after cancellation")
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// accountGroup is the fraud results of one account
+type accountGroup struct {
+	AccountID string
+	Results   []fraud.FraudResult
+}
+
+// groupResults groups the fraud results by account, keeping their order
+// within each account, with the most flagged accounts first
+func groupResults(results []fraud.FraudResult) []accountGroup {
+	index := make(map[string]int)
+	var groups []accountGroup
+	for _, result := range results {
+		id := result.Transaction.AccountID
+		i, ok := index[id]
+		if !ok {
+			i = len(groups)
+			index[id] = i
+			groups = append(groups, accountGroup{AccountID: id})
+		}
+		groups[i].Results = append(groups[i].Results, result)
+	}
+
+	sort.SliceStable(groups, func(i, j int) bool {
+		if len(groups[i].Results) != len(groups[j].Results) {
+			return len(groups[i].Results) > len(groups[j].Results)
+		}
+		return groups[i].AccountID < groups[j].AccountID
+	})
+	return groups
+}
+
+// displayGrouped shows the fraud results as one table per account, with the
+// account's flag count
+func displayGrouped(results []fraud.FraudResult, config fraud.Config, columns []string, color bool, redactions *redactor) {
+	if len(results) == 0 {
+		fmt.Println("No fraudulent transactions detected.")
+		return
+	}
+
+	fmt.Println("Potentially Fraudulent Transactions by Account:")
+	for _, group := range groupResults(results) {
+		noun := "transactions"
+		if len(group.Results) == 1 {
+			noun = "transaction"
+		}
+		fmt.Printf("\nAccount %s: %d flagged %s\n", redactions.shownAccount(group.AccountID), len(group.Results), noun)
+		printTable(redactions.results(group.Results), config, columns, color)
+	}
+}
+
+// writeGroupedJSON writes the fraud results as an indented JSON object
+// mapping each account ID to its results. Masked IDs of different accounts
+// can be equal, so later ones get a numbered suffix, e.g. "****1234 (2)".
+func writeGroupedJSON(w io.Writer, results []fraud.FraudResult, config fraud.Config, redactions *redactor) error {
+	grouped := make(map[string][]fraud.FraudResult)
+	for _, group := range groupResults(results) {
+		id := redactions.shownAccount(group.AccountID)
+		key := id
+		for n := 2; grouped[key] != nil; n++ {
+			key = fmt.Sprintf("%s (%d)", id, n)
+		}
+		grouped[key] = redactions.results(group.Results)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(grouped)
+}
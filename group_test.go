@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// joinIDs returns the comma-separated transaction IDs of results
+func joinIDs(results []fraud.FraudResult) string {
+	return strings.Join(resultIDs(results), ",")
+}
+
+func TestGroupResults(t *testing.T) {
+	results := []fraud.FraudResult{
+		{Transaction: fraud.Transaction{ID: "1", AccountID: "B"}},
+		{Transaction: fraud.Transaction{ID: "2", AccountID: "A"}},
+		{Transaction: fraud.Transaction{ID: "3", AccountID: "C"}},
+		{Transaction: fraud.Transaction{ID: "4", AccountID: "C"}},
+		{Transaction: fraud.Transaction{ID: "5", AccountID: "A"}},
+	}
+	var got []string
+	for _, group := range groupResults(results) {
+		got = append(got, group.AccountID+":"+joinIDs(group.Results))
+	}
+	// Most flagged first, ties by account ID, results in their original order
+	if want := []string{"A:2,5", "C:3,4", "B:1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %v, want %v", got, want)
+	}
+}
+
+func TestWriteGroupedJSON(t *testing.T) {
+	results := flaggedResults(5)
+	for i := range results {
+		results[i].Transaction.AccountID = []string{"A", "B"}[i%2]
+	}
+	dir := t.TempDir()
+	grouped := filepath.Join(dir, "grouped.json")
+	if err := exportResults(results, fraud.Config{}, 5, grouped, "", false, nil, true, nil); err != nil {
+		t.Fatalf("exportResults: %v", err)
+	}
+	data, err := os.ReadFile(grouped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var accounts map[string][]fraud.FraudResult
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		t.Fatalf("grouped output is not an object of result arrays: %v\n%s", err, data)
+	}
+	got := make(map[string]string)
+	for account, results := range accounts {
+		got[account] = joinIDs(results)
+		for _, result := range results {
+			if result.Transaction.AccountID != account {
+				t.Errorf("%s is grouped under account %s, not %s", result.Transaction.ID, account, result.Transaction.AccountID)
+			}
+		}
+	}
+	if want := map[string]string{"A": "tx-0,tx-2,tx-4", "B": "tx-1,tx-3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("grouped = %v, want %v", got, want)
+	}
+
+	// Without grouping the export stays a flat list
+	flat := filepath.Join(dir, "flat.json")
+	if err := exportResults(results, fraud.Config{}, 5, flat, "", false, nil, false, nil); err != nil {
+		t.Fatalf("exportResults: %v", err)
+	}
+	if data, err = os.ReadFile(flat); err != nil {
+		t.Fatal(err)
+	}
+	var list []fraud.FraudResult
+	if err := json.Unmarshal(data, &list); err != nil || len(list) != 5 {
+		t.Errorf("flat output has %d results (%v), want a list of 5", len(list), err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// histogramWidth is the length of the longest histogram bar
+const histogramWidth = 40
+
+// parseBuckets parses comma-separated, increasing, positive bucket bounds
+func parseBuckets(value string) ([]float64, error) {
+	var bounds []float64
+	for _, item := range strings.Split(value, ",") {
+		bound, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bucket bound %q", item)
+		}
+		if bound <= 0 || (len(bounds) > 0 && bound <= bounds[len(bounds)-1]) {
+			return nil, fmt.Errorf("bucket bounds must be positive and increasing, got %s", value)
+		}
+		bounds = append(bounds, bound)
+	}
+	return bounds, nil
+}
+
+// bucketCounts counts the results whose absolute amount falls in each
+// bucket: [0, bounds[0]), [bounds[0], bounds[1]), ..., and [last bound, ∞)
+func bucketCounts(results []fraud.FraudResult, bounds []float64) []int {
+	counts := make([]int, len(bounds)+1)
+	for _, result := range results {
+		amount := math.Abs(result.Transaction.Amount)
+		counts[sort.Search(len(bounds), func(i int) bool { return amount < bounds[i] })]++
+	}
+	return counts
+}
+
+// printHistogram writes an ASCII histogram of the flagged amounts in the
+// given buckets. Amounts are not comparable across currencies, so each
+// currency gets its own histogram, the default currency's first.
+func printHistogram(w io.Writer, results []fraud.FraudResult, config fraud.Config, bounds []float64) {
+	byCurrency := make(map[string][]fraud.FraudResult)
+	currencies := make(map[string]fraud.Currency)
+	for _, result := range results {
+		currency := config.CurrencyOf(result.Transaction)
+		byCurrency[currency.Code] = append(byCurrency[currency.Code], result)
+		currencies[currency.Code] = currency
+	}
+
+	base := config.CurrencyOf(fraud.Transaction{})
+	if len(byCurrency) <= 1 {
+		if len(results) > 0 {
+			base = config.CurrencyOf(results[0].Transaction)
+		}
+		printCurrencyHistogram(w, "Flagged Amounts", results, base.Symbol, bounds)
+		return
+	}
+
+	codes := make([]string, 0, len(byCurrency))
+	for code := range byCurrency {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		if (codes[i] == base.Code) != (codes[j] == base.Code) {
+			return codes[i] == base.Code
+		}
+		return codes[i] < codes[j]
+	})
+	for _, code := range codes {
+		title := fmt.Sprintf("Flagged Amounts (%s)", code)
+		printCurrencyHistogram(w, title, byCurrency[code], currencies[code].Symbol, bounds)
+	}
+}
+
+// printCurrencyHistogram writes one histogram of results in a single
+// currency, labeled with its symbol
+func printCurrencyHistogram(w io.Writer, title string, results []fraud.FraudResult, symbol string, bounds []float64) {
+	counts := bucketCounts(results, bounds)
+	most := 0
+	for _, count := range counts {
+		most = max(most, count)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "\n%s:\n", title)
+	lower := 0.0
+	for i, count := range counts {
+		label := fmt.Sprintf("%s%g+", symbol, lower)
+		if i < len(bounds) {
+			label = fmt.Sprintf("%s%g-%g", symbol, lower, bounds[i])
+			lower = bounds[i]
+		}
+
+		bar := 0
+		if most > 0 {
+			bar = (count*histogramWidth + most - 1) / most
+		}
+		fmt.Fprintf(tw, "  %s\t%s %d\n", label, strings.Repeat("#", bar), count)
+	}
+	tw.Flush()
+}
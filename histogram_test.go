@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestBucketCounts(t *testing.T) {
+	var results []fraud.FraudResult
+	for _, amount := range []float64{0, 99.99, 100, 250, -300, 499.99, 500, 1000, 25000} {
+		results = append(results, fraud.FraudResult{Transaction: fraud.Transaction{Amount: amount}})
+	}
+	bounds := []float64{100, 500, 1000}
+	// Bounds belong to the bucket above them, and refunds count by size
+	if got, want := bucketCounts(results, bounds), []int{2, 4, 1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("bucketCounts = %v, want %v", got, want)
+	}
+	if got, want := bucketCounts(nil, bounds), []int{0, 0, 0, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("bucketCounts(nil) = %v, want %v", got, want)
+	}
+
+	var buf bytes.Buffer
+	printHistogram(&buf, results, fraud.Config{}, bounds)
+	for _, want := range []string{
+		"$0-100     " + strings.Repeat("#", 20) + " 2",
+		"$100-500   " + strings.Repeat("#", 40) + " 4",
+		"$500-1000  " + strings.Repeat("#", 10) + " 1",
+		"$1000+     " + strings.Repeat("#", 20) + " 2",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("histogram has no line %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestHistogramPerCurrency(t *testing.T) {
+	results := []fraud.FraudResult{
+		{Transaction: fraud.Transaction{Amount: 50}},
+		{Transaction: fraud.Transaction{Amount: 600, Currency: "EUR"}},
+		{Transaction: fraud.Transaction{Amount: 20000, Currency: "JPY"}},
+		{Transaction: fraud.Transaction{Amount: 30000, Currency: "JPY"}},
+	}
+	var buf bytes.Buffer
+	printHistogram(&buf, results, fraud.Config{}, []float64{100, 1000})
+	out := buf.String()
+
+	usd, eur, jpy := strings.Index(out, "Flagged Amounts (USD):"), strings.Index(out, "Flagged Amounts (EUR):"), strings.Index(out, "Flagged Amounts (JPY):")
+	if usd < 0 || eur < usd || jpy < eur {
+		t.Fatalf("want USD, EUR, then JPY histograms:\n%s", out)
+	}
+	for _, want := range []string{
+		"$0-100     " + strings.Repeat("#", 40) + " 1",
+		"€100-1000  " + strings.Repeat("#", 40) + " 1",
+		"¥1000+     " + strings.Repeat("#", 40) + " 2",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("histogram has no line %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out[:eur], "$1000+     #") {
+		t.Errorf("yen amounts counted in the dollar histogram:\n%s", out)
+	}
+
+	// A single currency other than the default is labeled with its own symbol
+	buf.Reset()
+	printHistogram(&buf, results[2:], fraud.Config{}, []float64{100, 1000})
+	if out := buf.String(); !strings.Contains(out, "Flagged Amounts:") || !strings.Contains(out, "¥1000+") {
+		t.Errorf("single-currency histogram:\n%s", out)
+	}
+}
+
+func TestParseBuckets(t *testing.T) {
+	bounds, err := parseBuckets("100, 500,1000")
+	if err != nil {
+		t.Fatalf("parseBuckets: %v", err)
+	}
+	if want := []float64{100, 500, 1000}; !reflect.DeepEqual(bounds, want) {
+		t.Errorf("bounds = %v, want %v", bounds, want)
+	}
+
+	for _, value := range []string{"", "100,lots", "0,100", "500,100", "100,100"} {
+		if _, err := parseBuckets(value); err == nil {
+			t.Errorf("parseBuckets(%q) accepted invalid bounds", value)
+		}
+	}
+}
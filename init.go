@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Files written by -init
+const (
+	initConfigFile = "config.yaml"
+	initInputFile  = "transactions.csv"
+)
+
+// initSkipFlags are the flags left out of the sample config because they do
+// not make sense in one
+var initSkipFlags = map[string]bool{"version": true, "config": true, "init": true, "force": true}
+
+// sampleTransactions is the example input written by -init. It trips the
+// high amount, rapid succession, duplicate, and round amount rules.
+const sampleTransactions = `id,amount,timestamp,account_id,merchant,category
+1,42.50,2024-03-20T09:15:00Z,ACC100,Corner Cafe,purchase
+2,1500.00,2024-03-20T10:00:00Z,ACC100,Electronics Hub,purchase
+3,89.99,2024-03-20T10:02:00Z,ACC100,Electronics Hub,purchase
+4,89.99,2024-03-20T10:02:30Z,ACC100,Electronics Hub,purchase
+5,12.00,2024-03-20T12:30:00Z,ACC200,Book Nook,purchase
+6,700.00,2024-03-20T14:00:00Z,ACC200,Cash Point,withdrawal
+7,35.20,2024-03-21T08:45:00Z,ACC300,Corner Cafe,purchase
+8,250.75,2024-03-21T18:20:00Z,ACC300,Grocery Mart,purchase
+`
+
+// writeInitFiles writes a sample config documenting every setting and a
+// sample input file to dir. Existing files are only replaced with force, and
+// nothing is written when either exists.
+func writeInitFiles(dir string, force bool) error {
+	files := map[string][]byte{
+		initConfigFile: sampleConfig(),
+		initInputFile:  []byte(sampleTransactions),
+	}
+
+	if !force {
+		for name := range files {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return fmt.Errorf("%s already exists; use -force to overwrite it", name)
+			}
+		}
+	}
+
+	for _, name := range []string{initConfigFile, initInputFile} {
+		if err := os.WriteFile(filepath.Join(dir, name), files[name], 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sampleConfig returns a YAML config listing every flag with its
+// description, commented out at its default value. Only the input is set.
+func sampleConfig() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("# Settings for go-frauddetector-cli, keyed by flag name. Use with:\n")
+	buf.WriteString("#   go-frauddetector-cli -config " + initConfigFile + "\n")
+	buf.WriteString("# Uncomment a setting to change it; command line flags take precedence.\n")
+
+	flag.VisitAll(func(f *flag.Flag) {
+		if initSkipFlags[f.Name] {
+			return
+		}
+
+		fmt.Fprintf(&buf, "\n# %s\n", f.Usage)
+		if f.Name == "input" {
+			fmt.Fprintf(&buf, "input: %s\n", initInputFile)
+			return
+		}
+		fmt.Fprintf(&buf, "# %s: %s\n", f.Name, yamlScalar(f.DefValue))
+	})
+
+	return buf.Bytes()
+}
+
+// yamlScalar writes a flag value as YAML, quoting it only when YAML would
+// otherwise read it as something else
+func yamlScalar(value string) string {
+	var decoded any
+	if err := yaml.Unmarshal([]byte("v: "+value), &decoded); err == nil && !strings.ContainsAny(value, "#\n") {
+		if m, ok := decoded.(map[string]any); ok && m["v"] != nil && configValue(m["v"]) == value {
+			return value
+		}
+	}
+	return strconv.Quote(value)
+}
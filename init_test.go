@@ -0,0 +1,75 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInitFilesParseBack(t *testing.T) {
+	dir := t.TempDir()
+	if _, stderr, status := runCLI(t, dir, "-init"); status != 0 {
+		t.Fatalf("-init exited %d: %s", status, stderr)
+	}
+
+	stdout, stderr, status := runCLI(t, dir, "-config", initConfigFile, "-count")
+	if status != 0 || !strings.Contains(stdout, "flagged transactions") {
+		t.Fatalf("running the sample: status %d, stdout %q, stderr %q", status, stdout, stderr)
+	}
+	if !strings.Contains(stderr, "rows=8") {
+		t.Errorf("sample input was not fully read: %s", stderr)
+	}
+
+	// Every documented default is a valid setting once uncommented
+	data, err := os.ReadFile(filepath.Join(dir, initConfigFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := strings.Split(string(data), "\n\n")
+	settings := blocks[:1]
+	for i, block := range blocks[1:] {
+		lines := strings.Split(strings.TrimSpace(block), "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[0], "# ") {
+			t.Fatalf("setting %d is not a description and a value: %q", i+1, block)
+		}
+		// The test binary adds flags of its own
+		setting := strings.TrimPrefix(lines[1], "# ")
+		if !strings.HasPrefix(setting, "test.") {
+			settings = append(settings, lines[0]+"\n"+setting)
+		}
+	}
+	uncommented := filepath.Join(dir, "uncommented.yaml")
+	if err := os.WriteFile(uncommented, []byte(strings.Join(settings, "\n\n")+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, stderr, status := runCLI(t, dir, "-config", uncommented, "-count"); status != 0 {
+		t.Errorf("uncommented sample config exited %d: %s", status, stderr)
+	}
+}
+
+func TestInitKeepsExistingFiles(t *testing.T) {
+	dir := t.TempDir()
+	input := filepath.Join(dir, initInputFile)
+	if err := os.WriteFile(input, []byte("mine"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := writeInitFiles(dir, false)
+	if err == nil || !strings.Contains(err.Error(), "use -force") {
+		t.Errorf("writeInitFiles error = %v, want one suggesting -force", err)
+	}
+	if data, _ := os.ReadFile(input); string(data) != "mine" {
+		t.Errorf("existing input replaced with %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, initConfigFile)); !os.IsNotExist(err) {
+		t.Errorf("config written alongside an existing input: %v", err)
+	}
+
+	if err := writeInitFiles(dir, true); err != nil {
+		t.Fatalf("writeInitFiles with force: %v", err)
+	}
+	if data, _ := os.ReadFile(input); string(data) != sampleTransactions {
+		t.Error("-force did not replace the input")
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+const testCSV = `id,amount,timestamp,account_id,merchant
+1,10.00,2024-01-01T10:00:00Z,A,Shop
+2,2500.00,2024-01-01T10:01:00Z,A,"Smith, Jones & Co"
+3,42.50,2024-01-01T12:00:00Z,B,Bakery
+`
+
+// writeTemp writes content to a file in a temporary directory
+func writeTemp(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// readInput reads all transactions of one input path
+func readInput(t *testing.T, path, fileType string) []fraud.Transaction {
+	t.Helper()
+	var transactions []fraud.Transaction
+	err := streamTransactions(context.Background(), path, fileType, false, nil, fraud.ReadOptions{}, func(tx fraud.Transaction) error {
+		transactions = append(transactions, tx)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("reading %s: %v", path, err)
+	}
+	return transactions
+}
+
+func TestReadStdin(t *testing.T) {
+	path := writeTemp(t, "transactions.csv", testCSV)
+	fromFile := readInput(t, path, "csv")
+
+	for _, fileType := range []string{"csv", "auto"} {
+		stdin, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved := os.Stdin
+		os.Stdin = stdin
+		fromStdin := readInput(t, "-", fileType)
+		os.Stdin = saved
+		stdin.Close()
+
+		if len(fromStdin) != 3 || !reflect.DeepEqual(fromStdin, fromFile) {
+			t.Errorf("-type %s: stdin read %+v, file read %+v", fileType, fromStdin, fromFile)
+		}
+	}
+}
+
+func TestReadGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(testCSV))
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := readInput(t, writeTemp(t, "plain.csv", testCSV), "csv")
+
+	tests := []struct {
+		name     string
+		gzipped  bool
+		fileType string
+	}{
+		{"transactions.csv.gz", false, "csv"},
+		{"transactions.csv.gz", false, "auto"},
+		{"transactions.dat", true, "csv"},
+	}
+	for _, tt := range tests {
+		path := writeTemp(t, tt.name, buf.String())
+		var got []fraud.Transaction
+		err := streamTransactions(context.Background(), path, tt.fileType, tt.gzipped, nil, fraud.ReadOptions{}, func(tx fraud.Transaction) error {
+			got = append(got, tx)
+			return nil
+		})
+		if err != nil {
+			t.Errorf("%s (-gzip=%v, -type %s): %v", tt.name, tt.gzipped, tt.fileType, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s (-gzip=%v, -type %s) read %+v, want %+v", tt.name, tt.gzipped, tt.fileType, got, want)
+		}
+	}
+}
+
+func TestInputList(t *testing.T) {
+	inputs := &inputList{paths: []string{"transactions.csv"}}
+	for _, value := range []string{"a.csv, b.json", "c.xlsx"} {
+		if err := inputs.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := []string{"a.csv", "b.json", "c.xlsx"}; !reflect.DeepEqual(inputs.paths, want) {
+		t.Errorf("paths = %q, want %q", inputs.paths, want)
+	}
+}
+
+func TestMultipleInputs(t *testing.T) {
+	// A's transactions are a minute apart, but in different files
+	first := writeTemp(t, "morning.csv", "id,amount,timestamp,account_id,merchant\n1,10,2024-01-01T10:00:00Z,A,Shop\n")
+	second := writeTemp(t, "later.json", `[{"id": "2", "amount": 10, "timestamp": "2024-01-01T10:01:00Z", "account_id": "A", "merchant": "Shop"}]`)
+	config := fraud.Config{HighAmountThreshold: 1000, TimeWindow: 5 * time.Minute}
+
+	read := func(paths ...string) []fraud.Transaction {
+		var transactions []fraud.Transaction
+		err := streamInputs(context.Background(), paths, "auto", false, nil, fraud.ReadOptions{}, func(tx fraud.Transaction) error {
+			transactions = append(transactions, tx)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("reading %v: %v", paths, err)
+		}
+		return transactions
+	}
+
+	for _, path := range []string{first, second} {
+		if results := fraud.Detect(read(path), config); len(results) != 0 {
+			t.Errorf("%s alone flagged %v", filepath.Base(path), resultIDs(results))
+		}
+	}
+	if got, want := resultIDs(fraud.Detect(read(first, second), config)), []string{"1", "2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("combined inputs flagged %v, want %v", got, want)
+	}
+}
+
+func TestMultipleInputsNameFailingFile(t *testing.T) {
+	good := writeTemp(t, "good.csv", testCSV)
+	bad := writeTemp(t, "bad.csv", "id,amount,timestamp,account_id,merchant\n1,lots,2024-01-01T10:00:00Z,A,Shop\n")
+	err := streamInputs(context.Background(), []string{good, bad}, "auto", false, nil, fraud.ReadOptions{}, func(fraud.Transaction) error { return nil })
+	if err == nil || !strings.HasPrefix(err.Error(), bad+": ") {
+		t.Errorf("error = %v, want one prefixed with %s", err, bad)
+	}
+}
+
+func TestEmptyInputWarns(t *testing.T) {
+	var logs bytes.Buffer
+	saved := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+	defer slog.SetDefault(saved)
+
+	inputs := map[string]string{
+		"header.csv": "id,amount,timestamp,account_id,merchant\n",
+		"empty.json": "[]",
+	}
+	for name, content := range inputs {
+		logs.Reset()
+		path := writeTemp(t, name, content)
+		if transactions := readInput(t, path, "auto"); len(transactions) != 0 {
+			t.Errorf("%s: read %d transactions, want 0", name, len(transactions))
+		}
+		if err := streamInputs(context.Background(), []string{path}, "auto", false, nil, fraud.ReadOptions{}, func(fraud.Transaction) error { return nil }); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !strings.Contains(logs.String(), `level=WARN msg="0 transactions found in input"`) {
+			t.Errorf("%s: logged %q, want a warning of 0 transactions", name, logs.String())
+		}
+	}
+
+	logs.Reset()
+	if err := streamInputs(context.Background(), []string{writeTemp(t, "some.csv", testCSV)}, "auto", false, nil, fraud.ReadOptions{}, func(fraud.Transaction) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(logs.String(), "level=WARN") {
+		t.Errorf("warned about a non-empty input: %q", logs.String())
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// kafkaOptions are the settings of -kafka
+type kafkaOptions struct {
+	Brokers []string
+	Topic   string // Topic of JSON transactions to consume
+	Output  string // Topic the flagged results are produced to
+	Group   string // Consumer group whose offsets are committed
+}
+
+// parseKafkaOptions parses comma-separated key=value settings of -kafka.
+// Brokers may be listed with commas too, e.g.
+// brokers=k1:9092,k2:9092,topic=transactions.
+func parseKafkaOptions(value string) (kafkaOptions, error) {
+	opts := kafkaOptions{Output: "fraud-results", Group: "go-frauddetector-cli"}
+	key := ""
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		k, v, ok := strings.Cut(item, "=")
+		if !ok {
+			// A further broker of the preceding brokers= setting
+			if key != "brokers" || item == "" {
+				return opts, fmt.Errorf("expected key=value, got %q", item)
+			}
+			opts.Brokers = append(opts.Brokers, item)
+			continue
+		}
+
+		key = strings.ToLower(strings.TrimSpace(k))
+		v = strings.TrimSpace(v)
+		switch key {
+		case "brokers":
+			opts.Brokers = append(opts.Brokers, v)
+		case "topic":
+			opts.Topic = v
+		case "output":
+			opts.Output = v
+		case "group":
+			opts.Group = v
+		default:
+			return opts, fmt.Errorf("unknown setting %q (use brokers, topic, output, or group)", key)
+		}
+	}
+
+	if len(opts.Brokers) == 0 || opts.Topic == "" {
+		return opts, fmt.Errorf("brokers and topic are required, e.g. brokers=localhost:9092,topic=transactions")
+	}
+	if opts.Output == "" || opts.Group == "" {
+		return opts, fmt.Errorf("output and group cannot be empty")
+	}
+	return opts, nil
+}
+
+// consumeKafka scores JSON transactions from a Kafka topic with the streaming
+// rules until ctx is done, producing each flagged result as JSON, keyed by
+// account ID, to the output topic. Results are masked by redactions, which
+// may be nil, key included. A message's offset is committed only after its
+// results are produced, so a restart resumes with the first unprocessed
+// message. Messages that are not transactions are passed to onInvalid and
+// skipped.
+func consumeKafka(ctx context.Context, opts kafkaOptions, config fraud.Config, redactions *redactor, onInvalid func(error)) error {
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: opts.Brokers,
+		Topic:   opts.Topic,
+		GroupID: opts.Group,
+	})
+	defer reader.Close()
+
+	writer := &kafka.Writer{
+		Addr:         kafka.TCP(opts.Brokers...),
+		Topic:        opts.Output,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+	}
+	defer writer.Close()
+
+	detector := fraud.NewStreamDetector(config)
+	var consumed, flagged int
+	defer func() {
+		slog.Info("kafka consumer stopped", "consumed", consumed, "flagged", flagged)
+	}()
+
+	slog.Info("consuming transactions", "topic", opts.Topic, "group", opts.Group, "output", opts.Output)
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("reading %s: %v", opts.Topic, err)
+		}
+
+		tx, err := fraud.UnmarshalTransaction(msg.Value)
+		if err != nil {
+			onInvalid(fmt.Errorf("invalid transaction at partition %d offset %d: %v", msg.Partition, msg.Offset, err))
+		} else {
+			consumed++
+			out, err := kafkaMessages(config.FilterRisk(fraud.MergeResults(detector.Add(tx))), redactions)
+			if err != nil {
+				return err
+			}
+			if len(out) > 0 {
+				if err := writer.WriteMessages(ctx, out...); err != nil {
+					if ctx.Err() != nil {
+						return nil
+					}
+					return fmt.Errorf("producing to %s: %v", opts.Output, err)
+				}
+				flagged += len(out)
+			}
+		}
+
+		if err := reader.CommitMessages(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("committing offset: %v", err)
+		}
+	}
+}
+
+// kafkaMessages encodes results as messages keyed by account ID, both masked
+// by redactions. Masked IDs that match hash to the same partition, which
+// keeps each account's results in order.
+func kafkaMessages(results []fraud.FraudResult, redactions *redactor) ([]kafka.Message, error) {
+	var out []kafka.Message
+	for _, result := range results {
+		shown := redactions.result(result)
+		value, err := json.Marshal(shown)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, kafka.Message{Key: []byte(shown.Transaction.AccountID), Value: value})
+	}
+	return out, nil
+}
@@ -0,0 +1,150 @@
+//go:build integration
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// Run with a broker, e.g.:
+//
+//	KAFKA_BROKERS=localhost:9092 go test -tags integration -run Kafka .
+
+// kafkaBrokers returns the brokers of KAFKA_BROKERS, skipping the test when
+// it is unset
+func kafkaBrokers(t *testing.T) []string {
+	t.Helper()
+	brokers := os.Getenv("KAFKA_BROKERS")
+	if brokers == "" {
+		t.Skip("KAFKA_BROKERS is not set")
+	}
+	return strings.Split(brokers, ",")
+}
+
+// createTopics creates single-partition topics on the cluster's controller
+func createTopics(t *testing.T, brokers []string, topics ...string) {
+	t.Helper()
+	conn, err := kafka.Dial("tcp", brokers[0])
+	if err != nil {
+		t.Fatalf("dialing %s: %v", brokers[0], err)
+	}
+	defer conn.Close()
+	controller, err := conn.Controller()
+	if err != nil {
+		t.Fatalf("finding controller: %v", err)
+	}
+	controllerConn, err := kafka.Dial("tcp", net.JoinHostPort(controller.Host, strconv.Itoa(controller.Port)))
+	if err != nil {
+		t.Fatalf("dialing controller: %v", err)
+	}
+	defer controllerConn.Close()
+
+	configs := make([]kafka.TopicConfig, len(topics))
+	for i, topic := range topics {
+		configs[i] = kafka.TopicConfig{Topic: topic, NumPartitions: 1, ReplicationFactor: 1}
+	}
+	if err := controllerConn.CreateTopics(configs...); err != nil {
+		t.Fatalf("creating topics: %v", err)
+	}
+}
+
+// produce writes messages to a topic
+func produce(t *testing.T, brokers []string, topic string, values ...string) {
+	t.Helper()
+	writer := &kafka.Writer{Addr: kafka.TCP(brokers...), Topic: topic, RequiredAcks: kafka.RequireAll}
+	defer writer.Close()
+	messages := make([]kafka.Message, len(values))
+	for i, value := range values {
+		messages[i] = kafka.Message{Value: []byte(value)}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := writer.WriteMessages(ctx, messages...); err != nil {
+		t.Fatalf("producing to %s: %v", topic, err)
+	}
+}
+
+// consumeResults runs consumeKafka until want results are read from the
+// output topic, starting at offset, and returns them with their keys
+func consumeResults(t *testing.T, opts kafkaOptions, config fraud.Config, offset int64, want int) ([]string, []fraud.FraudResult) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- consumeKafka(ctx, opts, config, nil, func(err error) { t.Logf("skipped: %v", err) }) }()
+	defer func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("consumeKafka: %v", err)
+		}
+	}()
+
+	reader := kafka.NewReader(kafka.ReaderConfig{Brokers: opts.Brokers, Topic: opts.Output, Partition: 0})
+	defer reader.Close()
+	reader.SetOffset(offset)
+
+	readCtx, readCancel := context.WithTimeout(ctx, 60*time.Second)
+	defer readCancel()
+	var keys []string
+	var results []fraud.FraudResult
+	for len(results) < want {
+		msg, err := reader.ReadMessage(readCtx)
+		if err != nil {
+			t.Fatalf("reading %s after %d results: %v", opts.Output, len(results), err)
+		}
+		var result fraud.FraudResult
+		if err := json.Unmarshal(msg.Value, &result); err != nil {
+			t.Fatalf("decoding result: %v", err)
+		}
+		keys = append(keys, string(msg.Key))
+		results = append(results, result)
+	}
+	return keys, results
+}
+
+func TestKafkaConsumer(t *testing.T) {
+	brokers := kafkaBrokers(t)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 36)
+	opts := kafkaOptions{
+		Brokers: brokers,
+		Topic:   "transactions-" + suffix,
+		Output:  "fraud-results-" + suffix,
+		Group:   "fraud-detector-" + suffix,
+	}
+	createTopics(t, brokers, opts.Topic, opts.Output)
+	config := fraud.Config{HighAmountThreshold: 1000}
+
+	message := func(id, account string, amount float64) string {
+		return fmt.Sprintf(`{"id": %q, "amount": %v, "timestamp": "2024-01-01T10:00:00Z", "account_id": %q, "merchant": "Shop"}`, id, amount, account)
+	}
+	produce(t, brokers, opts.Topic,
+		message("1", "A", 5000),
+		`{"id": "2", "amount": 9000}`, // Missing fields, skipped
+		"not json",
+		message("3", "B", 10),
+	)
+
+	keys, results := consumeResults(t, opts, config, kafka.FirstOffset, 1)
+	if keys[0] != "A" || results[0].Transaction.ID != "1" || results[0].Rules[0] != "high-amount" {
+		t.Errorf("first result %q: %+v, want transaction 1 of account A flagged by high-amount", keys[0], results[0])
+	}
+
+	// Offsets were committed as messages were processed, so a restarted
+	// consumer in the same group only scores the new message
+	produce(t, brokers, opts.Topic, message("4", "C", 7000))
+	keys, results = consumeResults(t, opts, config, 1, 1)
+	if keys[0] != "C" || results[0].Transaction.ID != "4" {
+		t.Errorf("result after restart %q: %+v, want transaction 4 of account C", keys[0], results[0])
+	}
+}
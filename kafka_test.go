@@ -0,0 +1,74 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestParseKafkaOptions(t *testing.T) {
+	tests := []struct {
+		value string
+		want  kafkaOptions
+		err   string
+	}{
+		{
+			value: "brokers=k1:9092,topic=transactions",
+			want:  kafkaOptions{Brokers: []string{"k1:9092"}, Topic: "transactions", Output: "fraud-results", Group: "go-frauddetector-cli"},
+		},
+		{
+			value: "brokers=k1:9092,k2:9092, topic=tx, output=flags, group=scorers",
+			want:  kafkaOptions{Brokers: []string{"k1:9092", "k2:9092"}, Topic: "tx", Output: "flags", Group: "scorers"},
+		},
+		{value: "topic=tx", err: "brokers and topic are required"},
+		{value: "brokers=k1:9092", err: "brokers and topic are required"},
+		{value: "brokers=k1:9092,topic=tx,output=", err: "cannot be empty"},
+		{value: "brokers=k1:9092,topic=tx,acks=all", err: `unknown setting "acks"`},
+		{value: "topic=tx,k2:9092", err: "expected key=value"},
+	}
+	for _, tt := range tests {
+		got, err := parseKafkaOptions(tt.value)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseKafkaOptions(%q) error = %v, want %q", tt.value, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseKafkaOptions(%q): %v", tt.value, err)
+			continue
+		}
+		if !slices.Equal(got.Brokers, tt.want.Brokers) || got.Topic != tt.want.Topic || got.Output != tt.want.Output || got.Group != tt.want.Group {
+			t.Errorf("parseKafkaOptions(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestKafkaMessagesRedacted(t *testing.T) {
+	results := fraud.Detect(redactTransactions, fraud.Config{HighAmountThreshold: 1000})
+	messages, err := kafkaMessages(results, &redactor{account: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("got %d messages, want 2", len(messages))
+	}
+	for _, msg := range messages {
+		if string(msg.Key) != "****1234" {
+			t.Errorf("key = %q, want ****1234", msg.Key)
+		}
+		if strings.Contains(string(msg.Value), "0001234") {
+			t.Errorf("value reveals the account: %s", msg.Value)
+		}
+	}
+
+	messages, err = kafkaMessages(results, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(messages[0].Key) != "A0001234" {
+		t.Errorf("unredacted key = %q, want A0001234", messages[0].Key)
+	}
+}
@@ -1,262 +1,1684 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
 	"encoding/csv"
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
-	"sync"
+	"text/tabwriter"
+	"text/template"
 	"time"
 
+	"go-frauddetector-cli/pkg/fraud"
+
 	"github.com/olekukonko/tablewriter"
 )
 
-// Transaction represents a financial transaction
-type Transaction struct {
-	ID        string    `json:"id"`
-	Amount    float64   `json:"amount"`
-	Timestamp time.Time `json:"timestamp"`
-	AccountID string    `json:"account_id"`
-	Merchant  string    `json:"merchant"`
-}
-
-// FraudResult represents a detected fraudulent transaction with reason
-type FraudResult struct {
-	Transaction Transaction
-	Reason      string
-}
+// Build metadata, set at build time with
+// -ldflags "-X main.version=1.2.0 -X main.commit=abc123 -X main.date=2024-03-20"
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
 
-// Config holds the fraud detection thresholds
-type Config struct {
-	HighAmountThreshold float64
-	TimeWindow          time.Duration
-	OutputFile          string
-}
+// exitFraudDetected is the exit status used with -fail-on-detect or -any
+// when any transaction is flagged. Operational errors exit with status 1.
+const exitFraudDetected = 2
 
 func main() {
 	// Parse command line flags
-	inputFile := flag.String("input", "transactions.csv", "Path to input file (CSV or JSON)")
-	fileType := flag.String("type", "csv", "Input file type (csv or json)")
+	showVersion := flag.Bool("version", false, "Print version information and exit")
+	initFiles := flag.Bool("init", false, "Write a sample config.yaml documenting every setting and a sample transactions.csv to the current directory, then exit")
+	force := flag.Bool("force", false, "Let -init overwrite existing files")
+	diffFile := flag.String("diff", "", "Compare two JSON result exports, given as -diff old.json new.json, printing newly flagged, no longer flagged, and changed transactions, then exit")
+	configFile := flag.String("config", "", "YAML or JSON file of settings keyed by flag name; command line flags take precedence")
+	inputFiles := &inputList{paths: []string{"transactions.csv"}}
+	flag.Var(inputFiles, "input", "Path or HTTP(S) URL of input file (CSV, JSON, or XLSX), or - for stdin; repeat the flag or separate paths with commas to analyze several files together")
+	fileType := flag.String("type", "auto", "Input file type (csv, json, jsonl/ndjson, xlsx, or auto to detect it from each file's extension or content)")
+	timeFormat := flag.String("time-format", "", "Go layout for CSV timestamps, e.g. \"2006-01-02 15:04:05\" (auto-detects RFC3339, that layout, and Unix epoch seconds when empty)")
+	csvDelimiter := flag.String("csv-delimiter", ",", "CSV field separator: a single character, or \"tab\" for tab-separated files")
+	csvColumns := flag.String("csv-columns", "", "CSV column of each field as a 0-based index or header name, e.g. id=0,amount=3 or amount=\"Amount USD\"")
+	decimalSeparator := flag.String("decimal-separator", ".", "Decimal mark of CSV amounts, e.g. \",\" for 1.234,56")
+	thousandsSeparator := flag.String("thousands-separator", "", "Digit group separator in CSV amounts, removed before parsing, e.g. \".\" for 1.234,56, or \"space\" (default none)")
+	jsonPath := flag.String("json-path", "", "Dot-separated path of keys to the transactions array in wrapped JSON input, e.g. data.transactions for {\"data\": {\"transactions\": [...]}}")
+	fieldsFromHeader := flag.Bool("fields-from-header", false, "Find CSV and Excel fields by header name, in any order and with common aliases such as txn_id, amt, and acct, instead of by position")
+	noHeader := flag.Bool("no-header", false, "The CSV file has no header row; its first row is a transaction")
+	checkpointFile := flag.String("checkpoint", "", "State file of the latest transactions analyzed; later runs report only newer transactions and update it once results are shown or saved")
+	cacheFile := flag.String("cache", "", "File caching the parsed transactions, reused while the input files and parse flags are unchanged, e.g. transactions.gob (not with -stream)")
+	dedupInput := flag.Bool("dedup-input", false, "Drop transactions whose ID repeats an earlier one, such as upstream retries, before detection")
+	skipInvalid := flag.Bool("skip-invalid", false, "Skip malformed rows and JSON records missing required fields, reporting each to stderr, instead of aborting")
+	gzipped := flag.Bool("gzip", false, "Decompress gzip input (implied by a .gz input extension)")
+	headers := &headerList{}
+	flag.Var(headers, "header", "HTTP request header for URL inputs, e.g. \"Authorization: Bearer TOKEN\"; repeat for several")
 	highAmount := flag.Float64("amount", 1000.0, "High amount threshold")
-	timeWindow := flag.Int("window", 5, "Time window in minutes for rapid transactions")
+	categoryThresholds := flag.String("category-thresholds", "", "High amount thresholds per transaction category, e.g. withdrawal=300,transfer=5000")
+	accountThresholds := flag.String("account-thresholds", "", "CSV file of account_id,threshold rows overriding -amount for those accounts")
+	timeWindow := &minutesValue{5 * time.Minute}
+	flag.Var(timeWindow, "window", "Time window for rapid transactions, in minutes or as a duration such as 90s")
+	flagSimultaneous := flag.Bool("flag-simultaneous", false, "Also flag an account's transactions that share the same timestamp, which rapid succession otherwise ignores")
+	rapidEscalation := flag.Float64("rapid-escalation", 0, "Flag rapid transactions only when the later amount exceeds the earlier one times this factor, e.g. 10 for a test charge then a large one (0 flags every rapid pair)")
+	velocityCount := flag.Int("velocity-count", 5, "Maximum transactions per account within the velocity window (0 disables)")
+	velocityWindow := flag.Duration("velocity-window", 10*time.Minute, "Rolling time window for velocity detection")
+	duplicateWindow := flag.Duration("duplicate-window", 60*time.Second, "Time window for duplicate charge detection (0 disables)")
 	outputFile := flag.String("output", "", "Output file for flagged transactions")
-	
+	outputFormat := flag.String("output-format", "", "Output file format (json, csv, md, html, or parquet); inferred from the output file extension when empty")
+	sqlitePath := flag.String("sqlite", "", "SQLite database to add flagged transactions to, in a fraud_results table created when missing")
+	webhookURL := flag.String("webhook", "", "URL to POST flagged transactions to as JSON arrays, e.g. an alerting relay")
+	webhookTimeout := flag.Duration("webhook-timeout", 10*time.Second, "Timeout of each -webhook request")
+	resultColumns := flag.String("columns", "", "Comma-separated fields of the results table and JSON/CSV exports, e.g. id,amount,reason (default all; one of "+strings.Join(outputColumns, ", ")+")")
+	redact := flag.Bool("redact", false, "Mask -redact-fields in the table, exports, and other outputs, keeping their last 4 characters; detection uses the real values")
+	redactFields := flag.String("redact-fields", "account", "Comma-separated fields -redact masks: account, merchant")
+	redactHash := flag.Bool("redact-hash", false, "With -redact, replace values with a short SHA-256 digest instead, so they stay comparable across reports")
+	appendOutput := flag.Bool("append", false, "Add results to an existing JSON -output file instead of replacing it, skipping ones already there")
+	templates := &reasonTemplates{}
+	flag.Var(templates, "reason-template", "Go template rewording a rule's reasons, as rule=template, e.g. \"high-amount=AMT {{.Amount}} > {{.Threshold}}\"; repeat for several rules")
+	highRiskMCCs := flag.String("high-risk-mcc", "", "Comma-separated merchant category codes to flag, e.g. 6011,7995,4829")
+	allowedCountries := flag.String("allowed-countries", "", "Comma-separated country codes transactions may come from; others are flagged, e.g. US,CA,GB")
+	blockedCountries := flag.String("blocked-countries", "", "Comma-separated country codes to flag, e.g. RU,KP")
+	merchantAliases := flag.String("merchant-aliases", "", "CSV file of pattern,name rows mapping merchant names that match a case-insensitive regular expression to one name for merchant-based rules, e.g. \"^(amazon|amzn)\",amazon")
+	blocklistFile := flag.String("blocklist", "", "File of merchant names to flag, one per line (case-insensitive)")
+	flagWeekends := flag.Bool("flag-weekends", false, "Flag transactions on Saturdays and Sundays in -tz")
+	holidaysFile := flag.String("holidays", "", "File of holiday dates to flag in -tz, one YYYY-MM-DD date per line")
+	offHoursStart := flag.Int("offhours-start", 0, "Hour (0-23) when the off-hours window starts")
+	offHoursEnd := flag.Int("offhours-end", 0, "Hour (0-23) when the off-hours window ends, exclusive; may wrap past midnight (equal to -offhours-start disables)")
+	roundMultiple := flag.Float64("round-multiple", 100, "Flag amounts that are exact multiples of this value (0 disables)")
+	roundMin := flag.Float64("round-min", 500, "Smallest amount the round-amount rule applies to")
+	dailyLimit := flag.Float64("daily-limit", 0, "Flag accounts whose total spend in a calendar day exceeds this amount (0 disables)")
+	maxSpeed := flag.Float64("max-speed-kmh", 900, "Flag consecutive located transactions of an account implying travel faster than this (0 disables)")
+	zScore := flag.Float64("zscore", 3.0, "Flag amounts this many standard deviations above the account's mean (0 disables)")
+	minSamples := flag.Int("min-samples", 5, "Fewest transactions an account needs before the z-score rule applies")
+	historyMin := flag.Int("history-min", 5, "Prior transactions an account needs before its first transaction with a new merchant is flagged (0 disables)")
+	minAmount := flag.Float64("min-amount", 0, "Flag nonzero amounts below this, e.g. -1000 for refunds larger than $1000 (0 disables)")
+	flagZero := flag.Bool("flag-zero", false, "Flag zero-amount transactions as possible card probes")
+	structuringLimit := flag.Float64("structuring-threshold", 0, "Reporting limit to watch for transactions structured just below it, e.g. 10000 (0 disables)")
+	structuringBand := flag.Float64("structuring-band", 5, "Percentage below -structuring-threshold that counts as near it")
+	structuringCount := flag.Int("structuring-count", 2, "Near-limit transactions in an account-day that are flagged as structuring")
+	newAccountCount := flag.Int("newacct-count", 0, "Flag accounts with more than this many transactions within -newacct-window of their first (0 disables)")
+	newAccountWindow := flag.Duration("newacct-window", 10*time.Minute, "Time after an account's first transaction that -newacct-count applies to")
+	distinctMerchants := flag.Int("distinct-merchants", 0, "Flag accounts using more than this many different merchants within -merchant-window (0 disables)")
+	merchantWindow := flag.Duration("merchant-window", 5*time.Minute, "Rolling time window for -distinct-merchants")
+	maxCards := flag.Int("max-cards", 0, "Flag accounts using more than this many different cards within -card-window (0 disables)")
+	cardWindow := flag.Duration("card-window", time.Hour, "Rolling time window for -max-cards")
+	maxAccountsPerDevice := flag.Int("max-accounts-per-device", 0, "Flag transactions from devices used by more than this many accounts (0 disables)")
+	currency := flag.String("currency", "USD", "ISO 4217 code of transaction amounts, e.g. EUR or JPY; a currency column or field overrides it per transaction")
+	groupDigits := flag.Bool("group-digits", true, "Write displayed amounts and amounts in reasons with thousands separators, e.g. $1,234,567.00; exported amount fields stay plain numbers")
+	minSeverity := flag.String("min-severity", "", "Drop rule findings less severe than this: low, medium, or high (default keeps all)")
+	ruleNames := flag.String("rules", "", "Comma-separated rules to apply, e.g. high-amount,velocity (default all: "+strings.Join(fraud.RuleNames(), ", ")+")")
+	timeZone := flag.String("tz", "UTC", "Time zone for hour and day based rules, e.g. America/Chicago")
+	whitelistFile := flag.String("whitelist", "", "File of account IDs and merchant names never to flag, one per line (case-insensitive)")
+	concurrency := flag.Int("concurrency", 0, "Batches analyzed at once (0 uses the CPU count, 1 runs sequentially)")
+	streamBuffer := flag.Int("stream-buffer", 0, "Most recent transactions -stream keeps per account, dropping the oldest when full (0 keeps all within the longest rule window)")
+	sampleFraction := flag.Float64("sample", 0, "Detect fraud in a random fraction of the transactions, e.g. 0.01 for 1%, for quick checks of large inputs (0 scans all)")
+	seed := flag.Int64("seed", 0, "Random seed for -sample, so a sample can be repeated (0 picks one and logs it)")
+	maxTransactions := flag.Int("max-transactions", 0, "Abort when the input, or a -serve request, has more than this many transactions (0 means no limit)")
+	batchSize := flag.Int("batch-size", 0, "Transactions per goroutine batch (0 sizes batches from the CPU count)")
+	kafkaSpec := flag.String("kafka", "", "Consume JSON transactions from Kafka and produce flagged results until interrupted, e.g. brokers=localhost:9092,topic=transactions,output=fraud-results,group=fraud-detector")
+	follow := flag.Bool("follow", false, "Keep reading lines appended to a JSON Lines input, like tail -f, printing flags as they occur until interrupted")
+	stream := flag.Bool("stream", false, "Read and analyze transactions incrementally to bound memory use (input must be time-ordered per account)")
+	showProgress := flag.Bool("progress", false, "Report rows read and batches processed on stderr (only when stderr is a terminal)")
+	noColor := flag.Bool("no-color", false, "Disable colored table output (color is off automatically when stdout is not a terminal)")
+	serveAddr := flag.String("serve", "", "Run an HTTP server on this address (e.g. :8080) exposing POST /detect instead of reading a file")
+	groupBy := flag.String("group-by", "", "Group results by account in the table and JSON exports: account (default ungrouped)")
+	sortBy := flag.String("sort-by", fraud.SortByTimestamp, "Order results by timestamp, amount, or account")
+	maxTableRows := flag.Int("max-table-rows", 10000, "Skip the results table when more transactions than this are flagged (0 means no limit)")
+	top := flag.Int("top", 0, "Show only the N most severe results in the table (0 shows all); exports keep every result")
+	topBy := flag.String("top-by", "severity", "Rank -top results by severity, weighed with -severity-weights, or by risk score: severity or score")
+	riskWeights := flag.String("risk-weights", "", "Risk score each rule adds to a transaction it matches, e.g. high-amount=40,rapid-succession=30,off-hours=10; other rules add 10, 25, or 50 by severity")
+	riskThreshold := flag.Float64("risk-threshold", 0, "Report only transactions whose risk score, summed over the rules they match, is above this (0 reports all)")
+	severityWeights := flag.String("severity-weights", "", "Severity each rule adds for -top, e.g. blocklist=5000,velocity=50; high-amount adds the amount and other rules default to 100")
+	summaryOnly := flag.Bool("summary-only", false, "Print only the summary statistics, not the per-transaction table")
+	explain := flag.Bool("explain", false, "Write every rule checked for each transaction, with the values compared, to stderr (not with -stream)")
+	histogram := flag.Bool("histogram", false, "After the summary, print a histogram of flagged amounts in -histogram-buckets")
+	histogramBuckets := flag.String("histogram-buckets", "100,500,1000,5000,10000", "Upper bounds of the -histogram amount buckets; a last bucket holds larger amounts")
+	byMerchant := flag.Bool("by-merchant", false, "After the summary, rank merchants by flagged transactions and amount")
+	countOnly := flag.Bool("count", false, "Print only the number of flagged transactions and accounts; skips the table and -output")
+	timeout := flag.Duration("timeout", 0, "Stop the analysis after this long, e.g. 30s, reporting partial results; in -serve mode, the limit per request (0 means no limit)")
+	quiet := flag.Bool("quiet", false, "Print nothing on stdout and log only errors; results go only to -output or -sqlite")
+	cpuProfile := flag.String("cpuprofile", "", "Write a CPU profile of the run to this file, for go tool pprof")
+	memProfile := flag.String("memprofile", "", "Write a heap profile to this file at the end of the run, for go tool pprof")
+	logFormat := flag.String("log-format", "text", "Format of log lines on stderr: text or json")
+	failOnDetect := flag.Bool("fail-on-detect", false, "Exit with status 2 when fraud is detected (status 1 is reserved for errors)")
+	anyMatch := flag.Bool("any", false, "Stop at the first flagged transaction, print it on one line, and exit with status 2; exit 0 when none is flagged")
+
 	flag.Parse()
 
-	config := Config{
-		HighAmountThreshold: *highAmount,
-		TimeWindow:          time.Duration(*timeWindow) * time.Minute,
-		OutputFile:          *outputFile,
+	if *showVersion {
+		fmt.Printf("go-frauddetector-cli %s (commit %s, built %s)\n", version, commit, date)
+		return
+	}
+
+	if *initFiles {
+		if err := writeInitFiles(".", *force); err != nil {
+			slog.Error("writing sample files", "error", err)
+			exit(1)
+		}
+		fmt.Printf("Wrote %s and %s. Try: go-frauddetector-cli -config %s\n", initConfigFile, initInputFile, initConfigFile)
+		return
+	}
+
+	if *configFile != "" {
+		if err := loadConfigFile(*configFile); err != nil {
+			slog.Error("loading config", "file", *configFile, "error", err)
+			exit(1)
+		}
+	}
+
+	// Logs go to stderr so stdout carries only results
+	level := slog.LevelInfo
+	if *quiet {
+		level = slog.LevelError
+	}
+	logger, err := newLogger(os.Stderr, *logFormat, level)
+	if err != nil {
+		slog.Error("configuring logging", "error", err)
+		exit(1)
+	}
+	slog.SetDefault(logger)
+
+	// Profiles cover the rest of the run, and are written on every way out
+	if err := startProfiles(*cpuProfile, *memProfile); err != nil {
+		slog.Error("starting profiles", "error", err)
+		exit(1)
+	}
+	defer runExitHooks()
+
+	start := time.Now()
+
+	config := fraud.Config{
+		HighAmountThreshold:  *highAmount,
+		TimeWindow:           timeWindow.Duration,
+		RapidEscalation:      *rapidEscalation,
+		FlagSimultaneous:     *flagSimultaneous,
+		VelocityCount:        *velocityCount,
+		VelocityWindow:       *velocityWindow,
+		DuplicateWindow:      *duplicateWindow,
+		BatchSize:            *batchSize,
+		StreamBuffer:         *streamBuffer,
+		Concurrency:          *concurrency,
+		ReasonTemplates:      templates.templates,
+		OffHoursStart:        *offHoursStart,
+		OffHoursEnd:          *offHoursEnd,
+		FlagWeekends:         *flagWeekends,
+		RoundMultiple:        *roundMultiple,
+		RoundMin:             *roundMin,
+		DailyLimit:           *dailyLimit,
+		MaxSpeedKmh:          *maxSpeed,
+		ZScore:               *zScore,
+		MinSamples:           *minSamples,
+		HistoryMin:           *historyMin,
+		MinAmount:            *minAmount,
+		FlagZero:             *flagZero,
+		StructuringLimit:     *structuringLimit,
+		StructuringBand:      *structuringBand / 100,
+		StructuringCount:     *structuringCount,
+		NewAccountCount:      *newAccountCount,
+		NewAccountWindow:     *newAccountWindow,
+		DistinctMerchants:    *distinctMerchants,
+		MerchantWindow:       *merchantWindow,
+		MaxCards:             *maxCards,
+		CardWindow:           *cardWindow,
+		MaxAccountsPerDevice: *maxAccountsPerDevice,
+	}
+
+	location, err := time.LoadLocation(*timeZone)
+	if err != nil {
+		slog.Error("loading time zone", "error", err)
+		exit(1)
+	}
+	config.Location = location
+
+	if *minSeverity != "" {
+		config.MinSeverity, err = fraud.ParseSeverity(*minSeverity)
+		if err != nil {
+			slog.Error("parsing -min-severity", "error", err)
+			exit(1)
+		}
+	}
+
+	if *merchantAliases != "" {
+		aliases, err := readMerchantAliases(*merchantAliases)
+		if err != nil {
+			slog.Error("reading merchant aliases", "file", *merchantAliases, "error", err)
+			exit(1)
+		}
+		config.MerchantAliases = aliases
+	}
+
+	if *blocklistFile != "" {
+		merchants, err := readList(*blocklistFile)
+		if err != nil {
+			slog.Error("reading blocklist", "file", *blocklistFile, "error", err)
+			exit(1)
+		}
+		config.MerchantBlocklist = merchantSet(merchants, config)
+	}
+
+	if *holidaysFile != "" {
+		holidays, err := readHolidays(*holidaysFile)
+		if err != nil {
+			slog.Error("reading holidays", "file", *holidaysFile, "error", err)
+			exit(1)
+		}
+		config.Holidays = holidays
+	}
+
+	if *highRiskMCCs != "" {
+		config.HighRiskMCCs = make(map[string]bool)
+		for _, mcc := range strings.Split(*highRiskMCCs, ",") {
+			if mcc = strings.TrimSpace(mcc); mcc != "" {
+				config.HighRiskMCCs[mcc] = true
+			}
+		}
+	}
+
+	if *allowedCountries != "" {
+		countries, err := parseCountries(*allowedCountries)
+		if err != nil {
+			slog.Error("parsing -allowed-countries", "error", err)
+			exit(1)
+		}
+		config.AllowedCountries = countries
+	}
+	if *blockedCountries != "" {
+		countries, err := parseCountries(*blockedCountries)
+		if err != nil {
+			slog.Error("parsing -blocked-countries", "error", err)
+			exit(1)
+		}
+		config.BlockedCountries = countries
+	}
+
+	config.Currency = strings.ToUpper(*currency)
+	config.GroupDigits = *groupDigits
+
+	if *categoryThresholds != "" {
+		thresholds, err := parseThresholds(*categoryThresholds)
+		if err != nil {
+			slog.Error("parsing -category-thresholds", "error", err)
+			exit(1)
+		}
+		config.CategoryThresholds = thresholds
+	}
+
+	if *accountThresholds != "" {
+		thresholds, err := readThresholds(*accountThresholds)
+		if err != nil {
+			slog.Error("reading account thresholds", "file", *accountThresholds, "error", err)
+			exit(1)
+		}
+		config.AccountThresholds = thresholds
+	}
+
+	if *ruleNames != "" {
+		var names []string
+		for _, name := range strings.Split(*ruleNames, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		rules, err := fraud.LookupRules(names)
+		if err != nil {
+			slog.Error("selecting rules", "error", err)
+			exit(1)
+		}
+		config.Rules = rules
+	}
+
+	if *whitelistFile != "" {
+		entries, err := readList(*whitelistFile)
+		if err != nil {
+			slog.Error("reading whitelist", "file", *whitelistFile, "error", err)
+			exit(1)
+		}
+		// Entries are account IDs or merchant names, so both forms are kept
+		config.Whitelist = lowerSet(entries)
+		for merchant := range merchantSet(entries, config) {
+			config.Whitelist[merchant] = true
+		}
+	}
+
+	if *riskWeights != "" {
+		var err error
+		if config.RuleWeights, err = parseThresholds(*riskWeights); err == nil {
+			names := make([]string, 0, len(config.RuleWeights))
+			for name := range config.RuleWeights {
+				names = append(names, name)
+			}
+			_, err = fraud.LookupRules(names)
+		}
+		if err != nil {
+			slog.Error("parsing -risk-weights", "error", err)
+			exit(1)
+		}
+	}
+	config.RiskThreshold = *riskThreshold
+
+	var weights map[string]float64
+	if *severityWeights != "" {
+		var err error
+		if weights, err = parseThresholds(*severityWeights); err == nil {
+			names := make([]string, 0, len(weights))
+			for name := range weights {
+				names = append(names, name)
+			}
+			_, err = fraud.LookupRules(names)
+		}
+		if err != nil {
+			slog.Error("parsing -severity-weights", "error", err)
+			exit(1)
+		}
+	}
+
+	if err := config.Validate(); err != nil {
+		for _, err := range configErrors(err) {
+			slog.Error("invalid config", "error", err)
+		}
+		exit(1)
+	}
+
+	// With -diff, two earlier runs are compared instead of analyzing input
+	if *diffFile != "" {
+		if flag.NArg() != 1 {
+			slog.Error("-diff needs the old and new result files, e.g. -diff old.json new.json")
+			exit(1)
+		}
+		old, err := readDiffResults(*diffFile)
+		if err != nil {
+			slog.Error("reading results", "file", *diffFile, "error", err)
+			exit(1)
+		}
+		results, err := readDiffResults(flag.Arg(0))
+		if err != nil {
+			slog.Error("reading results", "file", flag.Arg(0), "error", err)
+			exit(1)
+		}
+		printDiff(os.Stdout, diffResults(old, results), config)
+		return
+	}
+
+	switch *sortBy {
+	case fraud.SortByTimestamp, fraud.SortByAmount, fraud.SortByAccount:
+	default:
+		slog.Error("unsupported -sort-by value (use timestamp, amount, or account)", "sort_by", *sortBy)
+		exit(1)
+	}
+
+	for _, path := range inputFiles.paths {
+		if strings.EqualFold(*fileType, "auto") {
+			break
+		}
+		if ext := fraud.FormatForName(inputPath(path)); ext != "" && !sameFileType(ext, *fileType) {
+			slog.Error("input file does not match input type; all input files must share one type", "file", path, "type", *fileType)
+			exit(1)
+		}
+	}
+
+	var selectedColumns []string
+	if *resultColumns != "" {
+		var err error
+		selectedColumns, err = parseOutputColumns(*resultColumns)
+		if err != nil {
+			slog.Error("parsing -columns", "error", err)
+			exit(1)
+		}
+	}
+
+	if *topBy != "severity" && *topBy != "score" {
+		slog.Error("unsupported -top-by value (use severity or score)", "top_by", *topBy)
+		exit(1)
+	}
+
+	if *groupBy != "" && *groupBy != "account" {
+		slog.Error("unsupported -group-by value (use account)", "group_by", *groupBy)
+		exit(1)
+	}
+	grouped := *groupBy == "account"
+
+	if *outputFile != "" {
+		format := *outputFormat
+		if format == "" {
+			format = formatForExtension(*outputFile)
+		}
+		json := strings.EqualFold(format, "json")
+		if *appendOutput && !json {
+			slog.Error("-append requires json output", "output", *outputFile, "format", format)
+			exit(1)
+		}
+		if *appendOutput && len(selectedColumns) > 0 {
+			slog.Error("-append needs whole results and cannot be used with -columns")
+			exit(1)
+		}
+		if grouped && (!json || *appendOutput || len(selectedColumns) > 0) {
+			slog.Error("-group-by exports require json output of whole results, without -append or -columns", "output", *outputFile, "format", format)
+			exit(1)
+		}
+	}
+
+	var redactions *redactor
+	if *redact {
+		r, err := parseRedactFields(*redactFields, *redactHash)
+		if err != nil {
+			slog.Error("parsing -redact-fields", "error", err)
+			exit(1)
+		}
+		redactions = &r
+	}
+
+	var bounds []float64
+	if *histogram {
+		var err error
+		if bounds, err = parseBuckets(*histogramBuckets); err != nil {
+			slog.Error("parsing -histogram-buckets", "error", err)
+			exit(1)
+		}
+	}
+
+	if *explain && *stream {
+		slog.Error("-explain needs the whole input and cannot be used with -stream")
+		exit(1)
+	}
+
+	if *cacheFile != "" && *stream {
+		slog.Error("-cache holds the whole input and cannot be used with -stream")
+		exit(1)
+	}
+
+	if *sampleFraction < 0 || *sampleFraction > 1 {
+		slog.Error("-sample must be a fraction between 0 and 1", "sample", *sampleFraction)
+		exit(1)
+	}
+	// The sample is drawn from the whole input, so it needs every
+	// transaction read before detection starts
+	if *sampleFraction > 0 && (*stream || *serveAddr != "" || *kafkaSpec != "" || *follow || *checkpointFile != "") {
+		slog.Error("-sample cannot be used with -stream, -serve, -kafka, -follow, or -checkpoint")
+		exit(1)
+	}
+
+	// -any stops at the first match, so it cannot serve modes that report
+	// every match
+	if *anyMatch && (*serveAddr != "" || *kafkaSpec != "" || *follow || *checkpointFile != "" || *explain) {
+		slog.Error("-any cannot be used with -serve, -kafka, -follow, -checkpoint, or -explain")
+		exit(1)
+	}
+	config.StopOnMatch = *anyMatch
+
+	if *follow {
+		if len(inputFiles.paths) != 1 || inputFiles.paths[0] == "-" || isURL(inputFiles.paths[0]) {
+			slog.Error("-follow needs a single local input file")
+			exit(1)
+		}
+		t := strings.ToLower(*fileType)
+		if t == "auto" {
+			t = fraud.FormatForName(inputPath(inputFiles.paths[0]))
+		}
+		if t != "jsonl" && t != "ndjson" {
+			slog.Error("-follow needs JSON Lines input (-type jsonl)", "type", *fileType)
+			exit(1)
+		}
+		if *explain {
+			slog.Error("-explain needs the whole input and cannot be used with -follow")
+			exit(1)
+		}
 	}
 
-	// Read and parse transactions
-	transactions, err := readTransactions(*inputFile, *fileType)
+	// Stop on SIGINT. A second SIGINT kills the process as usual.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if *serveAddr != "" {
+		server := &http.Server{Addr: *serveAddr, Handler: newServer(config, *timeout, *maxTransactions, redactions)}
+		stopped := make(chan struct{})
+		go func() {
+			<-ctx.Done()
+			server.Shutdown(context.Background())
+			close(stopped)
+		}()
+
+		slog.Info("listening", "addr", *serveAddr)
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			slog.Error("running server", "error", err)
+			exit(1)
+		}
+		<-stopped
+		slog.Info("server stopped")
+		return
+	}
+
+	// Fail before any work when an input is missing
+	for _, path := range inputFiles.paths {
+		if path == "-" || isURL(path) {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			slog.Error("opening input", "error", err)
+			exit(1)
+		}
+	}
+
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	var skipped int
+	readOpts := fraud.ReadOptions{
+		TimeFormat:  *timeFormat,
+		SkipInvalid: *skipInvalid,
+		OnInvalid: func(err error) {
+			skipped++
+			slog.Warn("skipping invalid row", "error", err)
+		},
+		NoHeader:         *noHeader,
+		FieldsFromHeader: *fieldsFromHeader,
+		JSONPath:         *jsonPath,
+	}
+
+	delimiter, err := parseDelimiter(*csvDelimiter)
 	if err != nil {
-		fmt.Printf("Error reading transactions: %v\n", err)
-		os.Exit(1)
+		slog.Error("parsing -csv-delimiter", "error", err)
+		exit(1)
 	}
+	readOpts.Delimiter = delimiter
 
-	// Detect fraudulent transactions
-	fraudResults := detectFraud(transactions, config)
+	if readOpts.DecimalSeparator, err = parseSeparator(*decimalSeparator); err != nil {
+		slog.Error("parsing -decimal-separator", "error", err)
+		exit(1)
+	}
+	if readOpts.ThousandsSeparator, err = parseSeparator(*thousandsSeparator); err != nil {
+		slog.Error("parsing -thousands-separator", "error", err)
+		exit(1)
+	}
+	if err := readOpts.Validate(); err != nil {
+		slog.Error("invalid number format", "error", err)
+		exit(1)
+	}
 
-	// Display results
-	displayResults(fraudResults)
+	if *csvColumns != "" {
+		columns, err := parseColumns(*csvColumns)
+		if err != nil {
+			slog.Error("parsing -csv-columns", "error", err)
+			exit(1)
+		}
+		readOpts.Columns = columns
+	}
+
+	var prog *progress
+	if *showProgress && !*quiet && isTerminal(os.Stderr) {
+		prog = startProgress(os.Stderr)
+		config.OnBatchDone = prog.batchDone
+	}
+
+	// With -checkpoint, only transactions after the last run's are reported.
+	// Earlier ones within the rules' lookback are read again as context.
+	var cp checkpoint
+	if *checkpointFile != "" {
+		var err error
+		if cp, err = readCheckpoint(*checkpointFile); err != nil {
+			slog.Error("reading checkpoint", "file", *checkpointFile, "error", err)
+			exit(1)
+		}
+	}
+	cutoff := cp.LastTimestamp.Add(-config.Lookback())
+	latest := checkpoint{LastTimestamp: cp.LastTimestamp, LastIDs: slices.Clone(cp.LastIDs)}
+	stale := func(tx fraud.Transaction) bool {
+		latest.advance(tx)
+		return !cp.LastTimestamp.IsZero() && tx.Timestamp.Before(cutoff)
+	}
+
+	// With -dedup-input, later transactions reusing an ID are dropped
+	var seenIDs *idSet
+	if *dedupInput {
+		seenIDs = newIDSet()
+	}
+	duplicate := seenIDs.duplicate
+
+	// With -kafka, a topic is scored until interrupted
+	if *kafkaSpec != "" {
+		opts, err := parseKafkaOptions(*kafkaSpec)
+		if err != nil {
+			slog.Error("parsing -kafka", "error", err)
+			exit(1)
+		}
+		if err := consumeKafka(ctx, opts, config, redactions, readOpts.OnInvalid); err != nil {
+			slog.Error("consuming from kafka", "error", err)
+			exit(1)
+		}
+		return
+	}
+
+	// With -follow, flags are printed as they occur until interrupted
+	if *follow {
+		columns := selectedColumns
+		if len(columns) == 0 {
+			columns = defaultTableColumns
+		}
+		if !*quiet {
+			fmt.Println(strings.Join(columns, "\t"))
+		}
 
-	// Export results if output file specified
-	if config.OutputFile != "" {
-		err := exportResults(fraudResults, config.OutputFile)
+		detector := fraud.NewStreamDetector(config)
+		var rows, flagged int
+		err := followTransactions(ctx, inputFiles.paths[0], func(tx fraud.Transaction) error {
+			if duplicate(tx) {
+				return nil
+			}
+			rows++
+			for _, result := range config.FilterRisk(fraud.MergeResults(detector.Add(tx))) {
+				flagged++
+				if !*quiet {
+					printFlag(os.Stdout, redactions.result(result), config, columns)
+				}
+			}
+			return nil
+		}, readOpts.OnInvalid)
 		if err != nil {
-			fmt.Printf("Error exporting results: %v\n", err)
+			slog.Error("following input", "file", inputFiles.paths[0], "error", err)
+			exit(1)
+		}
+		slog.Info("follow stopped", "rows", rows, "skipped", skipped, "flagged", flagged)
+		return
+	}
+
+	var fraudResults []fraud.FraudResult
+	var scanned, sampledFrom int
+	if *stream {
+		// Detect fraudulent transactions while reading
+		detector := fraud.NewStreamDetector(config)
+		var results []fraud.FraudResult
+		err := streamInputs(ctx, inputFiles.paths, *fileType, *gzipped, headers.header, readOpts, limitTransactions(*maxTransactions, func(tx fraud.Transaction) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			prog.rowRead()
+			if stale(tx) || duplicate(tx) {
+				return nil
+			}
+			scanned++
+			added := detector.Add(tx)
+			results = append(results, added...)
+			if *anyMatch && len(config.FilterRisk(fraud.MergeResults(added))) > 0 {
+				return errFirstMatch
+			}
+			return nil
+		}))
+		if err != nil && !errors.Is(err, errFirstMatch) && ctx.Err() == nil {
+			prog.stop()
+			slog.Error("reading transactions", "error", err)
+			exit(1)
+		}
+		fraudResults = config.FilterRisk(fraud.MergeResults(results))
+
+		if n := detector.Unordered(); n > 0 {
+			slog.Warn("input is not in timestamp order per account; -stream may have missed detections, rerun without -stream to sort it", "out_of_order", n)
+		}
+		if n := detector.Overflowed(); n > 0 {
+			slog.Warn("-stream-buffer filled up; bursts larger than it may be undercounted", "dropped", n)
+		}
+	} else {
+		// Read and parse transactions
+		var transactions []fraud.Transaction
+		collect := limitTransactions(*maxTransactions, func(tx fraud.Transaction) error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			prog.rowRead()
+			if stale(tx) || duplicate(tx) {
+				return nil
+			}
+			transactions = append(transactions, tx)
+			return nil
+		})
+
+		// With -cache, parsed transactions are reused while the input is
+		// unchanged, and saved for next time when it has changed
+		var cacheInfo []cachedInput
+		var settings string
+		var cached []fraud.Transaction
+		hit := false
+		if *cacheFile != "" {
+			var err error
+			if cacheInfo, err = cacheInputs(inputFiles.paths); err != nil {
+				slog.Warn("input cannot be cached", "error", err)
+			} else {
+				settings = cacheSettings(*fileType, *gzipped, readOpts)
+				cached, hit = readCache(*cacheFile, cacheInfo, settings)
+			}
+		}
+
+		var err error
+		if hit {
+			slog.Info("transactions loaded from cache", "file", *cacheFile, "rows", len(cached))
+			for _, tx := range cached {
+				if err = collect(tx); err != nil {
+					break
+				}
+			}
 		} else {
-			fmt.Printf("\nResults exported to %s\n", config.OutputFile)
+			read := collect
+			if cacheInfo != nil {
+				read = func(tx fraud.Transaction) error {
+					cached = append(cached, tx)
+					return collect(tx)
+				}
+			}
+			err = streamInputs(ctx, inputFiles.paths, *fileType, *gzipped, headers.header, readOpts, read)
+		}
+		if err != nil && ctx.Err() == nil {
+			prog.stop()
+			slog.Error("reading transactions", "error", err)
+			exit(1)
+		}
+
+		if !hit && cacheInfo != nil && ctx.Err() == nil {
+			if err := writeCache(*cacheFile, cacheInfo, settings, cached); err != nil {
+				slog.Warn("writing cache", "file", *cacheFile, "error", err)
+			}
+		}
+
+		if *sampleFraction > 0 && ctx.Err() == nil {
+			if *seed == 0 {
+				*seed = time.Now().UnixNano()
+			}
+			sampledFrom = len(transactions)
+			transactions = sampleInput(transactions, *sampleFraction, rand.New(rand.NewSource(*seed)))
+			slog.Info("transactions sampled", "sample", len(transactions), "of", sampledFrom, "seed", *seed)
 		}
+
+		// Detect fraudulent transactions, unless reading was interrupted
+		scanned = len(transactions)
+		if ctx.Err() == nil {
+			fraudResults, _ = fraud.DetectContext(ctx, transactions, config)
+		}
+
+		if *explain && ctx.Err() == nil {
+			prog.stop()
+			if err := writeExplanations(os.Stderr, fraud.Explain(transactions, config), config, redactions); err != nil {
+				slog.Error("writing explanations", "error", err)
+			}
+		}
+	}
+	prog.stop()
+
+	if duplicates := seenIDs.count(); duplicates > 0 {
+		slog.Warn("dropped transactions with duplicate IDs", "count", duplicates)
+	}
+
+	interrupted := ctx.Err() != nil
+	if interrupted {
+		slog.Error("analysis stopped early; results are partial", "error", ctx.Err())
+	}
+
+	if !cp.LastTimestamp.IsZero() {
+		fraudResults = slices.DeleteFunc(fraudResults, func(result fraud.FraudResult) bool {
+			return cp.seen(result.Transaction)
+		})
+	}
+
+	slog.Info("analysis complete",
+		"inputs", inputFiles.paths,
+		"rows", scanned,
+		"skipped", skipped,
+		"flagged", len(fraudResults),
+		"duration", time.Since(start),
+	)
+
+	fraud.SortResults(fraudResults, *sortBy)
+
+	// With -any, the first flagged transaction is all that is reported
+	if *anyMatch {
+		if len(fraudResults) == 0 {
+			if interrupted {
+				exit(1)
+			}
+			if !*quiet {
+				fmt.Println("No fraudulent transactions detected.")
+			}
+			return
+		}
+		if !*quiet {
+			first := redactions.result(fraudResults[0])
+			fmt.Printf("Fraud detected: transaction %s of account %s: %s\n", first.Transaction.ID, first.Transaction.AccountID, first.Reason)
+		}
+		exit(exitFraudDetected)
+	}
+
+	saveFailed := false
+
+	if *countOnly && !*quiet {
+		accounts := make(map[string]bool)
+		for _, result := range fraudResults {
+			accounts[result.Transaction.AccountID] = true
+		}
+		fmt.Printf("%d flagged transactions across %d accounts\n", len(fraudResults), len(accounts))
+	} else if !*countOnly {
+		// Display results, unless -quiet leaves stdout empty
+		show := func(results []fraud.FraudResult) {
+			color := !*noColor && isTerminal(os.Stdout)
+			if grouped {
+				displayGrouped(results, config, selectedColumns, color, redactions)
+			} else {
+				displayResults(redactions.results(results), config, selectedColumns, color)
+			}
+		}
+		if !*quiet {
+			if *top > 0 && len(fraudResults) > *top {
+				ranking := "most severe"
+				if *topBy == "score" {
+					ranking = "highest-scoring"
+				}
+				fmt.Printf("Showing the %d %s of %d flagged transactions.\n", *top, ranking, len(fraudResults))
+				if !*summaryOnly {
+					if *topBy == "score" {
+						show(topResultsByScore(fraudResults, *top))
+					} else {
+						show(topResults(fraudResults, *top, weights))
+					}
+				}
+			} else if *maxTableRows > 0 && len(fraudResults) > *maxTableRows {
+				// Building a table this large takes more memory than it is worth
+				fmt.Printf("%d flagged transactions exceed -max-table-rows (%d); table skipped, use -output to export them.\n", len(fraudResults), *maxTableRows)
+			} else if !*summaryOnly {
+				show(fraudResults)
+			}
+			printSummary(fraudResults, config, scanned, sampledFrom)
+			if *byMerchant {
+				printMerchants(fraudResults, config, redactions)
+			}
+			if *histogram {
+				printHistogram(os.Stdout, fraudResults, config, bounds)
+			}
+		}
+
+		// Export results if output file specified
+		if *outputFile != "" {
+			err := exportResults(fraudResults, config, scanned, *outputFile, *outputFormat, *appendOutput, selectedColumns, grouped, redactions)
+			if err != nil {
+				slog.Error("exporting results", "file", *outputFile, "error", err)
+				saveFailed = true
+			} else {
+				slog.Info("results exported", "file", *outputFile)
+			}
+		}
+
+		if *sqlitePath != "" {
+			if err := writeResultsSQLite(*sqlitePath, redactions.results(fraudResults), config, start); err != nil {
+				slog.Error("storing results", "db", *sqlitePath, "error", err)
+				saveFailed = true
+			} else {
+				slog.Info("results stored", "db", *sqlitePath, "rows", len(fraudResults))
+			}
+		}
+
+		// Webhook failures are logged without failing the run
+		if *webhookURL != "" && len(fraudResults) > 0 {
+			delivered, err := postWebhook(*webhookURL, redactions.results(fraudResults), *webhookTimeout)
+			if err != nil {
+				slog.Warn("posting results to webhook", "delivered", delivered, "total", len(fraudResults), "error", err)
+				saveFailed = true
+			} else {
+				slog.Info("results posted to webhook", "delivered", delivered)
+			}
+		}
+	}
+
+	if interrupted {
+		exit(1)
+	}
+
+	// The checkpoint only advances once results are shown or saved, so a
+	// failed run, or one whose results went nowhere, is retried in full
+	delivered := !*countOnly && (!*quiet || *outputFile != "" || *sqlitePath != "" || *webhookURL != "")
+	if *checkpointFile != "" && latest.after(cp) {
+		if saveFailed || !delivered {
+			slog.Warn("checkpoint not advanced; results were not delivered", "file", *checkpointFile)
+		} else {
+			if err := writeCheckpoint(*checkpointFile, latest); err != nil {
+				slog.Error("saving checkpoint", "file", *checkpointFile, "error", err)
+				exit(1)
+			}
+			slog.Info("checkpoint saved", "file", *checkpointFile, "last_timestamp", latest.LastTimestamp)
+		}
+	}
+
+	if *failOnDetect && len(fraudResults) > 0 {
+		exit(exitFraudDetected)
 	}
 }
 
-// readTransactions reads transactions from a file based on its type
-func readTransactions(filePath, fileType string) ([]Transaction, error) {
-	file, err := os.Open(filePath)
+// openInput opens the input file, or standard input for a path of "-", or
+// fetches it when the path is an HTTP(S) URL. The input is decompressed when
+// gzipped is set or the path ends in ".gz".
+func openInput(ctx context.Context, filePath string, gzipped bool, header http.Header) (io.ReadCloser, error) {
+	var file io.ReadCloser = io.NopCloser(os.Stdin)
+	if isURL(filePath) {
+		body, err := openURL(ctx, filePath, header)
+		if err != nil {
+			return nil, err
+		}
+		file = body
+	} else if filePath != "-" {
+		f, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+		file = f
+	}
+
+	if !gzipped && !strings.EqualFold(filepath.Ext(inputPath(filePath)), ".gz") {
+		return file, nil
+	}
+
+	gz, err := gzip.NewReader(file)
 	if err != nil {
+		file.Close()
 		return nil, err
 	}
+	return gzipReadCloser{Reader: gz, file: file}, nil
+}
+
+// gzipReadCloser closes both the gzip reader and the underlying file
+type gzipReadCloser struct {
+	*gzip.Reader
+	file io.Closer
+}
+
+func (r gzipReadCloser) Close() error {
+	r.Reader.Close()
+	return r.file.Close()
+}
+
+// inputList is a flag.Value collecting input paths from repeated flags and
+// comma-separated lists. The first Set replaces the default path.
+type inputList struct {
+	paths []string
+	set   bool
+}
+
+func (l *inputList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(l.paths, ",")
+}
+
+func (l *inputList) Set(value string) error {
+	if !l.set {
+		l.paths = nil
+		l.set = true
+	}
+	for _, path := range strings.Split(value, ",") {
+		if path = strings.TrimSpace(path); path != "" {
+			l.paths = append(l.paths, path)
+		}
+	}
+	return nil
+}
+
+// idSet records the transaction IDs seen so far, for dropping later
+// transactions that reuse one. A nil idSet treats no transaction as a
+// duplicate.
+type idSet struct {
+	seen       map[string]bool
+	duplicates int
+}
+
+func newIDSet() *idSet {
+	return &idSet{seen: make(map[string]bool)}
+}
+
+// duplicate reports whether tx reuses the ID of an earlier transaction,
+// recording its ID otherwise
+func (s *idSet) duplicate(tx fraud.Transaction) bool {
+	if s == nil {
+		return false
+	}
+	if s.seen[tx.ID] {
+		s.duplicates++
+		return true
+	}
+	s.seen[tx.ID] = true
+	return false
+}
+
+// count returns the number of duplicates found
+func (s *idSet) count() int {
+	if s == nil {
+		return 0
+	}
+	return s.duplicates
+}
+
+// minutesValue is a flag.Value for a duration given either as a whole
+// number of minutes, e.g. "5", or as a Go duration, e.g. "5m" or "90s"
+type minutesValue struct {
+	time.Duration
+}
+
+func (m *minutesValue) String() string {
+	if m == nil {
+		return ""
+	}
+	if m.Duration%time.Minute == 0 {
+		return strconv.FormatInt(int64(m.Duration/time.Minute), 10)
+	}
+	return m.Duration.String()
+}
+
+func (m *minutesValue) Set(value string) error {
+	if minutes, err := strconv.Atoi(value); err == nil {
+		m.Duration = time.Duration(minutes) * time.Minute
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("expected minutes or a duration such as 5m")
+	}
+	m.Duration = d
+	return nil
+}
+
+// reasonTemplates is a flag.Value collecting rule=template pairs from
+// repeated flags, parsed and checked as they are set
+type reasonTemplates struct {
+	text      []string
+	templates map[string]*template.Template
+}
+
+func (t *reasonTemplates) String() string {
+	if t == nil {
+		return ""
+	}
+	return strings.Join(t.text, " ")
+}
+
+func (t *reasonTemplates) Set(value string) error {
+	rule, text, ok := strings.Cut(value, "=")
+	rule = strings.TrimSpace(rule)
+	if !ok || rule == "" {
+		return fmt.Errorf("expected rule=template, got %q", value)
+	}
+	if _, err := fraud.LookupRules([]string{rule}); err != nil {
+		return err
+	}
+
+	tmpl, err := fraud.ParseReasonTemplate(rule, text)
+	if err != nil {
+		return err
+	}
+	if t.templates == nil {
+		t.templates = make(map[string]*template.Template)
+	}
+	t.templates[rule] = tmpl
+	t.text = append(t.text, value)
+	return nil
+}
+
+// sameFileType reports whether two input types name the same format
+func sameFileType(a, b string) bool {
+	normalize := func(t string) string {
+		t = strings.ToLower(t)
+		if t == "ndjson" {
+			return "jsonl"
+		}
+		return t
+	}
+	return normalize(a) == normalize(b)
+}
+
+// streamInputs reads transactions from each input file in order, calling fn
+// for each one as it is parsed. Errors name the file when there are several.
+func streamInputs(ctx context.Context, filePaths []string, fileType string, gzipped bool, header http.Header, opts fraud.ReadOptions, fn func(fraud.Transaction) error) error {
+	for _, filePath := range filePaths {
+		rows := 0
+		err := streamTransactions(ctx, filePath, fileType, gzipped, header, opts, func(tx fraud.Transaction) error {
+			rows++
+			return fn(tx)
+		})
+		if err != nil && len(filePaths) > 1 {
+			return fmt.Errorf("%s: %w", filePath, err)
+		}
+		if err != nil {
+			return err
+		}
+		if rows == 0 {
+			// Distinguish an empty input from one where nothing was flagged
+			slog.Warn("0 transactions found in input", "file", filePath)
+		} else {
+			slog.Info("input read", "file", filePath, "rows", rows)
+		}
+	}
+	return nil
+}
+
+// streamTransactions reads transactions from a file based on its type,
+// calling fn for each one as it is parsed
+func streamTransactions(ctx context.Context, filePath, fileType string, gzipped bool, header http.Header, opts fraud.ReadOptions, fn func(fraud.Transaction) error) error {
+	file, err := openInput(ctx, filePath, gzipped, header)
+	if err != nil {
+		return err
+	}
 	defer file.Close()
 
-	switch strings.ToLower(fileType) {
-	case "csv":
-		return readCSV(file)
-	case "json":
-		return readJSON(file)
-	default:
-		return nil, fmt.Errorf("unsupported file type: %s", fileType)
+	if strings.EqualFold(fileType, "auto") {
+		return opts.StreamAuto(file, inputPath(filePath), fn)
 	}
+	return opts.StreamFormat(file, fileType, fn)
 }
 
-// readCSV reads transactions from a CSV file
-func readCSV(file io.Reader) ([]Transaction, error) {
-	reader := csv.NewReader(file)
-	records, err := reader.ReadAll()
+// errTooManyTransactions is returned once an input exceeds -max-transactions
+var errTooManyTransactions = errors.New("too many transactions")
+
+// errFirstMatch stops reading in -stream mode once -any has a match
+var errFirstMatch = errors.New("first match found")
+
+// limitTransactions wraps fn to fail once more than max transactions have
+// been read, stopping the reader before the rest is loaded. A max of 0 means
+// no limit.
+func limitTransactions(max int, fn func(fraud.Transaction) error) func(fraud.Transaction) error {
+	if max <= 0 {
+		return fn
+	}
+	n := 0
+	return func(tx fraud.Transaction) error {
+		if n++; n > max {
+			return fmt.Errorf("%w: more than %d (-max-transactions)", errTooManyTransactions, max)
+		}
+		return fn(tx)
+	}
+}
+
+// readList reads a file with one entry per line, skipping blank lines and
+// lines starting with #
+func readList(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
 
-	var transactions []Transaction
-	for i, record := range records {
-		// Skip header
-		if i == 0 {
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
+		entries = append(entries, line)
+	}
+	return entries, scanner.Err()
+}
 
-		if len(record) < 5 {
-			return nil, fmt.Errorf("invalid CSV format at line %d", i+1)
+// readHolidays reads a file of holiday dates, one YYYY-MM-DD date per line
+func readHolidays(filePath string) (map[string]bool, error) {
+	dates, err := readList(filePath)
+	if err != nil {
+		return nil, err
+	}
+	holidays := make(map[string]bool, len(dates))
+	for _, date := range dates {
+		if _, err := time.Parse(fraud.HolidayLayout, date); err != nil {
+			return nil, fmt.Errorf("invalid holiday date %q (use YYYY-MM-DD)", date)
 		}
+		holidays[date] = true
+	}
+	return holidays, nil
+}
 
-		amount, err := strconv.ParseFloat(record[1], 64)
+// ruleColors maps built-in rules to their ANSI color, most severe first, so
+// a row that matched several rules takes the color of the worst one
+var ruleColors = []struct {
+	rule  string
+	color int
+}{
+	{"high-amount", tablewriter.FgRedColor},
+	{"blocklist", tablewriter.FgRedColor},
+	{"impossible-travel", tablewriter.FgRedColor},
+	{"rapid-succession", tablewriter.FgYellowColor},
+	{"velocity", tablewriter.FgYellowColor},
+	{"duplicate", tablewriter.FgCyanColor},
+	{"off-hours", tablewriter.FgCyanColor},
+	{"weekend-holiday", tablewriter.FgCyanColor},
+	{"daily-limit", tablewriter.FgYellowColor},
+	{"outlier", tablewriter.FgYellowColor},
+	{"new-merchant", tablewriter.FgYellowColor},
+	{"min-amount", tablewriter.FgMagentaColor},
+	{"zero-amount", tablewriter.FgMagentaColor},
+	{"structuring", tablewriter.FgRedColor},
+	{"high-risk-mcc", tablewriter.FgYellowColor},
+	{"new-account-burst", tablewriter.FgRedColor},
+	{"card-testing", tablewriter.FgRedColor},
+	{"shared-device", tablewriter.FgRedColor},
+	{"multi-card", tablewriter.FgYellowColor},
+	{"blocked-country", tablewriter.FgRedColor},
+	{"round-amount", tablewriter.FgCyanColor},
+}
+
+// rowColor returns the ANSI color for the rules a result matched, or 0 for
+// none
+func rowColor(rules []string) int {
+	for _, rc := range ruleColors {
+		if slices.Contains(rules, rc.rule) {
+			return rc.color
+		}
+	}
+	return 0
+}
+
+// isTerminal reports whether f is attached to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// lowerSet returns a set of the lowercased entries
+func lowerSet(entries []string) map[string]bool {
+	set := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		set[strings.ToLower(entry)] = true
+	}
+	return set
+}
+
+// merchantSet returns a set of the merchant names as normalized for the
+// merchant-based rules
+func merchantSet(names []string, config fraud.Config) map[string]bool {
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[config.NormalizeMerchant(name)] = true
+	}
+	return set
+}
+
+// readMerchantAliases reads a CSV file of pattern,name rows, where pattern is
+// a regular expression matched case-insensitively against merchant names.
+// Lines starting with # are skipped.
+func readMerchantAliases(filePath string) ([]fraud.MerchantAlias, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	var aliases []fraud.MerchantAlias
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return aliases, nil
+		}
 		if err != nil {
-			return nil, fmt.Errorf("invalid amount at line %d: %v", i+1, err)
+			return nil, err
 		}
 
-		timestamp, err := time.Parse(time.RFC3339, record[2])
+		line, _ := reader.FieldPos(0)
+		pattern, err := regexp.Compile("(?i)" + record[0])
 		if err != nil {
-			return nil, fmt.Errorf("invalid timestamp at line %d: %v", i+1, err)
+			return nil, fmt.Errorf("invalid pattern at line %d: %v", line, err)
+		}
+		name := strings.TrimSpace(record[1])
+		if name == "" {
+			return nil, fmt.Errorf("empty merchant name at line %d", line)
 		}
+		aliases = append(aliases, fraud.MerchantAlias{Pattern: pattern, Name: name})
+	}
+}
 
-		transactions = append(transactions, Transaction{
-			ID:        record[0],
-			Amount:    amount,
-			Timestamp: timestamp,
-			AccountID: record[3],
-			Merchant:  record[4],
-		})
+// parseThresholds parses comma-separated name=amount pairs into a map keyed
+// by lowercased name
+func parseThresholds(value string) (map[string]float64, error) {
+	thresholds := make(map[string]float64)
+	for _, pair := range strings.Split(value, ",") {
+		name, amount, ok := strings.Cut(pair, "=")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !ok || name == "" {
+			return nil, fmt.Errorf("expected name=amount, got %q", pair)
+		}
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(amount), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid amount for %s: %v", name, err)
+		}
+		thresholds[name] = threshold
 	}
+	return thresholds, nil
+}
 
-	return transactions, nil
+// parseCountries parses comma-separated ISO 3166 country codes into a set of
+// uppercased codes
+func parseCountries(value string) (map[string]bool, error) {
+	countries := make(map[string]bool)
+	for _, code := range strings.Split(value, ",") {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if len(code) < 2 || len(code) > 3 || strings.Trim(code, "ABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+			return nil, fmt.Errorf("expected a 2- or 3-letter country code, got %q", code)
+		}
+		countries[code] = true
+	}
+	return countries, nil
 }
 
-// readJSON reads transactions from a JSON file
-func readJSON(file io.Reader) ([]Transaction, error) {
-	var transactions []Transaction
-	decoder := json.NewDecoder(file)
-	err := decoder.Decode(&transactions)
+// parseDelimiter parses a CSV delimiter given as a single character or "tab"
+func parseDelimiter(value string) (rune, error) {
+	if strings.EqualFold(value, "tab") || value == `\t` {
+		return '\t', nil
+	}
+	runes := []rune(value)
+	if len(runes) != 1 || runes[0] == '"' || runes[0] == '\r' || runes[0] == '\n' {
+		return 0, fmt.Errorf("expected a single character or tab, got %q", value)
+	}
+	return runes[0], nil
+}
+
+// parseSeparator parses a number separator: a single character, "space", or
+// empty for none
+func parseSeparator(value string) (rune, error) {
+	if value == "" {
+		return 0, nil
+	}
+	if strings.EqualFold(value, "space") {
+		return ' ', nil
+	}
+	runes := []rune(value)
+	if len(runes) != 1 {
+		return 0, fmt.Errorf("expected a single character or space, got %q", value)
+	}
+	return runes[0], nil
+}
+
+// parseColumns parses comma-separated field=column pairs, where a column is a
+// 0-based index or a header name
+func parseColumns(value string) (map[string]string, error) {
+	columns := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		field, column, ok := strings.Cut(pair, "=")
+		field = strings.ToLower(strings.TrimSpace(field))
+		column = strings.Trim(strings.TrimSpace(column), `"`)
+		if !ok || field == "" || column == "" {
+			return nil, fmt.Errorf("expected field=column, got %q", pair)
+		}
+		columns[field] = column
+	}
+	return columns, nil
+}
+
+// readThresholds reads a CSV file of name,amount rows into a map keyed by
+// lowercased name. A header row and lines starting with # are skipped.
+func readThresholds(filePath string) (map[string]float64, error) {
+	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, err
 	}
-	return transactions, nil
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 2
+	reader.TrimLeadingSpace = true
+
+	thresholds := make(map[string]float64)
+	for i := 0; ; i++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return thresholds, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		threshold, err := strconv.ParseFloat(strings.TrimSpace(record[1]), 64)
+		if err != nil && i == 0 {
+			continue // Header row
+		}
+		if err != nil {
+			line, _ := reader.FieldPos(1)
+			return nil, fmt.Errorf("invalid threshold at line %d: %v", line, err)
+		}
+		thresholds[strings.ToLower(strings.TrimSpace(record[0]))] = threshold
+	}
 }
 
-// detectFraud applies fraud detection rules to transactions
-func detectFraud(transactions []Transaction, config Config) []FraudResult {
-	var results []FraudResult
-	var mu sync.Mutex
-	var wg sync.WaitGroup
+// displayResults shows the fraud results in a table format, coloring rows
+// by reason when color is set. Columns defaults to the table columns.
+func displayResults(results []fraud.FraudResult, config fraud.Config, columns []string, color bool) {
+	if len(results) == 0 {
+		fmt.Println("No fraudulent transactions detected.")
+		return
+	}
+
+	fmt.Println("Potentially Fraudulent Transactions:")
+	printTable(results, config, columns, color)
+}
 
-	// Process transactions in batches using goroutines
-	batchSize := 100
-	batches := len(transactions) / batchSize
-	if len(transactions)%batchSize != 0 {
-		batches++
+// printTable prints results as a table of the given columns, coloring rows
+// by reason when color is set
+func printTable(results []fraud.FraudResult, config fraud.Config, columns []string, color bool) {
+	var buf bytes.Buffer
+	table := tablewriter.NewWriter(&buf)
+	if len(columns) == 0 {
+		columns = defaultTableColumns
 	}
+	titles := make([]string, len(columns))
+	for i, column := range columns {
+		titles[i] = columnTitles[column]
+	}
+	table.SetHeader(titles)
+	table.SetBorder(false)
+	table.SetRowLine(true)
 
-	for i := 0; i < batches; i++ {
-		start := i * batchSize
-		end := start + batchSize
-		if end > len(transactions) {
-			end = len(transactions)
+	for _, result := range results {
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = columnValue(column, result, config, true)
 		}
+		table.Append(row)
+	}
+	table.Render()
 
-		wg.Add(1)
-		go func(batch []Transaction) {
-			defer wg.Done()
-			batchResults := processBatch(batch, config)
-			
-			mu.Lock()
-			results = append(results, batchResults...)
-			mu.Unlock()
-		}(transactions[start:end])
+	if !color {
+		os.Stdout.Write(buf.Bytes())
+		return
 	}
 
-	wg.Wait()
+	// Color whole row blocks after rendering so wrapping and alignment are
+	// identical to the plain table. Rows are delimited by separator lines.
+	row := -1
+	for _, line := range strings.SplitAfter(buf.String(), "\n") {
+		if strings.Trim(line, "-+ \n") == "" {
+			if line != "" {
+				row++
+			}
+			fmt.Print(line)
+			continue
+		}
 
-	return results
+		c := 0
+		if row >= 0 && row < len(results) {
+			c = rowColor(results[row].Rules)
+		}
+		if c == 0 {
+			fmt.Print(line)
+			continue
+		}
+		fmt.Printf("\x1b[%dm%s\x1b[0m\n", c, strings.TrimSuffix(line, "\n"))
+	}
+}
+
+// summary holds aggregate statistics for a set of fraud results
+type summary struct {
+	Scanned  int         // Transactions analyzed
+	Flagged  int         // Transactions flagged
+	Amount   string      // Flagged amount, totaled per currency
+	Accounts int         // Distinct accounts flagged
+	ByRule   []ruleCount // Flagged transactions per rule, most frequent first
+}
+
+// ruleCount is the number of transactions flagged by one rule
+type ruleCount struct {
+	Rule  string
+	Count int
 }
 
-// processBatch processes a batch of transactions for fraud detection
-func processBatch(batch []Transaction, config Config) []FraudResult {
-	var batchResults []FraudResult
+// summarize computes aggregate statistics for the fraud results out of the
+// total number of transactions scanned
+func summarize(results []fraud.FraudResult, config fraud.Config, total int) summary {
+	// Amounts are totaled per currency
+	amounts := make(map[fraud.Currency]float64)
+	accounts := make(map[string]bool)
+	byRule := make(map[string]int)
+	for _, result := range results {
+		amounts[config.CurrencyOf(result.Transaction)] += result.Transaction.Amount
+		accounts[result.Transaction.AccountID] = true
 
-	for i, tx := range batch {
-		// Rule 1: High amount
-		if tx.Amount > config.HighAmountThreshold {
-			batchResults = append(batchResults, FraudResult{
-				Transaction: tx,
-				Reason:      fmt.Sprintf("High amount: $%.2f", tx.Amount),
-			})
+		for _, rule := range result.Rules {
+			byRule[rule]++
 		}
+	}
 
-		// Rule 2: Rapid succession (check next transactions in the batch)
-		for j := i + 1; j < len(batch); j++ {
-			nextTx := batch[j]
-			if nextTx.AccountID != tx.AccountID {
-				continue
-			}
+	counts := make([]ruleCount, 0, len(byRule))
+	for rule, count := range byRule {
+		counts = append(counts, ruleCount{rule, count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Rule < counts[j].Rule
+	})
 
-			timeDiff := nextTx.Timestamp.Sub(tx.Timestamp)
-			if timeDiff < config.TimeWindow && timeDiff > 0 {
-				batchResults = append(batchResults, FraudResult{
-					Transaction: tx,
-					Reason:      fmt.Sprintf("Rapid transaction: %v later with $%.2f", timeDiff, nextTx.Amount),
-				})
-				batchResults = append(batchResults, FraudResult{
-					Transaction: nextTx,
-					Reason:      fmt.Sprintf("Rapid transaction: following $%.2f after %v", tx.Amount, timeDiff),
-				})
-			}
+	return summary{
+		Scanned:  total,
+		Flagged:  len(results),
+		Amount:   formatTotals(amounts, config),
+		Accounts: len(accounts),
+		ByRule:   counts,
+	}
+}
+
+// printSummary prints aggregate statistics for the fraud results out of the
+// total number of transactions scanned. A nonzero sampledFrom is the number
+// of transactions the scanned ones were sampled from.
+func printSummary(results []fraud.FraudResult, config fraud.Config, total, sampledFrom int) {
+	stats := summarize(results, config, total)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\nSummary:")
+	fmt.Fprintf(w, "  Transactions scanned:\t%d\n", stats.Scanned)
+	if sampledFrom > 0 {
+		fmt.Fprintf(w, "  Sampled from:\t%d (results are from a random sample)\n", sampledFrom)
+	}
+	fmt.Fprintf(w, "  Transactions flagged:\t%d\n", stats.Flagged)
+	fmt.Fprintf(w, "  Amount flagged:\t%s\n", stats.Amount)
+	fmt.Fprintf(w, "  Accounts involved:\t%d\n", stats.Accounts)
+	if len(stats.ByRule) > 0 {
+		fmt.Fprintln(w, "  By rule:")
+	}
+	for _, rc := range stats.ByRule {
+		fmt.Fprintf(w, "    %s:\t%d\n", rc.Rule, rc.Count)
+	}
+	w.Flush()
+}
+
+// merchantTotal is the flagged transactions and amount of one merchant
+type merchantTotal struct {
+	Merchant string
+	Count    int
+	Amounts  map[fraud.Currency]float64
+	sum      float64 // Amount across currencies, for ranking only
+}
+
+// rankMerchants totals the fraud results by normalized merchant, named as
+// first seen, most flagged first and then by flagged amount
+func rankMerchants(results []fraud.FraudResult, config fraud.Config) []merchantTotal {
+	index := make(map[string]int)
+	var merchants []merchantTotal
+	for _, result := range results {
+		tx := result.Transaction
+		key := config.NormalizeMerchant(tx.Merchant)
+		i, ok := index[key]
+		if !ok {
+			i = len(merchants)
+			index[key] = i
+			merchants = append(merchants, merchantTotal{Merchant: tx.Merchant, Amounts: make(map[fraud.Currency]float64)})
 		}
+		merchants[i].Count++
+		merchants[i].Amounts[config.CurrencyOf(tx)] += tx.Amount
+		merchants[i].sum += tx.Amount
 	}
 
-	return batchResults
+	sort.Slice(merchants, func(i, j int) bool {
+		a, b := merchants[i], merchants[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if a.sum != b.sum {
+			return a.sum > b.sum
+		}
+		return a.Merchant < b.Merchant
+	})
+	return merchants
 }
 
-// displayResults shows the fraud results in a table format
-func displayResults(results []FraudResult) {
-	if len(results) == 0 {
-		fmt.Println("No fraudulent transactions detected.")
+// printMerchants prints a table of merchants ranked by flagged transactions
+// and flagged amount
+func printMerchants(results []fraud.FraudResult, config fraud.Config, redactions *redactor) {
+	merchants := rankMerchants(results, config)
+	if len(merchants) == 0 {
 		return
 	}
 
 	table := tablewriter.NewWriter(os.Stdout)
-	table.SetHeader([]string{"ID", "Account", "Merchant", "Amount", "Timestamp", "Reason"})
+	table.SetHeader([]string{"Rank", "Merchant", "Flagged", "Amount"})
 	table.SetBorder(false)
-	table.SetRowLine(true)
-
-	for _, result := range results {
-		tx := result.Transaction
-		table.Append([]string{
-			tx.ID,
-			tx.AccountID,
-			tx.Merchant,
-			fmt.Sprintf("$%.2f", tx.Amount),
-			tx.Timestamp.Format(time.RFC3339),
-			result.Reason,
-		})
+	for i, m := range merchants {
+		table.Append([]string{strconv.Itoa(i + 1), redactions.shownMerchant(m.Merchant), strconv.Itoa(m.Count), formatTotals(m.Amounts, config)})
 	}
 
-	fmt.Println("Potentially Fraudulent Transactions:")
+	fmt.Println("\nFlagged Merchants:")
 	table.Render()
 }
 
-// exportResults writes the fraud results to a file
-func exportResults(results []FraudResult, filePath string) error {
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
+// formatTotals writes per-currency totals ordered by currency code, e.g.
+// "$1500.00, ¥2000"
+func formatTotals(amounts map[fraud.Currency]float64, config fraud.Config) string {
+	if len(amounts) == 0 {
+		return config.FormatAmount(config.CurrencyOf(fraud.Transaction{}), 0)
 	}
-	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-	return encoder.Encode(results)
-}
\ No newline at end of file
+	currencies := make([]fraud.Currency, 0, len(amounts))
+	for currency := range amounts {
+		currencies = append(currencies, currency)
+	}
+	sort.Slice(currencies, func(i, j int) bool {
+		return currencies[i].Code < currencies[j].Code
+	})
+
+	totals := make([]string, len(currencies))
+	for i, currency := range currencies {
+		totals[i] = config.FormatAmount(currency, amounts[currency])
+	}
+	return strings.Join(totals, ", ")
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// resultIDs returns the transaction IDs of results, in order
+func resultIDs(results []fraud.FraudResult) []string {
+	var ids []string
+	for _, result := range results {
+		ids = append(ids, result.Transaction.ID)
+	}
+	return ids
+}
+
+func TestBlocklistFile(t *testing.T) {
+	path := writeTemp(t, "blocklist.txt", "# Known bad merchants\nSketchyShop\n\n  Fraud Mart  \n")
+	merchants, err := readList(path)
+	if err != nil {
+		t.Fatalf("readList: %v", err)
+	}
+
+	// Merchants are keyed by the name the merchant rules compare
+	got := merchantSet(merchants, fraud.Config{})
+	if want := map[string]bool{"sketchyshop": true, "fraud mart": true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("blocklist = %v, want %v", got, want)
+	}
+}
+
+func TestParseThresholds(t *testing.T) {
+	thresholds, err := parseThresholds("Withdrawal=300, transfer = 5000")
+	if err != nil {
+		t.Fatalf("parseThresholds: %v", err)
+	}
+	if want := map[string]float64{"withdrawal": 300, "transfer": 5000}; !reflect.DeepEqual(thresholds, want) {
+		t.Errorf("thresholds = %v, want %v", thresholds, want)
+	}
+
+	for _, value := range []string{"withdrawal", "=300", "withdrawal=lots"} {
+		if _, err := parseThresholds(value); err == nil {
+			t.Errorf("parseThresholds(%q) accepted an invalid value", value)
+		}
+	}
+}
+
+func TestAccountThresholds(t *testing.T) {
+	path := writeTemp(t, "thresholds.csv", "account_id,threshold\n# Business accounts\nBiz-1,5000\n")
+	thresholds, err := readThresholds(path)
+	if err != nil {
+		t.Fatalf("readThresholds: %v", err)
+	}
+	if want := map[string]float64{"biz-1": 5000}; !reflect.DeepEqual(thresholds, want) {
+		t.Errorf("thresholds = %v, want %v", thresholds, want)
+	}
+
+	if _, err := readThresholds(writeTemp(t, "bad.csv", "account_id,threshold\nA,lots\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("readThresholds error = %v, want one naming line 2", err)
+	}
+}
+
+func TestIDSet(t *testing.T) {
+	transactions := []fraud.Transaction{
+		{ID: "1", AccountID: "A"},
+		{ID: "2", AccountID: "A"},
+		{ID: "1", AccountID: "A"}, // An upstream retry
+		{ID: "3", AccountID: "B"},
+		{ID: "1", AccountID: "A"},
+	}
+
+	ids := newIDSet()
+	var kept []string
+	for _, tx := range transactions {
+		if !ids.duplicate(tx) {
+			kept = append(kept, tx.ID)
+		}
+	}
+	if want := []string{"1", "2", "3"}; !slices.Equal(kept, want) {
+		t.Errorf("kept %v, want %v", kept, want)
+	}
+	if got := ids.count(); got != 2 {
+		t.Errorf("count() = %d, want 2", got)
+	}
+
+	// Without -dedup-input nothing is dropped
+	var off *idSet
+	for _, tx := range transactions {
+		if off.duplicate(tx) {
+			t.Errorf("nil idSet dropped transaction %s", tx.ID)
+		}
+	}
+	if got := off.count(); got != 0 {
+		t.Errorf("nil count() = %d, want 0", got)
+	}
+}
+
+func TestParseCSVLayoutFlags(t *testing.T) {
+	for value, want := range map[string]rune{",": ',', "tab": '\t', `\t`: '\t', ";": ';'} {
+		if got, err := parseDelimiter(value); err != nil || got != want {
+			t.Errorf("parseDelimiter(%q) = %q, %v; want %q", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"", ";;", `"`} {
+		if _, err := parseDelimiter(value); err == nil {
+			t.Errorf("parseDelimiter(%q) accepted an invalid delimiter", value)
+		}
+	}
+
+	columns, err := parseColumns(`ID=4, amount="Amount USD"`)
+	if err != nil {
+		t.Fatalf("parseColumns: %v", err)
+	}
+	if want := map[string]string{"id": "4", "amount": "Amount USD"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("columns = %v, want %v", columns, want)
+	}
+	if _, err := parseColumns("id"); err == nil {
+		t.Error("parseColumns accepted a field without a column")
+	}
+}
+
+func TestMerchantAliasesFile(t *testing.T) {
+	path := writeTemp(t, "aliases.csv", "# pattern,name\n^amazon(\\.com)?$,Amazon\n^amzn\\b, Amazon\n")
+	aliases, err := readMerchantAliases(path)
+	if err != nil {
+		t.Fatalf("readMerchantAliases: %v", err)
+	}
+	config := fraud.Config{MerchantAliases: aliases}
+	for merchant, want := range map[string]string{
+		"AMAZON":         "amazon",
+		"Amazon.com":     "amazon",
+		"AMZN Mktp":      "amazon",
+		"Amazonia Books": "amazonia books",
+	} {
+		if got := config.NormalizeMerchant(merchant); got != want {
+			t.Errorf("NormalizeMerchant(%q) = %q, want %q", merchant, got, want)
+		}
+	}
+
+	for content, want := range map[string]string{
+		"[,Amazon\n":         "invalid pattern at line 1",
+		"^amazon$, \n":       "empty merchant name at line 1",
+		"^amazon$,A,extra\n": "wrong number of fields",
+	} {
+		if _, err := readMerchantAliases(writeTemp(t, "bad.csv", content)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("readMerchantAliases(%q) error = %v, want one containing %q", content, err, want)
+		}
+	}
+}
+
+func TestParseCountries(t *testing.T) {
+	countries, err := parseCountries("us, Ca,GBR")
+	if err != nil {
+		t.Fatalf("parseCountries: %v", err)
+	}
+	if want := map[string]bool{"US": true, "CA": true, "GBR": true}; !reflect.DeepEqual(countries, want) {
+		t.Errorf("countries = %v, want %v", countries, want)
+	}
+
+	for _, value := range []string{"", "U", "USA1", "U5", "US,,CA"} {
+		if _, err := parseCountries(value); err == nil {
+			t.Errorf("parseCountries(%q) accepted an invalid code", value)
+		}
+	}
+}
+
+func TestReadHolidays(t *testing.T) {
+	holidays, err := readHolidays(writeTemp(t, "holidays.txt", "# Bank holidays\n2024-01-01\n\n2024-12-25\n"))
+	if err != nil {
+		t.Fatalf("readHolidays: %v", err)
+	}
+	if want := map[string]bool{"2024-01-01": true, "2024-12-25": true}; !reflect.DeepEqual(holidays, want) {
+		t.Errorf("holidays = %v, want %v", holidays, want)
+	}
+
+	if _, err := readHolidays(writeTemp(t, "bad.txt", "25/12/2024\n")); err == nil || !strings.Contains(err.Error(), "25/12/2024") {
+		t.Errorf("readHolidays error = %v, want one naming the date", err)
+	}
+}
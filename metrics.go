@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// serverMetrics are the Prometheus metrics of -serve mode
+type serverMetrics struct {
+	registry     *prometheus.Registry
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	transactions prometheus.Counter
+	flagged      *prometheus.CounterVec
+}
+
+// newServerMetrics registers the server metrics, along with Go runtime and
+// process metrics, in a new registry
+func newServerMetrics() *serverMetrics {
+	m := &serverMetrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "fraud_detector_requests_total",
+			Help: "HTTP requests handled, by handler, method, and status code.",
+		}, []string{"handler", "method", "code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "fraud_detector_request_duration_seconds",
+			Help:    "Time taken to handle HTTP requests, by handler, method, and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"handler", "method", "code"}),
+		transactions: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "fraud_detector_transactions_scored_total",
+			Help: "Transactions run through the fraud detection rules.",
+		}),
+		flagged: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "fraud_detector_flagged_total",
+			Help: "Flagged transactions, by the rule that flagged them.",
+		}, []string{"rule"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		m.transactions,
+		m.flagged,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// instrument counts and times the requests served by next
+func (m *serverMetrics) instrument(name string, next http.Handler) http.Handler {
+	labels := prometheus.Labels{"handler": name}
+	return promhttp.InstrumentHandlerCounter(m.requests.MustCurryWith(labels),
+		promhttp.InstrumentHandlerDuration(m.duration.MustCurryWith(labels), next))
+}
+
+// observe records a detection request's scored transactions and results,
+// counting each result once per rule that flagged it
+func (m *serverMetrics) observe(scored int, results []fraud.FraudResult) {
+	m.transactions.Add(float64(scored))
+	for _, result := range results {
+		for _, rule := range result.Rules {
+			m.flagged.WithLabelValues(rule).Inc()
+		}
+	}
+}
+
+// handler serves the metrics in the Prometheus text format
+func (m *serverMetrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// parquetResult is a row of a Parquet export: a fraud result with its
+// transaction's fields flattened into columns. Fields a transaction may lack
+// are optional, so they are null rather than empty.
+type parquetResult struct {
+	ID        string    `parquet:"id"`
+	AccountID string    `parquet:"account_id"`
+	Merchant  string    `parquet:"merchant"`
+	Amount    float64   `parquet:"amount"`
+	Currency  string    `parquet:"currency"`
+	Timestamp time.Time `parquet:"timestamp,timestamp(millisecond)"`
+	Latitude  *float64  `parquet:"latitude,optional"`
+	Longitude *float64  `parquet:"longitude,optional"`
+	Category  string    `parquet:"category,optional"`
+	MCC       string    `parquet:"mcc,optional"`
+	DeviceID  string    `parquet:"device_id,optional"`
+	CardID    string    `parquet:"card_id,optional"`
+	Country   string    `parquet:"country,optional"`
+	Severity  string    `parquet:"severity"`
+	Score     float64   `parquet:"score"`
+	Reason    string    `parquet:"reason"`
+}
+
+// writeResultsParquet writes the fraud results as a Parquet file with one
+// row per result. Currency codes are resolved, so every row has one.
+func writeResultsParquet(w io.Writer, results []fraud.FraudResult, config fraud.Config) error {
+	rows := make([]parquetResult, len(results))
+	for i, result := range results {
+		tx := result.Transaction
+		rows[i] = parquetResult{
+			ID:        tx.ID,
+			AccountID: tx.AccountID,
+			Merchant:  tx.Merchant,
+			Amount:    tx.Amount,
+			Currency:  config.CurrencyOf(tx).Code,
+			Timestamp: tx.Timestamp,
+			Latitude:  tx.Latitude,
+			Longitude: tx.Longitude,
+			Category:  tx.Category,
+			MCC:       tx.MCC,
+			DeviceID:  tx.DeviceID,
+			CardID:    tx.CardID,
+			Country:   tx.Country,
+			Severity:  result.Severity.String(),
+			Score:     result.Score,
+			Reason:    result.Reason,
+		}
+	}
+
+	schema := parquet.NewSchema("fraud_result", parquet.SchemaOf(parquetResult{}))
+	writer := parquet.NewGenericWriter[parquetResult](w, schema)
+	if _, err := writer.Write(rows); err != nil {
+		return err
+	}
+	return writer.Close()
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestWriteResultsParquet(t *testing.T) {
+	results := flaggedResults(3)
+	latitude := 40.7128
+	results[1].Transaction.Latitude = &latitude
+	results[1].Transaction.CardID = "card-1"
+
+	path := filepath.Join(t.TempDir(), "results.parquet")
+	if err := exportResults(results, fraud.Config{}, 3, path, "", false, nil, false, nil); err != nil {
+		t.Fatalf("exportResults: %v", err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pf, err := parquet.OpenFile(file, info.Size())
+	if err != nil {
+		t.Fatalf("opening the export as Parquet: %v", err)
+	}
+	if pf.NumRows() != 3 {
+		t.Errorf("file has %d rows, want 3", pf.NumRows())
+	}
+
+	var columns []string
+	optional := make(map[string]bool)
+	for _, field := range pf.Schema().Fields() {
+		columns = append(columns, field.Name())
+		optional[field.Name()] = field.Optional()
+	}
+	want := []string{"id", "account_id", "merchant", "amount", "currency", "timestamp", "latitude", "longitude", "category", "mcc", "device_id", "card_id", "country", "severity", "score", "reason"}
+	if !reflect.DeepEqual(columns, want) {
+		t.Errorf("columns = %v, want %v", columns, want)
+	}
+	if !optional["latitude"] || !optional["card_id"] || optional["id"] || optional["amount"] {
+		t.Errorf("optional columns = %v, want only fields a transaction may lack", optional)
+	}
+
+	rows, err := parquet.ReadFile[parquetResult](path)
+	if err != nil {
+		t.Fatalf("reading rows: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("read %d rows, want 3", len(rows))
+	}
+	row := rows[1]
+	if row.ID != "tx-1" || row.Amount != 1001 || row.Currency != "USD" || row.Severity != "high" || row.Score != 50 || row.CardID != "card-1" {
+		t.Errorf("row = %+v, want transaction tx-1", row)
+	}
+	if row.Latitude == nil || *row.Latitude != latitude || rows[0].Latitude != nil {
+		t.Errorf("latitudes = %v, %v, want %v and null", rows[0].Latitude, row.Latitude, latitude)
+	}
+	if !row.Timestamp.Equal(results[1].Transaction.Timestamp) {
+		t.Errorf("timestamp = %v, want %v", row.Timestamp, results[1].Transaction.Timestamp)
+	}
+}
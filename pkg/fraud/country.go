@@ -0,0 +1,25 @@
+package fraud
+
+import (
+	"fmt"
+	"strings"
+)
+
+// checkCountry flags transactions from a country in config.BlockedCountries,
+// or missing from config.AllowedCountries when that is set. Country codes
+// are compared case-insensitively.
+func checkCountry(tx Transaction, config Config) []FraudResult {
+	if tx.Country == "" {
+		return nil
+	}
+
+	country := strings.ToUpper(tx.Country)
+	blocked := config.BlockedCountries[country]
+	if len(config.AllowedCountries) > 0 && !config.AllowedCountries[country] {
+		blocked = true
+	}
+	if !blocked {
+		return nil
+	}
+	return []FraudResult{{Transaction: tx, Reason: fmt.Sprintf("Transaction from blocked country: %s", country)}}
+}
@@ -0,0 +1,32 @@
+package fraud
+
+import "testing"
+
+func TestCheckCountry(t *testing.T) {
+	tests := []struct {
+		name             string
+		allowed, blocked map[string]bool
+		country          string
+		want             string
+	}{
+		{"allowed", map[string]bool{"US": true, "CA": true}, nil, "US", ""},
+		{"allowed lowercase", map[string]bool{"US": true, "CA": true}, nil, "ca", ""},
+		{"not allowed", map[string]bool{"US": true, "CA": true}, nil, "ru", "Transaction from blocked country: RU"},
+		{"blocked", nil, map[string]bool{"RU": true}, "Ru", "Transaction from blocked country: RU"},
+		{"not blocked", nil, map[string]bool{"RU": true}, "GB", ""},
+		{"allowed but blocked", map[string]bool{"US": true}, map[string]bool{"US": true}, "US", "Transaction from blocked country: US"},
+		{"no country", map[string]bool{"US": true}, map[string]bool{"RU": true}, "", ""},
+		{"no lists", nil, nil, "RU", ""},
+	}
+	for _, tt := range tests {
+		tx := testTx("1", "A", 0, 10, "Shop")
+		tx.Country = tt.country
+		var got string
+		if results := checkCountry(tx, Config{AllowedCountries: tt.allowed, BlockedCountries: tt.blocked}); len(results) > 0 {
+			got = results[0].Reason
+		}
+		if got != tt.want {
+			t.Errorf("%s: reason = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
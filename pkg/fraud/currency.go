@@ -0,0 +1,109 @@
+package fraud
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Currency describes how amounts in a currency are written
+type Currency struct {
+	Code     string // ISO 4217 code, e.g. "EUR"
+	Symbol   string // Prefix written before amounts, e.g. "€"
+	Decimals int    // Digits after the decimal point, e.g. 0 for JPY
+}
+
+// currencies are the currencies known to LookupCurrency, by code
+var currencies = map[string]Currency{
+	"USD": {"USD", "$", 2},
+	"EUR": {"EUR", "€", 2},
+	"GBP": {"GBP", "£", 2},
+	"JPY": {"JPY", "¥", 0},
+	"CNY": {"CNY", "CN¥", 2},
+	"INR": {"INR", "₹", 2},
+	"KRW": {"KRW", "₩", 0},
+	"CHF": {"CHF", "CHF ", 2},
+	"CAD": {"CAD", "CA$", 2},
+	"AUD": {"AUD", "A$", 2},
+}
+
+// LookupCurrency returns the currency with the given ISO 4217 code
+func LookupCurrency(code string) (Currency, error) {
+	currency, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		codes := make([]string, 0, len(currencies))
+		for code := range currencies {
+			codes = append(codes, code)
+		}
+		slices.Sort(codes)
+		return Currency{}, fmt.Errorf("unknown currency %q (supported: %s)", code, strings.Join(codes, ", "))
+	}
+	return currency, nil
+}
+
+// Format writes amount with the currency's symbol and decimals, e.g.
+// "$1500.00", "¥1500", or "-€20.00"
+func (c Currency) Format(amount float64) string {
+	return c.sign(amount) + strconv.FormatFloat(math.Abs(amount), 'f', c.Decimals, 64)
+}
+
+// FormatGrouped is like Format with comma-separated thousands, e.g.
+// "$4,200.00"
+func (c Currency) FormatGrouped(amount float64) string {
+	return c.sign(amount) + groupThousands(math.Abs(amount), c.Decimals)
+}
+
+// sign returns the minus sign, if any, followed by the symbol
+func (c Currency) sign(amount float64) string {
+	if amount < 0 && c.minorUnits(amount) != 0 {
+		return "-" + c.Symbol
+	}
+	return c.Symbol
+}
+
+// minorUnits converts amount to a whole number of the currency's smallest
+// unit, e.g. cents, so amounts that differ only by floating point error, such
+// as 999.9999999 and 1000, are the same
+func (c Currency) minorUnits(amount float64) int64 {
+	return int64(math.Round(amount * math.Pow10(c.Decimals)))
+}
+
+// compareAmounts compares a and b in the currency's smallest unit, returning
+// -1, 0, or +1
+func (c Currency) compareAmounts(a, b float64) int {
+	return cmp.Compare(c.minorUnits(a), c.minorUnits(b))
+}
+
+// CurrencyOf returns the currency of tx: its own Currency when set, otherwise
+// config.Currency, defaulting to USD. Unknown codes are written as a prefix
+// with two decimals.
+func (c Config) CurrencyOf(tx Transaction) Currency {
+	code := tx.Currency
+	if code == "" {
+		code = c.Currency
+	}
+	if code == "" {
+		return currencies["USD"]
+	}
+	if currency, err := LookupCurrency(code); err == nil {
+		return currency
+	}
+	return Currency{Code: code, Symbol: strings.ToUpper(code) + " ", Decimals: 2}
+}
+
+// FormatAmount writes amount in currency, grouping thousands when
+// c.GroupDigits is set
+func (c Config) FormatAmount(currency Currency, amount float64) string {
+	if c.GroupDigits {
+		return currency.FormatGrouped(amount)
+	}
+	return currency.Format(amount)
+}
+
+// formatAmount writes amount in the currency of tx
+func (c Config) formatAmount(tx Transaction, amount float64) string {
+	return c.FormatAmount(c.CurrencyOf(tx), amount)
+}
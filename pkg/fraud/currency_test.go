@@ -0,0 +1,104 @@
+package fraud
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCurrencyFormat(t *testing.T) {
+	tests := []struct {
+		code    string
+		amount  float64
+		grouped bool
+		want    string
+	}{
+		{"USD", 1500, false, "$1500.00"},
+		{"JPY", 1500, false, "¥1500"},
+		{"JPY", 1499.6, false, "¥1500"},
+		{"EUR", -20, false, "-€20.00"},
+		{"EUR", 4200, true, "€4,200.00"},
+		{"JPY", 1234567, true, "¥1,234,567"},
+	}
+	for _, tt := range tests {
+		currency, err := LookupCurrency(tt.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := (Config{GroupDigits: tt.grouped}).FormatAmount(currency, tt.amount); got != tt.want {
+			t.Errorf("%s %v (grouped %v) = %q, want %q", tt.code, tt.amount, tt.grouped, got, tt.want)
+		}
+	}
+
+	if _, err := LookupCurrency("XYZ"); err == nil {
+		t.Error("LookupCurrency accepted an unknown code")
+	}
+}
+
+func TestCurrencyOf(t *testing.T) {
+	config := Config{Currency: "EUR"}
+	tests := []struct {
+		currency string
+		want     string
+	}{
+		{"", "€10.00"},
+		{"JPY", "¥10"},
+		{"XYZ", "XYZ 10.00"},
+	}
+	for _, tt := range tests {
+		tx := Transaction{Amount: 10, Currency: tt.currency}
+		if got := config.formatAmount(tx, tx.Amount); got != tt.want {
+			t.Errorf("currency %q: %q, want %q", tt.currency, got, tt.want)
+		}
+	}
+
+	// Reasons use the transaction's currency
+	results := checkHighAmount(Transaction{ID: "1", Amount: 150000, Currency: "JPY"}, Config{HighAmountThreshold: 1000})
+	if len(results) != 1 || results[0].Reason != "High amount: ¥150000" {
+		t.Errorf("results = %+v, want a high amount of ¥150000", results)
+	}
+}
+
+func TestThresholdComparesCents(t *testing.T) {
+	config := Config{HighAmountThreshold: 1000}
+	tests := []struct {
+		amount float64
+		want   bool
+	}{
+		{999.9999999, false}, // 1000 after a round trip through floating point
+		{1000, false},
+		{1000.004, false},
+		{1000.0000001, false},
+		{1000.01, true},
+		{0.1 + 0.2 + 999.7, false},
+	}
+	for _, tt := range tests {
+		if got := len(checkHighAmount(Transaction{ID: "1", Amount: tt.amount}, config)) > 0; got != tt.want {
+			t.Errorf("amount %v flagged = %v, want %v", tt.amount, got, tt.want)
+		}
+	}
+
+	// Currencies without minor units compare whole amounts
+	jpy := Transaction{ID: "1", Amount: 1000.4, Currency: "JPY"}
+	if results := checkHighAmount(jpy, config); len(results) != 0 {
+		t.Errorf("¥1000.4 flagged over a 1000 threshold: %+v", results)
+	}
+}
+
+func TestDuplicateComparesCents(t *testing.T) {
+	transactions := []Transaction{
+		testTx("1", "A", 0, 9.999999999, "Shop"), // 10.00 after a round trip through floating point
+		testTx("2", "A", 30*time.Second, 10, "Shop"),
+		testTx("3", "B", 0, 10, "Shop"),
+		testTx("4", "B", 30*time.Second, 10.01, "Shop"),
+	}
+	config := Config{DuplicateWindow: time.Minute, Rules: onlyRules(t, "duplicate")}
+
+	want := []string{"1", "2"}
+	if got := flaggedIDs(Detect(transactions, config), "Duplicate charge"); !slices.Equal(got, want) {
+		t.Errorf("Detect flagged %v, want %v", got, want)
+	}
+	if got := flaggedIDs(streamResults(transactions, config), "Duplicate charge"); !slices.Equal(got, want) {
+		t.Errorf("StreamDetector flagged %v, want %v", got, want)
+	}
+}
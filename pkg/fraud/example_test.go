@@ -0,0 +1,33 @@
+package fraud_test
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func ExampleDetect() {
+	input := `id,amount,timestamp,account_id,merchant
+1,25.00,2024-01-01T10:00:00Z,A,Coffee Shop
+2,4999.00,2024-01-01T10:02:00Z,A,Electronics
+3,12.50,2024-01-01T15:00:00Z,B,Bakery
+`
+	transactions, err := fraud.ReadCSV(strings.NewReader(input))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	results := fraud.Detect(transactions, fraud.Config{
+		HighAmountThreshold: 1000,
+		TimeWindow:          5 * time.Minute,
+	})
+	for _, result := range results {
+		fmt.Printf("%s %v: %s\n", result.Transaction.ID, result.Rules, result.Reason)
+	}
+	// Output:
+	// 1 [rapid-succession]: Rapid: 1 related transaction within 5m
+	// 2 [high-amount rapid-succession]: High amount: $4999.00; Rapid: 1 related transaction within 5m
+}
@@ -0,0 +1,213 @@
+package fraud
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Explanation is the outcome of every configured rule for one transaction
+type Explanation struct {
+	Transaction Transaction
+	Whitelisted bool        // The transaction was excluded from every rule
+	Checks      []RuleCheck // One entry per configured rule, in rule order
+}
+
+// RuleCheck is the outcome of one rule for one transaction
+type RuleCheck struct {
+	Rule    string
+	Flagged bool
+	Detail  string // The rule's reasons when flagged, else the values it compared
+}
+
+// Explain evaluates every configured rule separately and reports, for each
+// transaction in input order, whether each rule flagged it and why
+func Explain(transactions []Transaction, config Config) []Explanation {
+	rules := config.rules()
+
+	// Reasons of each rule by transaction ID
+	reasons := make([]map[string][]string, len(rules))
+	for i := range reasons {
+		reasons[i] = make(map[string][]string)
+	}
+
+	var checked []Transaction
+	for _, tx := range transactions {
+		if !config.whitelisted(tx) {
+			checked = append(checked, tx)
+		}
+	}
+	if config.MaxAccountsPerDevice > 0 {
+		config.deviceAccounts = countDeviceAccounts(checked)
+	}
+	for _, account := range groupByAccount(checked) {
+		for i, rule := range rules {
+			for _, result := range config.label(rule, rule.Evaluate(account, config)) {
+				id := result.Transaction.ID
+				reasons[i][id] = append(reasons[i][id], result.Reason)
+			}
+		}
+	}
+
+	explanations := make([]Explanation, 0, len(transactions))
+	for _, tx := range transactions {
+		explanation := Explanation{Transaction: tx, Whitelisted: config.whitelisted(tx)}
+		if !explanation.Whitelisted {
+			for i, rule := range rules {
+				check := RuleCheck{Rule: rule.Name()}
+				if matched := reasons[i][tx.ID]; len(matched) > 0 {
+					check.Flagged = true
+					check.Detail = strings.Join(dedupe(matched), "; ")
+				} else {
+					check.Detail = explainPass(rule, tx, config)
+				}
+				explanation.Checks = append(explanation.Checks, check)
+			}
+		}
+		explanations = append(explanations, explanation)
+	}
+
+	return explanations
+}
+
+// dedupe returns values without repeats, keeping the first of each
+func dedupe(values []string) []string {
+	var unique []string
+	for _, value := range values {
+		if !slices.Contains(unique, value) {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// explainPass describes the values a built-in rule compared for a
+// transaction it did not flag. Other rules are described only by name.
+func explainPass(rule Rule, tx Transaction, config Config) string {
+	amount := config.formatAmount(tx, tx.Amount)
+	switch rule.Name() {
+	case highAmountRule.name:
+		threshold, _ := config.highAmountThreshold(tx)
+		return fmt.Sprintf("amount %s <= limit %s", amount, config.formatAmount(tx, threshold))
+	case rapidRule.name:
+		if config.RapidEscalation > 0 {
+			return fmt.Sprintf("no amount escalating more than %gx within %s", config.RapidEscalation, shortDuration(config.TimeWindow))
+		}
+		return fmt.Sprintf("no other transaction within %s", shortDuration(config.TimeWindow))
+	case velocityRule.name:
+		if config.VelocityCount <= 0 {
+			return "disabled"
+		}
+		return fmt.Sprintf("not in a burst of more than %d within %s", config.VelocityCount, shortDuration(config.VelocityWindow))
+	case duplicateRule.name:
+		return fmt.Sprintf("no same amount at %s within %s", tx.Merchant, shortDuration(config.DuplicateWindow))
+	case blocklistRule.name:
+		if len(config.MerchantBlocklist) == 0 {
+			return "disabled"
+		}
+		return fmt.Sprintf("merchant %s not blocklisted", tx.Merchant)
+	case offHoursRule.name:
+		if config.OffHoursStart == config.OffHoursEnd {
+			return "disabled"
+		}
+		return fmt.Sprintf("time %s outside %02d:00-%02d:00", tx.Timestamp.In(config.location()).Format("15:04"), config.OffHoursStart, config.OffHoursEnd)
+	case weekendHolidayRule.name:
+		if !config.FlagWeekends && len(config.Holidays) == 0 {
+			return "disabled"
+		}
+		local := tx.Timestamp.In(config.location())
+		if !config.FlagWeekends {
+			return fmt.Sprintf("%s not a holiday", local.Format(HolidayLayout))
+		}
+		return fmt.Sprintf("%s %s not a weekend or holiday", local.Weekday(), local.Format(HolidayLayout))
+	case roundAmountRule.name:
+		if config.RoundMultiple <= 0 {
+			return "disabled"
+		}
+		return fmt.Sprintf("amount %s not a multiple of %g from %s", amount, config.RoundMultiple, config.formatAmount(tx, config.RoundMin))
+	case dailyLimitRule.name:
+		if config.DailyLimit <= 0 {
+			return "disabled"
+		}
+		return fmt.Sprintf("day total within limit %s", config.formatAmount(tx, config.DailyLimit))
+	case impossibleTravelRule.name:
+		if config.MaxSpeedKmh <= 0 {
+			return "disabled"
+		}
+		if !tx.hasLocation() {
+			return "no location"
+		}
+		return fmt.Sprintf("travel within %g km/h", config.MaxSpeedKmh)
+	case outlierRule.name:
+		if config.ZScore <= 0 {
+			return "disabled"
+		}
+		return fmt.Sprintf("z-score below %g or fewer than %d samples", config.ZScore, config.MinSamples)
+	case newMerchantRule.name:
+		if config.HistoryMin <= 0 {
+			return "disabled"
+		}
+		return fmt.Sprintf("merchant %s seen before or fewer than %d prior transactions", tx.Merchant, config.HistoryMin)
+	case minAmountRule.name:
+		if config.MinAmount == 0 {
+			return "disabled"
+		}
+		return fmt.Sprintf("amount %s >= minimum %s", amount, config.formatAmount(tx, config.MinAmount))
+	case zeroAmountRule.name:
+		if !config.FlagZero {
+			return "disabled"
+		}
+		return fmt.Sprintf("amount %s is not zero", amount)
+	case structuringRule.name:
+		if config.StructuringLimit <= 0 {
+			return "disabled"
+		}
+		if !config.nearLimit(tx) {
+			return fmt.Sprintf("amount %s not near limit %s", amount, config.formatAmount(tx, config.StructuringLimit))
+		}
+		return fmt.Sprintf("fewer than %d near-limit transactions that day", config.StructuringCount)
+	case newAccountRule.name:
+		if config.NewAccountCount <= 0 {
+			return "disabled"
+		}
+		return fmt.Sprintf("no more than %d transactions within %s of first activity", config.NewAccountCount, shortDuration(config.NewAccountWindow))
+	case cardTestingRule.name:
+		if config.DistinctMerchants <= 0 {
+			return "disabled"
+		}
+		return fmt.Sprintf("no more than %d merchants within %s", config.DistinctMerchants, shortDuration(config.MerchantWindow))
+	case multiCardRule.name:
+		if config.MaxCards <= 0 {
+			return "disabled"
+		}
+		if tx.CardID == "" {
+			return "no card ID"
+		}
+		return fmt.Sprintf("no more than %d cards within %s", config.MaxCards, shortDuration(config.CardWindow))
+	case sharedDeviceRule.name:
+		if config.MaxAccountsPerDevice <= 0 {
+			return "disabled"
+		}
+		if tx.DeviceID == "" {
+			return "no device ID"
+		}
+		return fmt.Sprintf("device %s used by %d of at most %d accounts", tx.DeviceID, config.deviceAccounts[tx.DeviceID], config.MaxAccountsPerDevice)
+	case highRiskMCCRule.name:
+		if len(config.HighRiskMCCs) == 0 {
+			return "disabled"
+		}
+		if tx.MCC == "" {
+			return "no MCC"
+		}
+		return fmt.Sprintf("MCC %s not high risk", tx.MCC)
+	case countryRule.name:
+		if len(config.AllowedCountries) == 0 && len(config.BlockedCountries) == 0 {
+			return "disabled"
+		}
+		if tx.Country == "" {
+			return "no country"
+		}
+		return fmt.Sprintf("country %s allowed", strings.ToUpper(tx.Country))
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package fraud
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Input formats read by StreamFormat
+const (
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+	FormatXLSX  = "xlsx"
+)
+
+// FormatForName returns the input format implied by a file name's extension,
+// ignoring a trailing .gz, or "" when the extension is not recognized
+func FormatForName(name string) string {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == ".gz" {
+		ext = strings.ToLower(filepath.Ext(strings.TrimSuffix(name, filepath.Ext(name))))
+	}
+
+	switch ext {
+	case ".csv":
+		return FormatCSV
+	case ".json":
+		return FormatJSON
+	case ".jsonl", ".ndjson":
+		return FormatJSONL
+	case ".xlsx":
+		return FormatXLSX
+	default:
+		return ""
+	}
+}
+
+// DetectFormat detects the input format of r from its first bytes, leaving
+// them unread. A leading "[" is a JSON array and a leading "{" is JSON
+// Lines, or a JSON object wrapping the array when JSONPath is set; zip
+// archives are workbooks and anything else is CSV.
+func (o ReadOptions) DetectFormat(r *bufio.Reader) (string, error) {
+	peek, err := r.Peek(512)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if bytes.HasPrefix(peek, []byte("PK\x03\x04")) {
+		return FormatXLSX, nil
+	}
+
+	peek = bytes.TrimLeft(peek, " \t\r\n\ufeff")
+	switch {
+	case len(peek) > 0 && peek[0] == '[':
+		return FormatJSON, nil
+	case len(peek) > 0 && peek[0] == '{' && o.JSONPath != "":
+		return FormatJSON, nil
+	case len(peek) > 0 && peek[0] == '{':
+		return FormatJSONL, nil
+	default:
+		return FormatCSV, nil
+	}
+}
+
+// StreamFormat reads transactions in the given format, "csv", "json",
+// "jsonl" (or "ndjson"), or "xlsx", calling fn for each one as it is parsed
+func (o ReadOptions) StreamFormat(r io.Reader, format string, fn func(Transaction) error) error {
+	switch strings.ToLower(format) {
+	case FormatCSV:
+		return o.StreamCSV(r, fn)
+	case FormatJSON:
+		return o.StreamJSON(r, fn)
+	case FormatJSONL, "ndjson":
+		return o.StreamJSONL(r, fn)
+	case FormatXLSX:
+		return o.StreamXLSX(r, fn)
+	default:
+		return fmt.Errorf("unsupported file type: %s", format)
+	}
+}
+
+// StreamAuto reads transactions from r in the format implied by the
+// extension of name, or detected from the content with DetectFormat when
+// the extension is not recognized, calling fn for each one as it is parsed.
+// Gzip-compressed content is decompressed first, whatever its name.
+func (o ReadOptions) StreamAuto(r io.Reader, name string, fn func(Transaction) error) error {
+	buffered := bufio.NewReader(r)
+	if magic, _ := buffered.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		buffered = bufio.NewReader(gz)
+	}
+
+	format := FormatForName(name)
+	if format == "" {
+		var err error
+		if format, err = o.DetectFormat(buffered); err != nil {
+			return err
+		}
+	}
+	return o.StreamFormat(buffered, format, fn)
+}
@@ -0,0 +1,141 @@
+package fraud
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+const (
+	csvInput   = "id,amount,timestamp,account_id,merchant\n1,10.00,2024-01-01T10:00:00Z,A,Shop\n"
+	jsonInput  = `[{"id": "1", "amount": 10, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A", "merchant": "Shop"}]`
+	jsonlInput = `{"id": "1", "amount": 10, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A", "merchant": "Shop"}` + "\n"
+	wrapInput  = `{"data": [{"id": "1", "amount": 10, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A", "merchant": "Shop"}]}`
+)
+
+// xlsxInput returns a workbook with one transaction
+func xlsxInput(t *testing.T) string {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := f.GetSheetName(0)
+	f.SetSheetRow(sheet, "A1", &[]any{"id", "amount", "timestamp", "account_id", "merchant"})
+	f.SetSheetRow(sheet, "A2", &[]any{"1", 10, "2024-01-01T10:00:00Z", "A", "Shop"})
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatalf("writing workbook: %v", err)
+	}
+	return buf.String()
+}
+
+func gzipped(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(s))
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	return buf.String()
+}
+
+func TestFormatForName(t *testing.T) {
+	tests := map[string]string{
+		"tx.csv":          FormatCSV,
+		"tx.CSV":          FormatCSV,
+		"tx.json":         FormatJSON,
+		"tx.jsonl":        FormatJSONL,
+		"tx.ndjson":       FormatJSONL,
+		"tx.xlsx":         FormatXLSX,
+		"tx.csv.gz":       FormatCSV,
+		"dir.v2/tx.jsonl": FormatJSONL,
+		"tx.gz":           "",
+		"tx.txt":          "",
+		"-":               "",
+	}
+	for name, want := range tests {
+		if got := FormatForName(name); got != want {
+			t.Errorf("FormatForName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		opts  ReadOptions
+		want  string
+	}{
+		{"csv", csvInput, ReadOptions{}, FormatCSV},
+		{"json array", jsonInput, ReadOptions{}, FormatJSON},
+		{"json array after whitespace and BOM", "\ufeff \n\t" + jsonInput, ReadOptions{}, FormatJSON},
+		{"json lines", jsonlInput, ReadOptions{}, FormatJSONL},
+		{"wrapped json", wrapInput, ReadOptions{JSONPath: "data"}, FormatJSON},
+		{"xlsx", xlsxInput(t), ReadOptions{}, FormatXLSX},
+		{"empty", "", ReadOptions{}, FormatCSV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.input))
+			got, err := tt.opts.DetectFormat(r)
+			if err != nil {
+				t.Fatalf("DetectFormat: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("DetectFormat = %q, want %q", got, tt.want)
+			}
+			if rest, _ := io.ReadAll(r); string(rest) != tt.input {
+				t.Error("DetectFormat consumed input")
+			}
+		})
+	}
+}
+
+func TestStreamAuto(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		inputName string
+		opts      ReadOptions
+	}{
+		{"csv by content", csvInput, "-", ReadOptions{}},
+		{"json by content", jsonInput, "-", ReadOptions{}},
+		{"json lines by content", jsonlInput, "export", ReadOptions{}},
+		{"wrapped json by content", wrapInput, "-", ReadOptions{JSONPath: "data"}},
+		{"xlsx by content", xlsxInput(t), "-", ReadOptions{}},
+		{"gzipped csv by content", gzipped(t, csvInput), "-", ReadOptions{}},
+		{"json by extension", jsonInput, "tx.json", ReadOptions{}},
+		{"json lines by extension", jsonlInput, "tx.ndjson", ReadOptions{}},
+		{"gzipped json lines by extension", gzipped(t, jsonlInput), "tx.jsonl.gz", ReadOptions{}},
+		{"wrapped json by extension", wrapInput, "tx.json", ReadOptions{JSONPath: "data"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Transaction
+			err := tt.opts.StreamAuto(strings.NewReader(tt.input), tt.inputName, func(tx Transaction) error {
+				got = append(got, tx)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("StreamAuto: %v", err)
+			}
+			if len(got) != 1 || got[0].ID != "1" || got[0].Amount != 10 || got[0].Merchant != "Shop" {
+				t.Errorf("StreamAuto read %+v, want transaction 1 of 10.00 at Shop", got)
+			}
+		})
+	}
+}
+
+func TestStreamAutoExtensionOverridesContent(t *testing.T) {
+	// A CSV named .json is read as JSON, so the mismatch is an error
+	err := ReadOptions{}.StreamAuto(strings.NewReader(csvInput), "tx.json", func(Transaction) error { return nil })
+	if err == nil {
+		t.Error("StreamAuto read CSV content named tx.json without error")
+	}
+}
@@ -0,0 +1,1186 @@
+// Package fraud detects potentially fraudulent transactions in financial
+// data. Callers read transactions with ReadCSV or ReadJSON, or build them
+// directly, and pass them to Detect:
+//
+//	results := fraud.Detect(transactions, fraud.Config{
+//		HighAmountThreshold: 1000,
+//		TimeWindow:          5 * time.Minute,
+//	})
+package fraud
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Transaction represents a financial transaction
+type Transaction struct {
+	ID        string    `json:"id"`
+	Amount    float64   `json:"amount"`
+	Timestamp time.Time `json:"timestamp"`
+	AccountID string    `json:"account_id"`
+	Merchant  string    `json:"merchant"`
+	Latitude  *float64  `json:"latitude,omitempty"`
+	Longitude *float64  `json:"longitude,omitempty"`
+	Currency  string    `json:"currency,omitempty"`  // ISO 4217 code; empty uses Config.Currency
+	Category  string    `json:"category,omitempty"`  // Transaction type, e.g. purchase, withdrawal, or transfer
+	MCC       string    `json:"mcc,omitempty"`       // Merchant category code, e.g. 7995 for gambling
+	DeviceID  string    `json:"device_id,omitempty"` // Device the transaction was made from
+	CardID    string    `json:"card_id,omitempty"`   // Card or other payment instrument used
+	Country   string    `json:"country,omitempty"`   // ISO 3166 country code where the transaction was made
+}
+
+// FraudResult represents a detected fraudulent transaction with the reasons
+// of every rule it matched, separated by "; "
+type FraudResult struct {
+	Transaction Transaction
+	Reason      string
+	Severity    Severity // Highest severity of the rules matched
+	Score       float64  // Sum of the risk weights of the rules matched; see Config.RuleWeight
+	Rules       []string // Names of the rules matched, e.g. high-amount, in the order they matched
+}
+
+// Config holds the fraud detection thresholds
+type Config struct {
+	HighAmountThreshold  float64
+	CategoryThresholds   map[string]float64 // High amount thresholds by lowercased category, overriding HighAmountThreshold
+	AccountThresholds    map[string]float64 // High amount thresholds by lowercased account ID, overriding the others
+	TimeWindow           time.Duration
+	FlagSimultaneous     bool    // Rapid succession also flags an account's transactions at the same instant
+	RapidEscalation      float64 // With a factor of 1 or more, rapid succession flags only later amounts above the earlier amount times this; 0 flags every pair
+	VelocityCount        int
+	VelocityWindow       time.Duration
+	DuplicateWindow      time.Duration
+	BatchSize            int                // Transactions per goroutine; 0 sizes batches from the CPU count
+	Concurrency          int                // Batches processed at once; 0 uses the CPU count and 1 processes them in order
+	StreamBuffer         int                // Most recent transactions StreamDetector keeps per account and per rolling window; 0 keeps all within the lookback
+	MerchantBlocklist    map[string]bool    // Normalized merchant names to flag; see NormalizeMerchant
+	MerchantAliases      []MerchantAlias    // Merchant name patterns and the canonical names they stand for
+	HighRiskMCCs         map[string]bool    // Merchant category codes to flag
+	AllowedCountries     map[string]bool    // Uppercased country codes allowed; others are flagged. Empty allows all
+	BlockedCountries     map[string]bool    // Uppercased country codes to flag
+	Whitelist            map[string]bool    // Lowercased account IDs and merchant names never flagged
+	OffHoursStart        int                // First off-hours hour (0-23); equal to OffHoursEnd disables the rule
+	OffHoursEnd          int                // Hour the off-hours window ends, exclusive; may wrap past midnight
+	FlagWeekends         bool               // Flag transactions on Saturdays and Sundays in Location
+	Holidays             map[string]bool    // Dates to flag in Location, formatted with HolidayLayout
+	Location             *time.Location     // Time zone for hour and day based rules; nil means UTC
+	Currency             string             // ISO 4217 code of amounts of transactions without one; empty means USD
+	GroupDigits          bool               // Write amounts in reasons with comma-separated thousands, e.g. $1,500.00
+	RoundMultiple        float64            // Flag amounts that are exact multiples of this; 0 disables the rule
+	RoundMin             float64            // Smallest amount the round-amount rule applies to
+	DailyLimit           float64            // Flag account-days whose total spend exceeds this; 0 disables the rule
+	MaxSpeedKmh          float64            // Flag travel between transactions faster than this; 0 disables the rule
+	ZScore               float64            // Flag amounts this many standard deviations above the account mean; 0 disables the rule
+	MinSamples           int                // Fewest transactions an account needs before the z-score rule applies
+	HistoryMin           int                // Prior transactions an account needs before a new merchant is flagged; 0 disables the rule
+	MinAmount            float64            // Flag nonzero amounts below this, e.g. -1000 for large refunds; 0 disables the rule
+	FlagZero             bool               // Flag zero-amount transactions, which may be card probes
+	StructuringLimit     float64            // Reporting limit that structured transactions stay under; 0 disables the rule
+	StructuringBand      float64            // Fraction below StructuringLimit counted as near it, e.g. 0.05
+	StructuringCount     int                // Near-limit transactions in an account-day that are flagged as structuring
+	NewAccountCount      int                // Flag more than this many transactions soon after an account's first; 0 disables the rule
+	NewAccountWindow     time.Duration      // How soon after an account's first transaction the new-account rule looks
+	DistinctMerchants    int                // Flag more than this many merchants for an account within MerchantWindow; 0 disables the rule
+	MerchantWindow       time.Duration      // Rolling window of the distinct merchants rule
+	MaxAccountsPerDevice int                // Flag devices used by more than this many accounts; 0 disables the rule
+	MaxCards             int                // Flag more than this many cards for an account within CardWindow; 0 disables the rule
+	CardWindow           time.Duration      // Rolling window of the multiple cards rule
+	Rules                []Rule             // Rules to apply, in order; nil applies BuiltinRules
+	MinSeverity          Severity           // Drop rule results less severe than this; 0 keeps all
+	RuleWeights          map[string]float64 // Risk weight of each rule by name; unlisted rules weigh by severity
+	RiskThreshold        float64            // Report only transactions whose score is above this; 0 reports all
+	StopOnMatch          bool               // Stop DetectContext at the first account with results, skipping the rest
+
+	// ReasonTemplates, when set, reword the reasons of rules by rule name.
+	// Templates are rendered with ReasonData; see ParseReasonTemplate.
+	ReasonTemplates map[string]*template.Template
+
+	// deviceAccounts is the number of distinct accounts of each device ID
+	// in the input, counted by DetectContext for the shared device rule
+	deviceAccounts map[string]int
+
+	// OnBatchDone, when set, is called after each batch completes with the
+	// number of batches done and the total. Calls are serialized.
+	OnBatchDone func(done, total int)
+}
+
+// Detect applies fraud detection rules to transactions
+func Detect(transactions []Transaction, config Config) []FraudResult {
+	results, _ := DetectContext(context.Background(), transactions, config)
+	return results
+}
+
+// DetectContext is like Detect but stops early when ctx is done, returning
+// the results found so far along with the context's error. With
+// config.StopOnMatch it also stops once any account is flagged, and the
+// results are only those found by then.
+func DetectContext(ctx context.Context, transactions []Transaction, config Config) ([]FraudResult, error) {
+	var wg sync.WaitGroup
+
+	// Workers stop when ctx is done or, with StopOnMatch, at the first match
+	detectCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Whitelisted transactions are excluded from every rule
+	if len(config.Whitelist) > 0 {
+		transactions = slices.DeleteFunc(slices.Clone(transactions), config.whitelisted)
+	}
+
+	// The shared device rule looks across accounts
+	if config.MaxAccountsPerDevice > 0 && config.enabled(sharedDeviceRule) {
+		config.deviceAccounts = countDeviceAccounts(transactions)
+	}
+
+	// Group transactions by account so time-based rules see each account's
+	// full history, then process whole accounts in batches on a pool of
+	// goroutines
+	accounts := groupByAccount(transactions)
+
+	batchSize := config.BatchSize
+	if batchSize <= 0 {
+		batchSize = autoBatchSize(len(transactions))
+	}
+
+	var batches [][][]Transaction
+	var batch [][]Transaction
+	count := 0
+	for i, account := range accounts {
+		batch = append(batch, account)
+		count += len(account)
+		if count < batchSize && i < len(accounts)-1 {
+			continue
+		}
+		batches = append(batches, batch)
+		batch = nil
+		count = 0
+	}
+
+	workers := config.Concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// Workers send each batch's results over a channel to a single
+	// collector, so they never wait on a lock. The collector keeps each
+	// batch's results in its own slot and joins them in batch order, so the
+	// merged reasons do not depend on scheduling.
+	next := make(chan int)
+	out := make(chan batchOutput, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results := processBatch(detectCtx, batches[i], config)
+				if config.StopOnMatch && config.matched(results) {
+					cancel()
+				}
+				out <- batchOutput{index: i, results: results}
+			}
+		}()
+	}
+	go func() {
+		for i := range batches {
+			if detectCtx.Err() != nil {
+				break
+			}
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+		close(out)
+	}()
+
+	batchResults := make([][]FraudResult, len(batches))
+	done := 0
+	for output := range out {
+		batchResults[output.index] = output.results
+		done++
+		if config.OnBatchDone != nil {
+			config.OnBatchDone(done, len(batches))
+		}
+	}
+
+	var results []FraudResult
+	for _, batch := range batchResults {
+		results = append(results, batch...)
+	}
+
+	merged := config.FilterRisk(MergeResults(config.severe(results)))
+	SortResults(merged, SortByTimestamp)
+	return merged, ctx.Err()
+}
+
+// Orderings accepted by SortResults
+const (
+	SortByTimestamp = "timestamp"
+	SortByAmount    = "amount"
+	SortByAccount   = "account"
+)
+
+// SortResults orders results by timestamp, by amount (largest first), or by
+// account ID, breaking ties by timestamp and then transaction ID so the order
+// is the same on every run
+func SortResults(results []FraudResult, by string) error {
+	var primary func(a, b Transaction) int
+	switch by {
+	case SortByTimestamp:
+		primary = func(a, b Transaction) int { return 0 }
+	case SortByAmount:
+		primary = func(a, b Transaction) int { return cmp.Compare(b.Amount, a.Amount) }
+	case SortByAccount:
+		primary = func(a, b Transaction) int { return strings.Compare(a.AccountID, b.AccountID) }
+	default:
+		return fmt.Errorf("unsupported sort key: %s", by)
+	}
+
+	slices.SortStableFunc(results, func(x, y FraudResult) int {
+		a, b := x.Transaction, y.Transaction
+		if c := primary(a, b); c != 0 {
+			return c
+		}
+		if c := a.Timestamp.Compare(b.Timestamp); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return nil
+}
+
+// batchesPerCPU is how many batches autoBatchSize aims to give each CPU, so
+// that uneven accounts still balance across goroutines
+const batchesPerCPU = 4
+
+// autoBatchSize splits n transactions into a few batches per CPU
+func autoBatchSize(n int) int {
+	batches := runtime.NumCPU() * batchesPerCPU
+	size := (n + batches - 1) / batches
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// MergeResults combines results for the same transaction ID into a single
+// result whose reason joins every matched rule, keeping first-seen order,
+// whose rules are all of theirs, and whose severity is the highest of theirs
+func MergeResults(results []FraudResult) []FraudResult {
+	index := make(map[string]int)
+	var merged []FraudResult
+	var reasons [][]string
+
+	for _, result := range results {
+		i, ok := index[result.Transaction.ID]
+		if !ok {
+			i = len(merged)
+			index[result.Transaction.ID] = i
+			merged = append(merged, result)
+			merged[i].Rules = slices.Clone(result.Rules)
+			reasons = append(reasons, nil)
+		}
+		if !slices.Contains(reasons[i], result.Reason) {
+			reasons[i] = append(reasons[i], result.Reason)
+		}
+		merged[i].Severity = max(merged[i].Severity, result.Severity)
+		if !ok {
+			continue
+		}
+
+		// A rule's weight counts once, however many results it produced
+		added := len(result.Rules) == 0
+		for _, rule := range result.Rules {
+			if !slices.Contains(merged[i].Rules, rule) {
+				merged[i].Rules = append(merged[i].Rules, rule)
+				added = true
+			}
+		}
+		if added {
+			merged[i].Score += result.Score
+		}
+	}
+
+	for i := range merged {
+		merged[i].Reason = strings.Join(reasons[i], "; ")
+	}
+
+	return merged
+}
+
+// groupByAccount groups transactions by account ID in order of first
+// appearance, with each account's transactions sorted by timestamp
+func groupByAccount(transactions []Transaction) [][]Transaction {
+	index := make(map[string]int)
+	var accounts [][]Transaction
+	for _, tx := range transactions {
+		i, ok := index[tx.AccountID]
+		if !ok {
+			i = len(accounts)
+			index[tx.AccountID] = i
+			accounts = append(accounts, nil)
+		}
+		accounts[i] = append(accounts[i], tx)
+	}
+
+	for _, account := range accounts {
+		sort.SliceStable(account, func(i, j int) bool {
+			return account[i].Timestamp.Before(account[j].Timestamp)
+		})
+	}
+
+	return accounts
+}
+
+// batchOutput is the results of the batch at index, sent by a DetectContext
+// worker to the collector
+type batchOutput struct {
+	index   int
+	results []FraudResult
+}
+
+// processBatch processes a batch of accounts for fraud detection. Each
+// account's transactions must be sorted by timestamp. It stops between
+// accounts once ctx is done.
+func processBatch(ctx context.Context, batch [][]Transaction, config Config) []FraudResult {
+	var batchResults []FraudResult
+
+	rules := config.rules()
+	for _, account := range batch {
+		if ctx.Err() != nil {
+			break
+		}
+		mark := len(batchResults)
+		for _, rule := range rules {
+			batchResults = append(batchResults, config.label(rule, rule.Evaluate(account, config))...)
+		}
+		if config.StopOnMatch && config.matched(batchResults[mark:]) {
+			break
+		}
+	}
+
+	return batchResults
+}
+
+// checkTransaction applies the enabled rules that need only the transaction
+// itself
+func checkTransaction(tx Transaction, config Config) []FraudResult {
+	var results []FraudResult
+	for _, rule := range config.rules() {
+		if rule, ok := rule.(transactionRule); ok {
+			results = append(results, config.label(rule, rule.check(tx, config))...)
+		}
+	}
+	return results
+}
+
+// checkHighAmount flags amounts above the threshold for the transaction's
+// account, else its category, else config.HighAmountThreshold
+func checkHighAmount(tx Transaction, config Config) []FraudResult {
+	threshold, limit := config.highAmountThreshold(tx)
+	if config.CurrencyOf(tx).compareAmounts(tx.Amount, threshold) <= 0 {
+		return nil
+	}
+
+	reason := fmt.Sprintf("High amount: %s", config.formatAmount(tx, tx.Amount))
+	if limit != "" {
+		reason += fmt.Sprintf(" (%s limit %s)", limit, config.formatAmount(tx, threshold))
+	}
+	return []FraudResult{{Transaction: tx, Reason: reason}}
+}
+
+// highAmountThreshold returns the high amount threshold that applies to tx
+// and, when it is not the global one, what it is for
+func (c Config) highAmountThreshold(tx Transaction) (float64, string) {
+	if threshold, ok := c.AccountThresholds[strings.ToLower(tx.AccountID)]; ok {
+		return threshold, "account"
+	}
+	category := strings.ToLower(strings.TrimSpace(tx.Category))
+	if threshold, ok := c.CategoryThresholds[category]; ok {
+		return threshold, category
+	}
+	return c.HighAmountThreshold, ""
+}
+
+// checkBlocklist flags merchants on config.MerchantBlocklist. Only exact,
+// case-insensitive names are matched; prefix or glob patterns could be
+// supported by keeping a list of patterns and testing each with
+// strings.HasPrefix or path.Match.
+func checkBlocklist(tx Transaction, config Config) []FraudResult {
+	if !config.MerchantBlocklist[config.NormalizeMerchant(tx.Merchant)] {
+		return nil
+	}
+	return []FraudResult{{
+		Transaction: tx,
+		Reason:      fmt.Sprintf("Blocklisted merchant: %s", tx.Merchant),
+	}}
+}
+
+// checkOffHours flags transactions inside the configured off-hours window
+func checkOffHours(tx Transaction, config Config) []FraudResult {
+	if config.OffHoursStart == config.OffHoursEnd {
+		return nil
+	}
+	local := tx.Timestamp.In(config.location())
+	if !inHourWindow(local.Hour(), config.OffHoursStart, config.OffHoursEnd) {
+		return nil
+	}
+	return []FraudResult{{
+		Transaction: tx,
+		Reason:      fmt.Sprintf("Off-hours transaction at %s", local.Format("15:04")),
+	}}
+}
+
+// checkRoundAmount flags amounts of at least config.RoundMin that are exact
+// multiples of config.RoundMultiple
+func checkRoundAmount(tx Transaction, config Config) []FraudResult {
+	if config.RoundMultiple <= 0 || config.CurrencyOf(tx).compareAmounts(tx.Amount, config.RoundMin) < 0 || !isMultiple(tx.Amount, config.RoundMultiple) {
+		return nil
+	}
+	return []FraudResult{{
+		Transaction: tx,
+		Reason:      fmt.Sprintf("Round amount: %s (multiple of %g)", config.formatAmount(tx, tx.Amount), config.RoundMultiple),
+	}}
+}
+
+// checkMinAmount flags nonzero amounts below config.MinAmount, describing
+// negative amounts as refunds
+func checkMinAmount(tx Transaction, config Config) []FraudResult {
+	currency := config.CurrencyOf(tx)
+	if config.MinAmount == 0 || currency.minorUnits(tx.Amount) == 0 || currency.compareAmounts(tx.Amount, config.MinAmount) >= 0 {
+		return nil
+	}
+
+	kind := "Amount"
+	if tx.Amount < 0 {
+		kind = "Refund"
+	}
+	return []FraudResult{{
+		Transaction: tx,
+		Reason:      fmt.Sprintf("%s below minimum: %s (minimum %s)", kind, config.formatAmount(tx, tx.Amount), config.formatAmount(tx, config.MinAmount)),
+	}}
+}
+
+// checkZeroAmount flags zero-amount transactions when config.FlagZero is set
+func checkZeroAmount(tx Transaction, config Config) []FraudResult {
+	if !config.FlagZero || config.CurrencyOf(tx).minorUnits(tx.Amount) != 0 {
+		return nil
+	}
+	return []FraudResult{{
+		Transaction: tx,
+		Reason:      fmt.Sprintf("Zero amount: %s authorization, possible card probe", config.formatAmount(tx, 0)),
+	}}
+}
+
+// detectRapid flags each of an account's transactions that has others less
+// than config.TimeWindow before or after it, once, with the number of such
+// related transactions. The account's transactions must be sorted by
+// timestamp.
+func detectRapid(account []Transaction, config Config) []FraudResult {
+	var results []FraudResult
+	if config.FlagSimultaneous {
+		results = detectSimultaneous(account, config)
+	}
+	if config.RapidEscalation > 0 {
+		return append(results, detectRapidEscalation(account, config)...)
+	}
+
+	// Related transactions of account[i] lie between lo and hi; transactions
+	// at the same instant are not counted
+	lo, hi := 0, 0
+	for i, tx := range account {
+		for lo < i && tx.Timestamp.Sub(account[lo].Timestamp) >= config.TimeWindow {
+			lo++
+		}
+		if hi < i {
+			hi = i
+		}
+		for hi+1 < len(account) && account[hi+1].Timestamp.Sub(tx.Timestamp) < config.TimeWindow {
+			hi++
+		}
+
+		related := 0
+		for _, other := range account[lo : hi+1] {
+			if !other.Timestamp.Equal(tx.Timestamp) {
+				related++
+			}
+		}
+		if related > 0 {
+			results = append(results, FraudResult{Transaction: tx, Reason: rapidReason(related, config)})
+		}
+	}
+
+	return results
+}
+
+// rapidReason describes a transaction with related transactions inside the
+// rapid succession window
+func rapidReason(related int, config Config) string {
+	noun := "transactions"
+	if related == 1 {
+		noun = "transaction"
+	}
+	return fmt.Sprintf("Rapid: %d related %s within %s", related, noun, shortDuration(config.TimeWindow))
+}
+
+// detectSimultaneous flags an account's transactions that share their
+// timestamp with another of its transactions, as when a stolen card is
+// charged by several systems at once. The account's transactions must be
+// sorted by timestamp.
+func detectSimultaneous(account []Transaction, config Config) []FraudResult {
+	var results []FraudResult
+	for start := 0; start < len(account); {
+		end := start + 1
+		for end < len(account) && account[end].Timestamp.Equal(account[start].Timestamp) {
+			end++
+		}
+		if end-start > 1 {
+			reason := simultaneousReason(end-start, account[start].Timestamp, config)
+			for _, tx := range account[start:end] {
+				results = append(results, FraudResult{Transaction: tx, Reason: reason})
+			}
+		}
+		start = end
+	}
+	return results
+}
+
+// simultaneousReason describes count transactions of an account at the
+// instant t
+func simultaneousReason(count int, t time.Time, config Config) string {
+	return fmt.Sprintf("Simultaneous transactions: %d at %s", count, t.In(config.location()).Format("15:04:05"))
+}
+
+// escalates reports whether later escalates from earlier by more than
+// config.RapidEscalation
+func (c Config) escalates(earlier, later Transaction) bool {
+	return later.Amount > earlier.Amount*c.RapidEscalation
+}
+
+// detectRapidEscalation is the rapid succession rule with
+// config.RapidEscalation set: it flags both transactions of each pair less
+// than config.TimeWindow apart where the later amount escalates, like a small
+// test charge followed by a large one. Each transaction is flagged once,
+// with its largest escalation: from the smallest earlier amount when it is
+// the later of a pair, else to the largest later amount.
+func detectRapidEscalation(account []Transaction, config Config) []FraudResult {
+	var results []FraudResult
+
+	lo, hi := 0, 0
+	for i, tx := range account {
+		for lo < i && tx.Timestamp.Sub(account[lo].Timestamp) >= config.TimeWindow {
+			lo++
+		}
+		if hi < i {
+			hi = i
+		}
+		for hi+1 < len(account) && account[hi+1].Timestamp.Sub(tx.Timestamp) < config.TimeWindow {
+			hi++
+		}
+
+		var from, to *Transaction
+		for j := lo; j < i; j++ {
+			earlier := &account[j]
+			if earlier.Timestamp.Before(tx.Timestamp) && config.escalates(*earlier, tx) && (from == nil || earlier.Amount < from.Amount) {
+				from = earlier
+			}
+		}
+		if from == nil {
+			for k := i + 1; k <= hi; k++ {
+				later := &account[k]
+				if later.Timestamp.After(tx.Timestamp) && config.escalates(tx, *later) && (to == nil || later.Amount > to.Amount) {
+					to = later
+				}
+			}
+		}
+
+		switch {
+		case from != nil:
+			results = append(results, FraudResult{Transaction: tx, Reason: escalationReason(*from, tx, config)})
+		case to != nil:
+			results = append(results, FraudResult{Transaction: tx, Reason: escalationReason(tx, *to, config)})
+		}
+	}
+
+	return results
+}
+
+// escalationReason describes a rapid pair whose amount escalates
+func escalationReason(earlier, later Transaction, config Config) string {
+	return fmt.Sprintf("Rapid escalation: %s to %s within %s", config.formatAmount(earlier, earlier.Amount), config.formatAmount(later, later.Amount), shortDuration(later.Timestamp.Sub(earlier.Timestamp)))
+}
+
+// shortDuration formats d without trailing zero units, e.g. "5m" rather
+// than "5m0s"
+func shortDuration(d time.Duration) string {
+	s := d.String()
+	if strings.HasSuffix(s, "m0s") {
+		s = strings.TrimSuffix(s, "0s")
+	}
+	if strings.HasSuffix(s, "h0m") {
+		s = strings.TrimSuffix(s, "0m")
+	}
+	return s
+}
+
+// isMultiple reports whether amount is an exact multiple of multiple,
+// comparing whole cents to avoid floating point remainders
+func isMultiple(amount, multiple float64) bool {
+	cents := int64(math.Round(amount * 100))
+	step := int64(math.Round(multiple * 100))
+	return step > 0 && cents != 0 && cents%step == 0
+}
+
+// whitelisted reports whether the transaction's account or merchant is on
+// the whitelist
+func (c Config) whitelisted(tx Transaction) bool {
+	return c.Whitelist[strings.ToLower(tx.AccountID)] ||
+		c.Whitelist[c.NormalizeMerchant(tx.Merchant)]
+}
+
+// windowLookback returns the longest rolling window over which rules compare
+// an account's recent transactions
+func (c Config) windowLookback() time.Duration {
+	lookback := c.TimeWindow
+	if c.VelocityCount > 0 && c.VelocityWindow > lookback {
+		lookback = c.VelocityWindow
+	}
+	if c.DuplicateWindow > lookback {
+		lookback = c.DuplicateWindow
+	}
+	if c.DistinctMerchants > 0 && c.MerchantWindow > lookback {
+		lookback = c.MerchantWindow
+	}
+	if c.MaxCards > 0 && c.CardWindow > lookback {
+		lookback = c.CardWindow
+	}
+	return lookback
+}
+
+// longestDay is the longest calendar day in any time zone, when daylight
+// saving time ends and a day has 25 hours
+const longestDay = 25 * time.Hour
+
+// Lookback returns how long before a transaction the rules may look for
+// related transactions of its account. Rules that use an account's whole
+// history, such as outlier and new-merchant, can look further back.
+func (c Config) Lookback() time.Duration {
+	lookback := c.windowLookback()
+
+	// The daily limit and structuring rules need a whole day, and the
+	// impossible travel rule the last known location
+	if (c.DailyLimit > 0 || c.MaxSpeedKmh > 0 || c.StructuringLimit > 0) && lookback < longestDay {
+		lookback = longestDay
+	}
+	if c.NewAccountCount > 0 && c.NewAccountWindow > lookback {
+		lookback = c.NewAccountWindow
+	}
+	return lookback
+}
+
+// location returns the configured time zone, defaulting to UTC
+func (c Config) location() *time.Location {
+	if c.Location == nil {
+		return time.UTC
+	}
+	return c.Location
+}
+
+// inHourWindow reports whether hour falls in [start, end), where a start
+// after end wraps past midnight
+func inHourWindow(hour, start, end int) bool {
+	if start < end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// detectNewAccountBurst flags an account's transactions within
+// config.NewAccountWindow of its first one when there are more than
+// config.NewAccountCount of them. The account's transactions must be sorted by
+// timestamp.
+func detectNewAccountBurst(account []Transaction, config Config) []FraudResult {
+	if config.NewAccountCount <= 0 || len(account) == 0 {
+		return nil
+	}
+
+	first := account[0].Timestamp
+	end := 0
+	for end < len(account) && account[end].Timestamp.Sub(first) < config.NewAccountWindow {
+		end++
+	}
+	if end <= config.NewAccountCount {
+		return nil
+	}
+
+	reason := newAccountReason(end, account[end-1].Timestamp.Sub(first))
+	results := make([]FraudResult, 0, end)
+	for _, tx := range account[:end] {
+		results = append(results, FraudResult{Transaction: tx, Reason: reason})
+	}
+	return results
+}
+
+// newAccountReason describes count transactions spanning span from an
+// account's first activity
+func newAccountReason(count int, span time.Duration) string {
+	return fmt.Sprintf("New-account burst: %d transactions within %s of first activity", count, shortDuration(span))
+}
+
+// detectVelocity flags bursts where an account has more than
+// config.VelocityCount transactions inside config.VelocityWindow. The account's
+// transactions must be sorted by timestamp.
+func detectVelocity(account []Transaction, config Config) []FraudResult {
+	if config.VelocityCount <= 0 {
+		return nil
+	}
+
+	var results []FraudResult
+	for _, burst := range windowBursts(account, config.VelocityWindow, config.VelocityCount, nil) {
+		span := burst[len(burst)-1].Timestamp.Sub(burst[0].Timestamp)
+		for _, tx := range burst {
+			results = append(results, FraudResult{
+				Transaction: tx,
+				Reason:      fmt.Sprintf("Velocity: %d transactions in %v", len(burst), span),
+			})
+		}
+	}
+	return results
+}
+
+// detectCardTesting flags bursts where an account uses more than
+// config.DistinctMerchants different merchants inside config.MerchantWindow,
+// as when a stolen card is tried at many merchants. Repeat charges at one
+// merchant count once. The account's transactions must be sorted by
+// timestamp.
+func detectCardTesting(account []Transaction, config Config) []FraudResult {
+	if config.DistinctMerchants <= 0 {
+		return nil
+	}
+
+	var results []FraudResult
+	for _, burst := range windowBursts(account, config.MerchantWindow, config.DistinctMerchants, config.merchantKey) {
+		reason := cardTestingReason(distinctMerchants(burst, config), burst[len(burst)-1].Timestamp.Sub(burst[0].Timestamp))
+		for _, tx := range burst {
+			results = append(results, FraudResult{Transaction: tx, Reason: reason})
+		}
+	}
+	return results
+}
+
+// merchantKey returns the normalized merchant of tx, the key of the card
+// testing rule's window
+func (c Config) merchantKey(tx Transaction) string {
+	return c.NormalizeMerchant(tx.Merchant)
+}
+
+// distinctMerchants counts the different merchants of transactions
+func distinctMerchants(transactions []Transaction, config Config) int {
+	seen := make(map[string]bool)
+	for _, tx := range transactions {
+		seen[config.NormalizeMerchant(tx.Merchant)] = true
+	}
+	return len(seen)
+}
+
+// cardTestingReason describes count merchants used within span
+func cardTestingReason(count int, span time.Duration) string {
+	return fmt.Sprintf("Card testing: %d distinct merchants in %s", count, shortDuration(span))
+}
+
+// detectMultipleCards flags bursts where an account uses more than
+// config.MaxCards different cards inside config.CardWindow, as when stolen
+// cards are added to an account. Transactions without a card ID are
+// skipped. The account's transactions must be sorted by timestamp.
+func detectMultipleCards(account []Transaction, config Config) []FraudResult {
+	if config.MaxCards <= 0 {
+		return nil
+	}
+
+	var carded []Transaction
+	for _, tx := range account {
+		if tx.CardID != "" {
+			carded = append(carded, tx)
+		}
+	}
+
+	var results []FraudResult
+	for _, burst := range windowBursts(carded, config.CardWindow, config.MaxCards, cardKey) {
+		reason := multipleCardsReason(distinctCards(burst), config)
+		for _, tx := range burst {
+			results = append(results, FraudResult{Transaction: tx, Reason: reason})
+		}
+	}
+	return results
+}
+
+// cardKey returns the card ID of tx, the key of the multiple cards rule's
+// window
+func cardKey(tx Transaction) string {
+	return tx.CardID
+}
+
+// distinctCards counts the different card IDs of transactions, ignoring
+// transactions without one
+func distinctCards(transactions []Transaction) int {
+	seen := make(map[string]bool)
+	for _, tx := range transactions {
+		if tx.CardID != "" {
+			seen[tx.CardID] = true
+		}
+	}
+	return len(seen)
+}
+
+// multipleCardsReason describes count cards used within the card window
+func multipleCardsReason(count int, config Config) string {
+	return fmt.Sprintf("Multiple instruments: %d cards in %s", count, shortDuration(config.CardWindow))
+}
+
+// countDeviceAccounts counts the distinct accounts of each device ID
+func countDeviceAccounts(transactions []Transaction) map[string]int {
+	accounts := make(map[string]map[string]bool)
+	for _, tx := range transactions {
+		if tx.DeviceID == "" {
+			continue
+		}
+		if accounts[tx.DeviceID] == nil {
+			accounts[tx.DeviceID] = make(map[string]bool)
+		}
+		accounts[tx.DeviceID][tx.AccountID] = true
+	}
+
+	counts := make(map[string]int, len(accounts))
+	for device, ids := range accounts {
+		counts[device] = len(ids)
+	}
+	return counts
+}
+
+// detectSharedDevices flags transactions from devices used by more than
+// config.MaxAccountsPerDevice accounts, as counted by DetectContext
+func detectSharedDevices(account []Transaction, config Config) []FraudResult {
+	if config.MaxAccountsPerDevice <= 0 {
+		return nil
+	}
+
+	var results []FraudResult
+	for _, tx := range account {
+		if n := config.deviceAccounts[tx.DeviceID]; tx.DeviceID != "" && n > config.MaxAccountsPerDevice {
+			results = append(results, FraudResult{Transaction: tx, Reason: sharedDeviceReason(n)})
+		}
+	}
+	return results
+}
+
+// sharedDeviceReason describes a device used by n accounts
+func sharedDeviceReason(n int) string {
+	return fmt.Sprintf("Shared device: used by %d accounts", n)
+}
+
+// detectDuplicates flags transactions with the same amount and merchant as
+// another transaction of the account less than config.DuplicateWindow apart.
+// The account's transactions must be sorted by timestamp.
+func detectDuplicates(account []Transaction, config Config) []FraudResult {
+	var results []FraudResult
+
+	for i, tx := range account {
+		for j := i + 1; j < len(account); j++ {
+			nextTx := account[j]
+			timeDiff := nextTx.Timestamp.Sub(tx.Timestamp)
+			if timeDiff >= config.DuplicateWindow {
+				break
+			}
+			if config.CurrencyOf(tx).compareAmounts(nextTx.Amount, tx.Amount) != 0 || config.NormalizeMerchant(nextTx.Merchant) != config.NormalizeMerchant(tx.Merchant) {
+				continue
+			}
+
+			reason := fmt.Sprintf("Duplicate charge: %s at %s within %v", config.formatAmount(tx, tx.Amount), tx.Merchant, timeDiff)
+			results = append(results, FraudResult{Transaction: tx, Reason: reason})
+			results = append(results, FraudResult{Transaction: nextTx, Reason: reason})
+		}
+	}
+
+	return results
+}
+
+// detectDailyLimit flags every transaction of an account-day, in the
+// configured time zone, whose total amount exceeds config.DailyLimit.
+// Amounts in different currencies are totaled separately. The account's
+// transactions must be sorted by timestamp.
+func detectDailyLimit(account []Transaction, config Config) []FraudResult {
+	if config.DailyLimit <= 0 {
+		return nil
+	}
+
+	var results []FraudResult
+	for start := 0; start < len(account); {
+		day := dayKey(account[start].Timestamp, config)
+		end := start
+		totals := make(map[string]float64)
+		counts := make(map[string]int)
+		for end < len(account) && dayKey(account[end].Timestamp, config) == day {
+			code := config.CurrencyOf(account[end]).Code
+			totals[code] += account[end].Amount
+			counts[code]++
+			end++
+		}
+
+		for _, tx := range account[start:end] {
+			currency := config.CurrencyOf(tx)
+			if total := totals[currency.Code]; currency.compareAmounts(total, config.DailyLimit) > 0 {
+				results = append(results, FraudResult{Transaction: tx, Reason: dailyLimitReason(total, counts[currency.Code], currency)})
+			}
+		}
+		start = end
+	}
+
+	return results
+}
+
+// detectStructuring flags an account-day's transactions within
+// config.StructuringBand below config.StructuringLimit when there are at
+// least config.StructuringCount of them. The account's transactions must be
+// sorted by timestamp.
+func detectStructuring(account []Transaction, config Config) []FraudResult {
+	if config.StructuringLimit <= 0 {
+		return nil
+	}
+
+	var results []FraudResult
+	for start := 0; start < len(account); {
+		day := dayKey(account[start].Timestamp, config)
+		var near []Transaction
+		end := start
+		for end < len(account) && dayKey(account[end].Timestamp, config) == day {
+			if config.nearLimit(account[end]) {
+				near = append(near, account[end])
+			}
+			end++
+		}
+
+		if len(near) >= config.StructuringCount {
+			for _, tx := range near {
+				results = append(results, FraudResult{Transaction: tx, Reason: structuringReason(len(near), tx, config)})
+			}
+		}
+		start = end
+	}
+
+	return results
+}
+
+// nearLimit reports whether the amount of tx is under the structuring limit
+// by no more than the structuring band
+func (c Config) nearLimit(tx Transaction) bool {
+	currency := c.CurrencyOf(tx)
+	return currency.compareAmounts(tx.Amount, c.StructuringLimit) < 0 &&
+		currency.compareAmounts(tx.Amount, c.StructuringLimit*(1-c.StructuringBand)) >= 0
+}
+
+// structuringReason describes count near-limit transactions in an account-day
+func structuringReason(count int, tx Transaction, config Config) string {
+	currency := config.CurrencyOf(tx)
+	if config.StructuringLimit == math.Trunc(config.StructuringLimit) {
+		currency.Decimals = 0
+	}
+	return fmt.Sprintf("Possible structuring: %d transactions near %s limit", count, currency.FormatGrouped(config.StructuringLimit))
+}
+
+// dayKey returns the calendar day of t in the configured time zone. Days
+// follow the zone's clock, so they are 23 or 25 hours long when daylight
+// saving time starts or ends.
+func dayKey(t time.Time, config Config) string {
+	return t.In(config.location()).Format("2006-01-02")
+}
+
+// dailyLimitReason describes an account-day over the daily limit
+func dailyLimitReason(total float64, count int, currency Currency) string {
+	return fmt.Sprintf("Daily total exceeded: %s across %d transactions", currency.FormatGrouped(total), count)
+}
+
+// groupThousands formats an amount with the given decimals and
+// comma-separated thousands, e.g. 4200 becomes "4,200.00" with two decimals
+func groupThousands(amount float64, decimals int) string {
+	s := strconv.FormatFloat(math.Abs(amount), 'f', decimals, 64)
+	whole, frac, hasFrac := strings.Cut(s, ".")
+
+	var b strings.Builder
+	if amount < 0 {
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	if hasFrac {
+		b.WriteByte('.')
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+// earthRadiusKm is the mean radius of the Earth used for haversine distances
+const earthRadiusKm = 6371.0
+
+// hasLocation reports whether the transaction has both coordinates
+func (tx Transaction) hasLocation() bool {
+	return tx.Latitude != nil && tx.Longitude != nil
+}
+
+// detectImpossibleTravel flags consecutive located transactions of an
+// account whose distance implies a speed above config.MaxSpeedKmh.
+// Transactions without coordinates are skipped. The account's transactions
+// must be sorted by timestamp.
+func detectImpossibleTravel(account []Transaction, config Config) []FraudResult {
+	if config.MaxSpeedKmh <= 0 {
+		return nil
+	}
+
+	var results []FraudResult
+	var prev *Transaction
+	for i := range account {
+		if !account[i].hasLocation() {
+			continue
+		}
+		if prev != nil {
+			results = append(results, checkTravel(*prev, account[i], config)...)
+		}
+		prev = &account[i]
+	}
+
+	return results
+}
+
+// checkTravel flags both transactions when moving between their locations
+// in the time between them would exceed config.MaxSpeedKmh
+func checkTravel(prev, tx Transaction, config Config) []FraudResult {
+	distance := haversineKm(*prev.Latitude, *prev.Longitude, *tx.Latitude, *tx.Longitude)
+	elapsed := tx.Timestamp.Sub(prev.Timestamp)
+	if distance == 0 {
+		return nil
+	}
+
+	speed := math.Inf(1)
+	if elapsed > 0 {
+		speed = distance / elapsed.Hours()
+	}
+	if speed <= config.MaxSpeedKmh {
+		return nil
+	}
+
+	reason := fmt.Sprintf("Impossible travel: %.0fkm in %v (%.0f km/h)", distance, elapsed, speed)
+	return []FraudResult{
+		{Transaction: prev, Reason: reason},
+		{Transaction: tx, Reason: reason},
+	}
+}
+
+// haversineKm returns the great-circle distance in kilometers between two
+// points given in decimal degrees
+func haversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	toRadians := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// detectOutliers flags transactions whose amount is more than config.ZScore
+// standard deviations above the mean of the account's other transactions.
+// Accounts with fewer than config.MinSamples transactions are skipped.
+func detectOutliers(account []Transaction, config Config) []FraudResult {
+	if config.ZScore <= 0 || len(account) < 2 || len(account) < config.MinSamples {
+		return nil
+	}
+
+	var sum, sumSq float64
+	for _, tx := range account {
+		sum += tx.Amount
+		sumSq += tx.Amount * tx.Amount
+	}
+
+	var results []FraudResult
+	for _, tx := range account {
+		// Leave the transaction itself out so a single spike cannot mask
+		// itself by inflating the deviation
+		n := float64(len(account) - 1)
+		result, ok := checkOutlier(tx, n, sum-tx.Amount, sumSq-tx.Amount*tx.Amount, config)
+		if ok {
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// detectNewMerchants flags an account's first transaction with each
+// merchant once the account has at least config.HistoryMin earlier
+// transactions. The account's transactions must be sorted by timestamp.
+func detectNewMerchants(account []Transaction, config Config) []FraudResult {
+	if config.HistoryMin <= 0 {
+		return nil
+	}
+
+	var results []FraudResult
+	seen := make(map[string]bool)
+	for i, tx := range account {
+		if result, ok := checkNewMerchant(tx, i, seen, config); ok {
+			results = append(results, result)
+		}
+	}
+
+	return results
+}
+
+// checkNewMerchant flags tx when its merchant is not in seen and the account
+// has at least config.HistoryMin prior transactions, then records the merchant
+func checkNewMerchant(tx Transaction, prior int, seen map[string]bool, config Config) (FraudResult, bool) {
+	merchant := config.NormalizeMerchant(tx.Merchant)
+	if seen[merchant] {
+		return FraudResult{}, false
+	}
+	seen[merchant] = true
+
+	if prior < config.HistoryMin {
+		return FraudResult{}, false
+	}
+	return FraudResult{
+		Transaction: tx,
+		Reason:      fmt.Sprintf("First transaction with merchant %s", tx.Merchant),
+	}, true
+}
+
+// checkOutlier flags tx when its amount is more than config.ZScore standard
+// deviations above the mean of n other amounts with the given sum and sum of
+// squares
+func checkOutlier(tx Transaction, n, sum, sumSq float64, config Config) (FraudResult, bool) {
+	mean := sum / n
+	variance := sumSq/n - mean*mean
+	if variance <= 0 {
+		return FraudResult{}, false
+	}
+
+	z := (tx.Amount - mean) / math.Sqrt(variance)
+	if z <= config.ZScore {
+		return FraudResult{}, false
+	}
+
+	return FraudResult{
+		Transaction: tx,
+		Reason:      fmt.Sprintf("Statistical outlier: %s (z=%.1f, mean=%s)", config.formatAmount(tx, tx.Amount), z, config.formatAmount(tx, mean)),
+	}, true
+}
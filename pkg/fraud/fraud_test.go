@@ -0,0 +1,915 @@
+package fraud
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testStart is the time test transactions are offset from
+var testStart = time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+// testTx returns a transaction of account at offset after testStart
+func testTx(id, account string, offset time.Duration, amount float64, merchant string) Transaction {
+	return Transaction{ID: id, Amount: amount, Timestamp: testStart.Add(offset), AccountID: account, Merchant: merchant}
+}
+
+// onlyRules returns the built-in rules of the given names
+func onlyRules(t *testing.T, names ...string) []Rule {
+	t.Helper()
+	rules, err := LookupRules(names)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rules
+}
+
+// flaggedIDs returns the sorted IDs of results whose reason contains substr
+func flaggedIDs(results []FraudResult, substr string) []string {
+	var ids []string
+	for _, result := range results {
+		if strings.Contains(result.Reason, substr) {
+			ids = append(ids, result.Transaction.ID)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+func TestRapidSuccessionAcrossBatchBoundary(t *testing.T) {
+	// 99 unrelated transactions put A's pair at rows 100 and 101, on either
+	// side of what used to be a fixed 100-row batch boundary
+	var transactions []Transaction
+	for i := 0; i < 99; i++ {
+		transactions = append(transactions, testTx(fmt.Sprint(i), fmt.Sprintf("F%d", i), time.Duration(i)*time.Hour, 10, "Shop"))
+	}
+	transactions = append(transactions,
+		testTx("a1", "A", 0, 10, "Shop"),
+		testTx("a2", "A", time.Minute, 10, "Shop"),
+	)
+
+	for _, batchSize := range []int{0, 1, 100} {
+		config := Config{HighAmountThreshold: 1000, TimeWindow: 5 * time.Minute, BatchSize: batchSize}
+		got := flaggedIDs(Detect(transactions, config), "Rapid")
+		if want := []string{"a1", "a2"}; !slices.Equal(got, want) {
+			t.Errorf("batch size %d: flagged %v, want %v", batchSize, got, want)
+		}
+	}
+}
+
+func TestVelocityThreshold(t *testing.T) {
+	var transactions []Transaction
+	// E has exactly VelocityCount transactions in the window, O one more
+	for i := 0; i < 3; i++ {
+		transactions = append(transactions, testTx(fmt.Sprintf("e%d", i), "E", time.Duration(i)*time.Minute, 10, "Shop"))
+	}
+	for i := 0; i < 4; i++ {
+		transactions = append(transactions, testTx(fmt.Sprintf("o%d", i), "O", time.Duration(i)*time.Minute, 10, "Shop"))
+	}
+
+	config := Config{VelocityCount: 3, VelocityWindow: 10 * time.Minute}
+	got := flaggedIDs(Detect(transactions, config), "Velocity")
+	if want := []string{"o0", "o1", "o2", "o3"}; !slices.Equal(got, want) {
+		t.Errorf("flagged %v, want %v", got, want)
+	}
+}
+
+func TestResultsMergedPerTransaction(t *testing.T) {
+	transactions := []Transaction{
+		testTx("1", "A", 0, 5000, "Shop"),
+		testTx("2", "A", time.Minute, 10, "Shop"),
+	}
+	config := Config{HighAmountThreshold: 1000, TimeWindow: 5 * time.Minute}
+
+	results := Detect(transactions, config)
+	var rows []FraudResult
+	for _, result := range results {
+		if result.Transaction.ID == "1" {
+			rows = append(rows, result)
+		}
+	}
+	if len(rows) != 1 {
+		t.Fatalf("transaction 1 has %d results, want 1: %v", len(rows), rows)
+	}
+	if want := "High amount: $5000.00; Rapid: 1 related transaction within 5m"; rows[0].Reason != want {
+		t.Errorf("reason = %q, want %q", rows[0].Reason, want)
+	}
+}
+
+func TestDuplicateWindowBoundary(t *testing.T) {
+	transactions := []Transaction{
+		// Inside the window: a second under a minute apart
+		testTx("in1", "A", 0, 49.99, "Amazon"),
+		testTx("in2", "A", 59*time.Second, 49.99, "Amazon"),
+		// Exactly the window apart, which is not within it
+		testTx("at1", "B", 0, 49.99, "Amazon"),
+		testTx("at2", "B", time.Minute, 49.99, "Amazon"),
+		// Same amount far apart, or at another merchant
+		testTx("far1", "C", 0, 49.99, "Amazon"),
+		testTx("far2", "C", 3*time.Hour, 49.99, "Amazon"),
+		testTx("other1", "D", 0, 49.99, "Amazon"),
+		testTx("other2", "D", time.Second, 49.99, "eBay"),
+	}
+	config := Config{DuplicateWindow: time.Minute, Rules: onlyRules(t, "duplicate")}
+
+	results := Detect(transactions, config)
+	if got, want := flaggedIDs(results, "Duplicate charge"), []string{"in1", "in2"}; !slices.Equal(got, want) {
+		t.Errorf("flagged %v, want %v", got, want)
+	}
+	for _, result := range results {
+		if want := "Duplicate charge: $49.99 at Amazon within 59s"; result.Reason != want {
+			t.Errorf("reason = %q, want %q", result.Reason, want)
+		}
+	}
+}
+
+func TestAutoBatchSize(t *testing.T) {
+	batches := runtime.NumCPU() * batchesPerCPU
+	for _, n := range []int{0, 1, batches, 10*batches + 1, 10000000} {
+		size := autoBatchSize(n)
+		if size < 1 || (n+size-1)/size > batches {
+			t.Errorf("autoBatchSize(%d) = %d, making more than %d batches", n, size, batches)
+		}
+	}
+}
+
+// benchmarkTransactions returns n transactions spread over 10,000 accounts
+func benchmarkTransactions(n int) []Transaction {
+	transactions := make([]Transaction, n)
+	for i := range transactions {
+		transactions[i] = testTx(fmt.Sprint(i), fmt.Sprintf("A%d", i%10000), time.Duration(i)*time.Second, float64(10+i%5000), fmt.Sprintf("M%d", i%50))
+	}
+	return transactions
+}
+
+// BenchmarkBatchSize compares fixed batch sizes with the size chosen from
+// the CPU count
+func BenchmarkBatchSize(b *testing.B) {
+	transactions := benchmarkTransactions(500000)
+	config := Config{
+		HighAmountThreshold: 4000,
+		TimeWindow:          5 * time.Minute,
+		VelocityCount:       5,
+		VelocityWindow:      10 * time.Minute,
+	}
+	for _, size := range []int{100, 10000, 0} {
+		name := fmt.Sprintf("size=%d", size)
+		if size == 0 {
+			name = fmt.Sprintf("size=auto(%d)", autoBatchSize(len(transactions)))
+		}
+		b.Run(name, func(b *testing.B) {
+			config.BatchSize = size
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Detect(transactions, config)
+			}
+		})
+	}
+}
+
+// detectWithMutex is DetectContext as it was before the channel pipeline:
+// workers append each batch's results to a shared slice under a mutex. It is
+// kept to benchmark the two against each other.
+func detectWithMutex(transactions []Transaction, config Config) []FraudResult {
+	var batches [][][]Transaction
+	var batch [][]Transaction
+	count := 0
+	accounts := groupByAccount(transactions)
+	for i, account := range accounts {
+		batch = append(batch, account)
+		count += len(account)
+		if count < config.BatchSize && i < len(accounts)-1 {
+			continue
+		}
+		batches = append(batches, batch)
+		batch = nil
+		count = 0
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var results []FraudResult
+	next := make(chan int)
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				batchResults := processBatch(context.Background(), batches[i], config)
+				mu.Lock()
+				results = append(results, batchResults...)
+				mu.Unlock()
+			}
+		}()
+	}
+	for i := range batches {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	merged := MergeResults(results)
+	SortResults(merged, SortByTimestamp)
+	return merged
+}
+
+func BenchmarkResultCollection(b *testing.B) {
+	transactions := benchmarkTransactions(500000)
+	config := Config{
+		HighAmountThreshold: 4000,
+		TimeWindow:          5 * time.Minute,
+		VelocityCount:       5,
+		VelocityWindow:      10 * time.Minute,
+		BatchSize:           100, // Many small batches, so collection is contended
+	}
+	if got, want := len(detectWithMutex(transactions, config)), len(Detect(transactions, config)); got != want {
+		b.Fatalf("mutex collection found %d results, channel pipeline %d", got, want)
+	}
+
+	b.Run("mutex", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			detectWithMutex(transactions, config)
+		}
+	})
+	b.Run("channel", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			Detect(transactions, config)
+		}
+	})
+}
+
+func TestOffHours(t *testing.T) {
+	at := func(hour, minute int) Transaction {
+		return Transaction{ID: "1", Amount: 10, Timestamp: time.Date(2024, 1, 1, hour, minute, 0, 0, time.UTC), AccountID: "A", Merchant: "Shop"}
+	}
+	tests := []struct {
+		name       string
+		start, end int
+		tx         Transaction
+		want       string
+	}{
+		{"inside", 2, 5, at(3, 14), "Off-hours transaction at 03:14"},
+		{"at start", 2, 5, at(2, 0), "Off-hours transaction at 02:00"},
+		{"at end", 2, 5, at(5, 0), ""},
+		{"before", 2, 5, at(1, 59), ""},
+		{"wrapping late", 22, 5, at(23, 30), "Off-hours transaction at 23:30"},
+		{"wrapping early", 22, 5, at(4, 59), "Off-hours transaction at 04:59"},
+		{"wrapping outside", 22, 5, at(12, 0), ""},
+		{"disabled", 3, 3, at(3, 0), ""},
+	}
+	for _, tt := range tests {
+		var got string
+		if results := Detect([]Transaction{tt.tx}, Config{HighAmountThreshold: 1000, OffHoursStart: tt.start, OffHoursEnd: tt.end}); len(results) > 0 {
+			got = results[0].Reason
+		}
+		if got != tt.want {
+			t.Errorf("%s: reason = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestOffHoursLocation(t *testing.T) {
+	location := time.FixedZone("UTC-5", -5*60*60)
+	// 07:30 UTC is 02:30 five hours west
+	tx := Transaction{ID: "1", Amount: 10, Timestamp: time.Date(2024, 1, 1, 7, 30, 0, 0, time.UTC), AccountID: "A", Merchant: "Shop"}
+
+	if results := Detect([]Transaction{tx}, Config{HighAmountThreshold: 1000, OffHoursStart: 2, OffHoursEnd: 5}); len(results) != 0 {
+		t.Errorf("flagged in UTC: %v", results)
+	}
+	results := Detect([]Transaction{tx}, Config{HighAmountThreshold: 1000, OffHoursStart: 2, OffHoursEnd: 5, Location: location})
+	if len(results) != 1 || results[0].Reason != "Off-hours transaction at 02:30" {
+		t.Errorf("results in UTC-5 = %v, want one at 02:30", results)
+	}
+}
+
+func TestRoundAmount(t *testing.T) {
+	config := Config{HighAmountThreshold: 10000, RoundMultiple: 100, RoundMin: 500}
+	tests := []struct {
+		amount float64
+		want   string
+	}{
+		{499.99, ""},
+		{500, "Round amount: $500.00 (multiple of 100)"},
+		{1000, "Round amount: $1000.00 (multiple of 100)"},
+		{550, ""},
+		{100, ""}, // Below the floor
+	}
+	for _, tt := range tests {
+		var got string
+		if results := Detect([]Transaction{testTx("1", "A", 0, tt.amount, "ATM")}, config); len(results) > 0 {
+			got = results[0].Reason
+		}
+		if got != tt.want {
+			t.Errorf("amount %v: reason = %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestDailyLimit(t *testing.T) {
+	transactions := []Transaction{
+		// A stays under the limit on each transaction but not in total
+		testTx("a1", "A", 0, 1500, "Shop"),
+		testTx("a2", "A", time.Hour, 1500, "Shop"),
+		testTx("a3", "A", 2*time.Hour, 1200, "Shop"),
+		// A's next day starts a new total
+		testTx("a4", "A", 24*time.Hour, 1500, "Shop"),
+		// B's total is exactly the limit
+		testTx("b1", "B", 0, 2000, "Shop"),
+		testTx("b2", "B", time.Hour, 2000, "Shop"),
+	}
+	config := Config{DailyLimit: 4000, Rules: onlyRules(t, "daily-limit")}
+
+	results := Detect(transactions, config)
+	if got, want := flaggedIDs(results, ""), []string{"a1", "a2", "a3"}; !slices.Equal(got, want) {
+		t.Fatalf("flagged %v, want %v", got, want)
+	}
+	if want := "Daily total exceeded: $4,200.00 across 3 transactions"; results[0].Reason != want {
+		t.Errorf("reason = %q, want %q", results[0].Reason, want)
+	}
+}
+
+func TestDailyLimitMixedCurrencies(t *testing.T) {
+	in := func(tx Transaction, currency string) Transaction {
+		tx.Currency = currency
+		return tx
+	}
+	transactions := []Transaction{
+		// Neither total is over the limit, though their sum is
+		in(testTx("jpy1", "A", 0, 9000, "Shop"), "JPY"),
+		in(testTx("usd1", "A", time.Hour, 100, "Shop"), "USD"),
+		// B's yen are over the limit, its dollars are not
+		in(testTx("jpy2", "B", 0, 6000, "Shop"), "JPY"),
+		in(testTx("usd2", "B", time.Hour, 9000, "Shop"), ""),
+		in(testTx("jpy3", "B", 2*time.Hour, 6000, "Shop"), "JPY"),
+	}
+	config := Config{DailyLimit: 10000, Rules: onlyRules(t, "daily-limit")}
+
+	for name, results := range map[string][]FraudResult{"batch": Detect(transactions, config), "stream": streamResults(transactions, config)} {
+		if got, want := flaggedIDs(results, ""), []string{"jpy2", "jpy3"}; !slices.Equal(got, want) {
+			t.Errorf("%s: flagged %v, want %v", name, got, want)
+			continue
+		}
+		for _, result := range results {
+			if want := "Daily total exceeded: ¥12,000 across 2 transactions"; result.Reason != want {
+				t.Errorf("%s: %s reason = %q, want %q", name, result.Transaction.ID, result.Reason, want)
+			}
+		}
+	}
+}
+
+func TestImpossibleTravel(t *testing.T) {
+	located := func(id string, offset time.Duration, lat, lon float64) Transaction {
+		tx := testTx(id, "A", offset, 10, "Shop")
+		tx.Latitude, tx.Longitude = &lat, &lon
+		return tx
+	}
+	transactions := []Transaction{
+		located("london", 0, 51.5074, -0.1278),
+		testTx("unlocated", "A", 5*time.Minute, 10, "Shop"),
+		located("paris", 10*time.Minute, 48.8566, 2.3522),
+		// A day later the same trip is plausible
+		located("london-later", 24*time.Hour, 51.5074, -0.1278),
+	}
+	config := Config{MaxSpeedKmh: 900, Rules: onlyRules(t, "impossible-travel")}
+
+	results := Detect(transactions, config)
+	if got, want := flaggedIDs(results, ""), []string{"london", "paris"}; !slices.Equal(got, want) {
+		t.Fatalf("flagged %v, want %v", got, want)
+	}
+	if want := "Impossible travel: 344km in 10m0s (2061 km/h)"; results[0].Reason != want {
+		t.Errorf("reason = %q, want %q", results[0].Reason, want)
+	}
+}
+
+func TestDetectOrderIsStable(t *testing.T) {
+	transactions := benchmarkTransactions(2000)
+	config := Config{HighAmountThreshold: 500, TimeWindow: time.Minute, BatchSize: 10, Concurrency: 8}
+
+	want := Detect(transactions, config)
+	if len(want) == 0 {
+		t.Fatal("nothing flagged")
+	}
+	for run := 0; run < 5; run++ {
+		got := Detect(transactions, config)
+		if !slices.EqualFunc(got, want, func(a, b FraudResult) bool { return a.Transaction.ID == b.Transaction.ID }) {
+			t.Fatalf("run %d ordered results differently", run)
+		}
+	}
+}
+
+func TestSortResults(t *testing.T) {
+	results := []FraudResult{
+		{Transaction: testTx("c", "B", time.Minute, 50, "Shop")},
+		{Transaction: testTx("b", "A", time.Minute, 200, "Shop")},
+		{Transaction: testTx("a", "B", time.Minute, 50, "Shop")},
+		{Transaction: testTx("d", "A", 0, 10, "Shop")},
+	}
+	tests := []struct {
+		by   string
+		want []string
+	}{
+		{SortByTimestamp, []string{"d", "a", "b", "c"}},
+		{SortByAmount, []string{"b", "a", "c", "d"}},
+		{SortByAccount, []string{"d", "b", "a", "c"}},
+	}
+	for _, tt := range tests {
+		if err := SortResults(results, tt.by); err != nil {
+			t.Fatalf("SortResults(%s): %v", tt.by, err)
+		}
+		var got []string
+		for _, result := range results {
+			got = append(got, result.Transaction.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("SortResults(%s) order = %v, want %v", tt.by, got, tt.want)
+		}
+	}
+
+	if err := SortResults(results, "merchant"); err == nil {
+		t.Error("SortResults accepted an unsupported key")
+	}
+}
+
+func TestWhitelist(t *testing.T) {
+	transactions := []Transaction{
+		testTx("internal", "Treasury", 0, 50000, "Shop"),
+		testTx("partner", "A", time.Hour, 50000, "Partner"),
+		testTx("other", "B", 2*time.Hour, 50000, "Shop"),
+	}
+	config := Config{
+		HighAmountThreshold: 1000,
+		Whitelist:           map[string]bool{"treasury": true, "partner": true},
+	}
+
+	if got, want := flaggedIDs(Detect(transactions, config), ""), []string{"other"}; !slices.Equal(got, want) {
+		t.Errorf("Detect flagged %v, want %v", got, want)
+	}
+	if got, want := flaggedIDs(streamResults(transactions, config), ""), []string{"other"}; !slices.Equal(got, want) {
+		t.Errorf("stream flagged %v, want %v", got, want)
+	}
+}
+
+func TestOutlier(t *testing.T) {
+	var transactions []Transaction
+	for i, amount := range []float64{100, 110, 120, 130, 140, 850} {
+		transactions = append(transactions, testTx(fmt.Sprintf("a%d", i), "A", time.Duration(i)*time.Hour, amount, "Shop"))
+	}
+	// B spikes too, but has too few transactions to judge
+	for i, amount := range []float64{100, 110, 850} {
+		transactions = append(transactions, testTx(fmt.Sprintf("b%d", i), "B", time.Duration(i)*time.Hour, amount, "Shop"))
+	}
+	config := Config{ZScore: 3, MinSamples: 5, Rules: onlyRules(t, "outlier")}
+
+	results := Detect(transactions, config)
+	if got, want := flaggedIDs(results, ""), []string{"a5"}; !slices.Equal(got, want) {
+		t.Fatalf("flagged %v, want %v", got, want)
+	}
+	if want := "Statistical outlier: $850.00 (z=51.6, mean=$120.00)"; results[0].Reason != want {
+		t.Errorf("reason = %q, want %q", results[0].Reason, want)
+	}
+}
+
+func TestNewMerchant(t *testing.T) {
+	transactions := []Transaction{
+		testTx("1", "A", 0, 10, "Grocer"),
+		testTx("2", "A", time.Hour, 10, "Cafe"),
+		testTx("3", "A", 2*time.Hour, 10, "Grocer"),
+		testTx("4", "A", 3*time.Hour, 10, "NewStore"),
+		testTx("5", "A", 4*time.Hour, 10, "NewStore"),
+		// B has too little history for a new merchant to stand out
+		testTx("6", "B", 0, 10, "Grocer"),
+		testTx("7", "B", time.Hour, 10, "NewStore"),
+	}
+	config := Config{HistoryMin: 3, Rules: onlyRules(t, "new-merchant")}
+
+	results := Detect(transactions, config)
+	if got, want := flaggedIDs(results, ""), []string{"4"}; !slices.Equal(got, want) {
+		t.Fatalf("flagged %v, want %v", got, want)
+	}
+	if want := "First transaction with merchant NewStore"; results[0].Reason != want {
+		t.Errorf("reason = %q, want %q", results[0].Reason, want)
+	}
+}
+
+func TestNegativeAndZeroAmounts(t *testing.T) {
+	config := Config{MinAmount: -500, FlagZero: true}
+	tests := []struct {
+		amount float64
+		want   string
+	}{
+		{-2000, "Refund below minimum: -$2000.00 (minimum -$500.00)"},
+		{-100, ""},
+		{0, "Zero amount: $0.00 authorization, possible card probe"},
+		{0.001, "Zero amount: $0.00 authorization, possible card probe"},
+		{25, ""},
+	}
+	for _, tt := range tests {
+		tx := testTx("1", "A", 0, tt.amount, "Shop")
+		var reasons []string
+		for _, result := range append(checkMinAmount(tx, config), checkZeroAmount(tx, config)...) {
+			reasons = append(reasons, result.Reason)
+		}
+		if got := strings.Join(reasons, "; "); got != tt.want {
+			t.Errorf("amount %v: reasons %q, want %q", tt.amount, got, tt.want)
+		}
+	}
+
+	// Zero amounts are only flagged on request
+	if results := checkZeroAmount(testTx("1", "A", 0, 0, "Shop"), Config{}); len(results) != 0 {
+		t.Errorf("zero amount flagged without FlagZero: %+v", results)
+	}
+}
+
+func TestRapidBurstFlagsEachOnce(t *testing.T) {
+	var transactions []Transaction
+	for i := 0; i < 4; i++ {
+		transactions = append(transactions, testTx(fmt.Sprint(i), "A", time.Duration(i)*time.Minute, 10, "Shop"))
+	}
+	config := Config{TimeWindow: 5 * time.Minute, Rules: onlyRules(t, "rapid-succession")}
+
+	results := Detect(transactions, config)
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+	for _, result := range results {
+		if want := "Rapid: 3 related transactions within 5m"; result.Reason != want {
+			t.Errorf("transaction %s reason = %q, want %q", result.Transaction.ID, result.Reason, want)
+		}
+	}
+
+	// Streaming sees only earlier transactions, but still flags each once
+	if results := streamResults(transactions, config); len(results) != 4 {
+		t.Errorf("stream got %d results, want 4", len(results))
+	}
+}
+
+func TestCategoryThresholds(t *testing.T) {
+	config := Config{
+		HighAmountThreshold: 1000,
+		CategoryThresholds:  map[string]float64{"withdrawal": 300, "transfer": 5000},
+	}
+	tests := []struct {
+		category string
+		amount   float64
+		want     string
+	}{
+		{"withdrawal", 400, "High amount: $400.00 (withdrawal limit $300.00)"},
+		{" Withdrawal ", 400, "High amount: $400.00 (withdrawal limit $300.00)"},
+		{"withdrawal", 300, ""},
+		{"transfer", 4000, ""},
+		{"transfer", 6000, "High amount: $6000.00 (transfer limit $5000.00)"},
+		{"", 1500, "High amount: $1500.00"},
+		{"purchase", 800, ""},
+	}
+	for _, tt := range tests {
+		tx := testTx("1", "A", 0, tt.amount, "Shop")
+		tx.Category = tt.category
+		var got string
+		if results := checkHighAmount(tx, config); len(results) > 0 {
+			got = results[0].Reason
+		}
+		if got != tt.want {
+			t.Errorf("%q %v: reason = %q, want %q", tt.category, tt.amount, got, tt.want)
+		}
+	}
+}
+
+func TestAccountThresholds(t *testing.T) {
+	// The same amount is normal for the business account but not the student
+	config := Config{
+		HighAmountThreshold: 1000,
+		AccountThresholds:   map[string]float64{"biz-1": 5000},
+		Rules:               onlyRules(t, "high-amount"),
+	}
+	transactions := []Transaction{
+		testTx("biz", "BIZ-1", 0, 3000, "Supplier"),
+		testTx("student", "STUDENT-1", 0, 3000, "Supplier"),
+	}
+	if got, want := flaggedIDs(Detect(transactions, config), "High amount"), []string{"student"}; !slices.Equal(got, want) {
+		t.Errorf("flagged %v, want %v", got, want)
+	}
+}
+
+func TestBlocklistIgnoresCase(t *testing.T) {
+	config := Config{
+		MerchantBlocklist: map[string]bool{"sketchyshop": true, "fraud mart": true},
+		Rules:             onlyRules(t, "blocklist"),
+	}
+	transactions := []Transaction{
+		testTx("1", "A", 0, 10, "sketchyshop"),
+		testTx("2", "A", time.Hour, 10, "SKETCHYSHOP"),
+		testTx("3", "A", 2*time.Hour, 10, " Fraud  Mart"),
+		testTx("4", "A", 3*time.Hour, 10, "SketchyShop Outlet"),
+		testTx("5", "A", 4*time.Hour, 10, "Grocer"),
+	}
+
+	results := Detect(transactions, config)
+	if got, want := flaggedIDs(results, "Blocklisted merchant"), []string{"1", "2", "3"}; !slices.Equal(got, want) {
+		t.Errorf("flagged %v, want %v", got, want)
+	}
+	if len(results) > 1 && results[1].Reason != "Blocklisted merchant: SKETCHYSHOP" {
+		t.Errorf("reason = %q", results[1].Reason)
+	}
+}
+
+func TestStructuring(t *testing.T) {
+	transactions := []Transaction{
+		testTx("1", "A", 0, 9500, "Bank"),
+		testTx("2", "A", time.Hour, 9800, "Bank"),
+		testTx("3", "A", 2*time.Hour, 4000, "Bank"),
+		// At the limit is not under it
+		testTx("4", "A", 3*time.Hour, 10000, "Bank"),
+		// B has a single near-limit transaction
+		testTx("5", "B", 0, 9900, "Bank"),
+	}
+	config := Config{StructuringLimit: 10000, StructuringBand: 0.05, StructuringCount: 2, Rules: onlyRules(t, "structuring")}
+
+	results := Detect(transactions, config)
+	if got, want := flaggedIDs(results, ""), []string{"1", "2"}; !slices.Equal(got, want) {
+		t.Fatalf("flagged %v, want %v", got, want)
+	}
+	if want := "Possible structuring: 2 transactions near $10,000 limit"; results[0].Reason != want {
+		t.Errorf("reason = %q, want %q", results[0].Reason, want)
+	}
+}
+
+func TestSequentialMatchesParallel(t *testing.T) {
+	// 100 accounts with a transaction each every 100 seconds
+	var transactions []Transaction
+	for i := 0; i < 5000; i++ {
+		transactions = append(transactions, testTx(fmt.Sprint(i), fmt.Sprintf("A%d", i%100), time.Duration(i)*time.Second, float64(10+i%5000), fmt.Sprintf("M%d", i%3)))
+	}
+	config := Config{
+		HighAmountThreshold: 4000,
+		TimeWindow:          2 * time.Minute,
+		VelocityCount:       5,
+		VelocityWindow:      10 * time.Minute,
+		DuplicateWindow:     time.Hour,
+		BatchSize:           50,
+	}
+
+	config.Concurrency = 1
+	sequential := Detect(transactions, config)
+	if len(sequential) == 0 {
+		t.Fatal("nothing flagged")
+	}
+	for _, workers := range []int{2, 8, 32} {
+		config.Concurrency = workers
+		if parallel := Detect(transactions, config); !reflect.DeepEqual(parallel, sequential) {
+			t.Errorf("%d workers: %d results differ from %d sequential ones", workers, len(parallel), len(sequential))
+		}
+	}
+}
+
+func TestCardTesting(t *testing.T) {
+	var transactions []Transaction
+	// A tries five merchants in four minutes
+	for i := 0; i < 5; i++ {
+		transactions = append(transactions, testTx(fmt.Sprintf("a%d", i), "A", time.Duration(i)*time.Minute, 1, fmt.Sprintf("Store %d", i)))
+	}
+	// B charges one merchant just as often, and C spreads its merchants out
+	for i := 0; i < 5; i++ {
+		transactions = append(transactions, testTx(fmt.Sprintf("b%d", i), "B", time.Duration(i)*time.Minute, 1, "Store 0"))
+		transactions = append(transactions, testTx(fmt.Sprintf("c%d", i), "C", time.Duration(i)*time.Hour, 1, fmt.Sprintf("Store %d", i)))
+	}
+	config := Config{DistinctMerchants: 4, MerchantWindow: 10 * time.Minute, Rules: onlyRules(t, "card-testing")}
+
+	for _, results := range [][]FraudResult{Detect(transactions, config), streamResults(transactions, config)} {
+		if got, want := flaggedIDs(results, ""), []string{"a0", "a1", "a2", "a3", "a4"}; !slices.Equal(got, want) {
+			t.Fatalf("flagged %v, want %v", got, want)
+		}
+	}
+	results := Detect(transactions, config)
+	if want := "Card testing: 5 distinct merchants in 4m"; results[0].Reason != want {
+		t.Errorf("reason = %q, want %q", results[0].Reason, want)
+	}
+}
+
+func TestSharedDevice(t *testing.T) {
+	onDevice := func(id, account, device string) Transaction {
+		tx := testTx(id, account, 0, 10, "Shop")
+		tx.DeviceID = device
+		return tx
+	}
+	var transactions []Transaction
+	for i := 0; i < 5; i++ {
+		transactions = append(transactions, onDevice(fmt.Sprintf("ring%d", i), fmt.Sprintf("A%d", i), "dev-ring"))
+	}
+	transactions = append(transactions,
+		onDevice("ring-again", "A0", "dev-ring"),
+		onDevice("family1", "F1", "dev-family"),
+		onDevice("family2", "F2", "dev-family"),
+		onDevice("none1", "N1", ""),
+		onDevice("none2", "N2", ""),
+		onDevice("none3", "N3", ""),
+	)
+	config := Config{MaxAccountsPerDevice: 2, Rules: onlyRules(t, "shared-device")}
+
+	results := Detect(transactions, config)
+	want := []string{"ring-again", "ring0", "ring1", "ring2", "ring3", "ring4"}
+	if got := flaggedIDs(results, ""); !slices.Equal(got, want) {
+		t.Fatalf("flagged %v, want %v", got, want)
+	}
+	if want := "Shared device: used by 5 accounts"; results[0].Reason != want {
+		t.Errorf("reason = %q, want %q", results[0].Reason, want)
+	}
+}
+
+func TestDayBucketsAcrossDST(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Skipf("loading time zone: %v", err)
+	}
+	at := func(id string, utc time.Time) Transaction {
+		return Transaction{ID: id, Amount: 600, Timestamp: utc, AccountID: "A", Merchant: "Shop"}
+	}
+	// Clocks in Chicago went forward at 2024-03-10 02:00, from UTC-6 to UTC-5
+	transactions := []Transaction{
+		at("sat-night", time.Date(2024, 3, 10, 5, 30, 0, 0, time.UTC)), // Mar 9 23:30 CST
+		at("sun-early", time.Date(2024, 3, 10, 6, 30, 0, 0, time.UTC)), // Mar 10 00:30 CST
+		at("sun-late", time.Date(2024, 3, 11, 4, 30, 0, 0, time.UTC)),  // Mar 10 23:30 CDT
+		at("mon-early", time.Date(2024, 3, 11, 5, 30, 0, 0, time.UTC)), // Mar 11 00:30 CDT, but Mar 10 at UTC-6
+	}
+	config := Config{DailyLimit: 1000, Location: chicago, Rules: onlyRules(t, "daily-limit")}
+
+	days := make(map[string]string)
+	for _, tx := range transactions {
+		days[tx.ID] = dayKey(tx.Timestamp, config)
+	}
+	want := map[string]string{"sat-night": "2024-03-09", "sun-early": "2024-03-10", "sun-late": "2024-03-10", "mon-early": "2024-03-11"}
+	if !reflect.DeepEqual(days, want) {
+		t.Errorf("days = %v, want %v", days, want)
+	}
+
+	if got, want := flaggedIDs(Detect(transactions, config), ""), []string{"sun-early", "sun-late"}; !slices.Equal(got, want) {
+		t.Errorf("flagged %v, want %v", got, want)
+	}
+}
+
+func TestMerchantAliases(t *testing.T) {
+	config := Config{
+		DuplicateWindow: time.Hour,
+		MerchantAliases: []MerchantAlias{{Pattern: regexp.MustCompile(`(?i)^(amazon(\.com)?|amzn\b)`), Name: "Amazon"}},
+		Rules:           onlyRules(t, "duplicate"),
+	}
+	for _, merchant := range []string{"AMAZON", "Amazon.com", "  AMZN   Mktp "} {
+		if got := config.NormalizeMerchant(merchant); got != "amazon" {
+			t.Errorf("NormalizeMerchant(%q) = %q, want %q", merchant, got, "amazon")
+		}
+	}
+	if got := config.NormalizeMerchant(" Corner  SHOP"); got != "corner shop" {
+		t.Errorf("NormalizeMerchant(%q) = %q, want %q", " Corner  SHOP", got, "corner shop")
+	}
+
+	// Each pair of variants is a duplicate charge at the same merchant
+	transactions := []Transaction{
+		testTx("a1", "A", 0, 49.99, "AMAZON"),
+		testTx("a2", "A", time.Minute, 49.99, "Amazon.com"),
+		testTx("b1", "B", 0, 49.99, "Amazon.com"),
+		testTx("b2", "B", time.Minute, 49.99, "AMZN Mktp"),
+		testTx("c1", "C", 0, 49.99, "AMZN Mktp"),
+		testTx("c2", "C", time.Minute, 49.99, "AMAZON"),
+	}
+	results := Detect(transactions, config)
+	if got, want := flaggedIDs(results, "Duplicate charge"), []string{"a1", "a2", "b1", "b2", "c1", "c2"}; !slices.Equal(got, want) {
+		t.Fatalf("flagged %v, want %v", got, want)
+	}
+	for _, result := range results {
+		// The original name is kept for display
+		if result.Transaction.Merchant == "" || result.Transaction.Merchant == "amazon" {
+			t.Errorf("%s: merchant = %q, want the original name", result.Transaction.ID, result.Transaction.Merchant)
+		}
+	}
+
+	config.MerchantAliases = nil
+	if got := flaggedIDs(Detect(transactions, config), ""); len(got) != 0 {
+		t.Errorf("without aliases flagged %v, want none", got)
+	}
+}
+
+func TestRapidEscalation(t *testing.T) {
+	transactions := []Transaction{
+		// A small test charge, then a large one
+		testTx("esc1", "E", 0, 1, "Shop"),
+		testTx("esc2", "E", time.Minute, 500, "Shop"),
+		// Rapid, but flat
+		testTx("flat1", "F", 0, 100, "Shop"),
+		testTx("flat2", "F", time.Minute, 100, "Shop"),
+		// Escalating by exactly the factor, which is not more than it
+		testTx("factor1", "X", 0, 100, "Shop"),
+		testTx("factor2", "X", time.Minute, 300, "Shop"),
+		// Falling, or escalating outside the window
+		testTx("fall1", "D", 0, 500, "Shop"),
+		testTx("fall2", "D", time.Minute, 1, "Shop"),
+		testTx("slow1", "S", 0, 1, "Shop"),
+		testTx("slow2", "S", 10*time.Minute, 500, "Shop"),
+	}
+	config := Config{TimeWindow: 5 * time.Minute, RapidEscalation: 3, Rules: onlyRules(t, "rapid-succession")}
+
+	results := Detect(transactions, config)
+	if got, want := flaggedIDs(results, ""), []string{"esc1", "esc2"}; !slices.Equal(got, want) {
+		t.Fatalf("flagged %v, want %v", got, want)
+	}
+	for _, result := range results {
+		if want := "Rapid escalation: $1.00 to $500.00 within 1m"; result.Reason != want {
+			t.Errorf("%s: reason = %q, want %q", result.Transaction.ID, result.Reason, want)
+		}
+	}
+	if got := flaggedIDs(streamResults(transactions, config), ""); !slices.Equal(got, []string{"esc1", "esc2"}) {
+		t.Errorf("stream flagged %v, want esc1 and esc2", got)
+	}
+
+	// Without escalation every rapid pair is flagged, whatever the amounts
+	config.RapidEscalation = 0
+	want := []string{"esc1", "esc2", "factor1", "factor2", "fall1", "fall2", "flat1", "flat2"}
+	if got := flaggedIDs(Detect(transactions, config), "Rapid"); !slices.Equal(got, want) {
+		t.Errorf("without escalation flagged %v, want %v", got, want)
+	}
+}
+
+func TestMultipleCards(t *testing.T) {
+	card := func(id, account string, offset time.Duration, cardID string) Transaction {
+		tx := testTx(id, account, offset, 10, "Shop")
+		tx.CardID = cardID
+		return tx
+	}
+	transactions := []Transaction{
+		// Four cards in 15 minutes
+		card("cycle1", "A", 0, "c1"),
+		card("cycle2", "A", 5*time.Minute, "c2"),
+		card("cycle3", "A", 10*time.Minute, "c3"),
+		card("cycle4", "A", 15*time.Minute, "c4"),
+		// The same two cards over and over
+		card("same1", "B", 0, "c1"),
+		card("same2", "B", time.Minute, "c2"),
+		card("same3", "B", 2*time.Minute, "c1"),
+		card("same4", "B", 3*time.Minute, "c2"),
+		// Four cards, one every 2 hours
+		card("slow1", "C", 0, "c1"),
+		card("slow2", "C", 2*time.Hour, "c2"),
+		card("slow3", "C", 4*time.Hour, "c3"),
+		card("slow4", "C", 6*time.Hour, "c4"),
+		// Three cards, and records without one
+		card("three1", "D", 0, "c1"),
+		card("none1", "D", time.Minute, ""),
+		card("three2", "D", 2*time.Minute, "c2"),
+		card("none2", "D", 3*time.Minute, ""),
+		card("three3", "D", 4*time.Minute, "c3"),
+	}
+	config := Config{MaxCards: 3, CardWindow: time.Hour, Rules: onlyRules(t, "multi-card")}
+
+	results := Detect(transactions, config)
+	if got, want := flaggedIDs(results, ""), []string{"cycle1", "cycle2", "cycle3", "cycle4"}; !slices.Equal(got, want) {
+		t.Fatalf("flagged %v, want %v", got, want)
+	}
+	for _, result := range results {
+		if want := "Multiple instruments: 4 cards in 1h"; result.Reason != want {
+			t.Errorf("%s: reason = %q, want %q", result.Transaction.ID, result.Reason, want)
+		}
+	}
+
+	config.MaxCards = 2
+	want := []string{"cycle1", "cycle2", "cycle3", "cycle4", "three1", "three2", "three3"}
+	if got := flaggedIDs(Detect(transactions, config), ""); !slices.Equal(got, want) {
+		t.Errorf("with at most 2 cards flagged %v, want %v", got, want)
+	}
+}
+
+func TestFlagSimultaneous(t *testing.T) {
+	transactions := []Transaction{
+		testTx("same1", "A", 0, 10, "Shop"),
+		testTx("same2", "A", 0, 25, "Grocer"),
+		// The same instant, but different accounts
+		testTx("other1", "B", 0, 10, "Shop"),
+		testTx("other2", "C", 0, 10, "Shop"),
+	}
+	config := Config{TimeWindow: 5 * time.Minute, Rules: onlyRules(t, "rapid-succession")}
+
+	// Transactions at the same instant are not rapid, so go unflagged by default
+	if got := flaggedIDs(Detect(transactions, config), ""); len(got) != 0 {
+		t.Errorf("without FlagSimultaneous flagged %v, want none", got)
+	}
+
+	config.FlagSimultaneous = true
+	results := Detect(transactions, config)
+	if got, want := flaggedIDs(results, ""), []string{"same1", "same2"}; !slices.Equal(got, want) {
+		t.Fatalf("flagged %v, want %v", got, want)
+	}
+	for _, result := range results {
+		if want := "Simultaneous transactions: 2 at 10:00:00"; result.Reason != want {
+			t.Errorf("%s: reason = %q, want %q", result.Transaction.ID, result.Reason, want)
+		}
+	}
+	if got := flaggedIDs(streamResults(transactions, config), ""); !slices.Equal(got, []string{"same1", "same2"}) {
+		t.Errorf("stream flagged %v, want same1 and same2", got)
+	}
+}
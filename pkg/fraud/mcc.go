@@ -0,0 +1,39 @@
+package fraud
+
+import "fmt"
+
+// mccLabels describes well-known merchant category codes
+var mccLabels = map[string]string{
+	"4829": "wire transfer",
+	"5933": "pawn shop",
+	"5944": "jewelry",
+	"5967": "direct marketing",
+	"5993": "tobacco",
+	"6010": "cash disbursement",
+	"6011": "ATM",
+	"6012": "financial institution",
+	"6051": "quasi-cash",
+	"6540": "stored value load",
+	"7273": "dating service",
+	"7995": "gambling",
+}
+
+// MCCLabel returns a short description of a merchant category code, or ""
+// when the code is not known
+func MCCLabel(mcc string) string {
+	return mccLabels[mcc]
+}
+
+// checkHighRiskMCC flags transactions whose merchant category code is in
+// config.HighRiskMCCs
+func checkHighRiskMCC(tx Transaction, config Config) []FraudResult {
+	if tx.MCC == "" || !config.HighRiskMCCs[tx.MCC] {
+		return nil
+	}
+
+	reason := fmt.Sprintf("High-risk MCC: %s", tx.MCC)
+	if label := MCCLabel(tx.MCC); label != "" {
+		reason += fmt.Sprintf(" (%s)", label)
+	}
+	return []FraudResult{{Transaction: tx, Reason: reason}}
+}
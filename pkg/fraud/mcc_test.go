@@ -0,0 +1,28 @@
+package fraud
+
+import "testing"
+
+func TestHighRiskMCC(t *testing.T) {
+	config := Config{HighRiskMCCs: map[string]bool{"6011": true, "7995": true, "9999": true}}
+	tests := []struct {
+		mcc  string
+		want string
+	}{
+		{"7995", "High-risk MCC: 7995 (gambling)"},
+		{"6011", "High-risk MCC: 6011 (ATM)"},
+		{"9999", "High-risk MCC: 9999"},
+		{"5411", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		tx := testTx("1", "A", 0, 10, "Shop")
+		tx.MCC = tt.mcc
+		var got string
+		if results := checkHighRiskMCC(tx, config); len(results) > 0 {
+			got = results[0].Reason
+		}
+		if got != tt.want {
+			t.Errorf("MCC %q: reason = %q, want %q", tt.mcc, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package fraud
+
+import (
+	"regexp"
+	"strings"
+)
+
+// MerchantAlias maps merchant names matching Pattern to one canonical name,
+// e.g. "Amazon.com" and "AMZN Mktp" to "amazon"
+type MerchantAlias struct {
+	Pattern *regexp.Regexp
+	Name    string
+}
+
+// NormalizeMerchant returns the name merchant-based rules compare a merchant
+// by: lowercased with surrounding and repeated spaces removed, then replaced
+// by the canonical name of the first alias in c.MerchantAliases that matches
+// it. Transactions keep their original merchant name for display.
+func (c Config) NormalizeMerchant(merchant string) string {
+	name := strings.Join(strings.Fields(merchant), " ")
+	for _, alias := range c.MerchantAliases {
+		if alias.Pattern.MatchString(name) {
+			return strings.ToLower(alias.Name)
+		}
+	}
+	return strings.ToLower(name)
+}
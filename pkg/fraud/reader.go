@@ -0,0 +1,600 @@
+package fraud
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ReadOptions controls how transactions are parsed. The zero value parses
+// with the defaults used by ReadCSV and the other package-level readers.
+type ReadOptions struct {
+	TimeFormat  string          // Go layout for CSV timestamps; empty auto-detects common formats
+	SkipInvalid bool            // Skip malformed rows instead of failing the whole read
+	OnInvalid   func(err error) // Called for each skipped row when SkipInvalid is set
+
+	// CSV layout. Columns maps field names such as "amount" or "latitude" to
+	// a 0-based column index or a header name, overriding the default order
+	// of requiredColumns and the header names of optional columns.
+	Delimiter        rune              // Field separator; 0 means a comma
+	NoHeader         bool              // The first row is a transaction, not a header
+	Columns          map[string]string // Column of each remapped field
+	FieldsFromHeader bool              // Find required fields by header name or alias, e.g. "amt", instead of by position
+
+	// JSONPath is the dot-separated path of object keys leading to the
+	// transactions array in a JSON file whose top level is a wrapper object,
+	// e.g. "data.transactions"; empty means the file is the array
+	JSONPath string
+
+	// Number format of CSV amounts, e.g. ',' and '.' for "1.234,56"
+	DecimalSeparator   rune // Decimal mark; 0 means a period
+	ThousandsSeparator rune // Digit group separator, removed before parsing; 0 means none
+}
+
+// Validate reports whether the options are consistent
+func (o ReadOptions) Validate() error {
+	decimal := o.decimalSeparator()
+	for _, sep := range []rune{decimal, o.ThousandsSeparator} {
+		if sep != 0 && (unicode.IsDigit(sep) || strings.ContainsRune("+-eE", sep)) {
+			return fmt.Errorf("%q cannot be used as a number separator", sep)
+		}
+	}
+	if o.ThousandsSeparator == decimal {
+		return fmt.Errorf("thousands separator %q is also the decimal separator", decimal)
+	}
+	return nil
+}
+
+// decimalSeparator returns the decimal mark of amounts
+func (o ReadOptions) decimalSeparator() rune {
+	if o.DecimalSeparator == 0 {
+		return '.'
+	}
+	return o.DecimalSeparator
+}
+
+// parseAmount parses an amount written with the configured separators. With
+// a decimal mark other than a period, a period is an error rather than being
+// read as a decimal point, so "1.234" is not taken for 1.234 when the
+// thousands separator is unset.
+func (o ReadOptions) parseAmount(value string) (float64, error) {
+	if o.ThousandsSeparator != 0 {
+		value = strings.ReplaceAll(value, string(o.ThousandsSeparator), "")
+	}
+	if decimal := o.decimalSeparator(); decimal != '.' {
+		if strings.Contains(value, ".") {
+			return 0, fmt.Errorf("unexpected %q in %q with decimal separator %q", '.', value, decimal)
+		}
+		value = strings.Replace(value, string(decimal), ".", 1)
+	}
+	return strconv.ParseFloat(value, 64)
+}
+
+// requiredColumns are the fields every transaction needs, in their default
+// CSV column order
+var requiredColumns = []string{"id", "amount", "timestamp", "account_id", "merchant"}
+
+// optionalColumns are the fields a transaction may have, found by header name
+var optionalColumns = []string{"latitude", "longitude", "currency", "category", "mcc", "device_id", "card_id", "country"}
+
+// fieldAliases are the header names, after normalizing, recognized for each
+// field with FieldsFromHeader, in order of preference
+var fieldAliases = map[string][]string{
+	"id":         {"id", "txn_id", "transaction_id", "tx_id", "txid", "trans_id", "reference"},
+	"amount":     {"amount", "amt", "value", "transaction_amount"},
+	"timestamp":  {"timestamp", "datetime", "date_time", "ts", "time", "date", "created_at", "transaction_date"},
+	"account_id": {"account_id", "acct", "acct_id", "account", "account_number", "customer_id"},
+	"merchant":   {"merchant", "merchant_name", "payee", "vendor", "description"},
+	"latitude":   {"latitude", "lat"},
+	"longitude":  {"longitude", "lon", "lng", "long"},
+	"currency":   {"currency", "ccy", "currency_code"},
+	"category":   {"category", "transaction_type", "type"},
+	"mcc":        {"mcc", "merchant_category_code"},
+	"device_id":  {"device_id", "device"},
+	"card_id":    {"card_id", "card", "instrument", "instrument_id", "card_number"},
+	"country":    {"country", "country_code", "merchant_country"},
+}
+
+// normalizeHeader lowercases a header name and joins its words with
+// underscores, so "Account ID" and "account-id" read as "account_id"
+func normalizeHeader(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_' || r == '.'
+	}), "_")
+}
+
+// headerFields locates the fields named in a header by their aliases
+func headerFields(header []string) map[string]int {
+	index := make(map[string]int, len(header))
+	for i, name := range header {
+		if name := normalizeHeader(name); name != "" {
+			if _, ok := index[name]; !ok {
+				index[name] = i
+			}
+		}
+	}
+
+	fields := make(map[string]int)
+	for field, aliases := range fieldAliases {
+		for _, alias := range aliases {
+			if i, ok := index[alias]; ok {
+				fields[field] = i
+				break
+			}
+		}
+	}
+	return fields
+}
+
+// timeFormats are the layouts tried, in order, when no time format is set
+var timeFormats = []string{time.RFC3339, "2006-01-02 15:04:05"}
+
+// ReadCSV reads transactions from a CSV file
+func ReadCSV(file io.Reader) ([]Transaction, error) {
+	return ReadOptions{}.ReadCSV(file)
+}
+
+// StreamCSV reads transactions from a CSV file one row at a time, calling fn
+// for each transaction. It stops at the first error returned by fn.
+func StreamCSV(file io.Reader, fn func(Transaction) error) error {
+	return ReadOptions{}.StreamCSV(file, fn)
+}
+
+// ReadCSV reads transactions from a CSV file
+func (o ReadOptions) ReadCSV(file io.Reader) ([]Transaction, error) {
+	var transactions []Transaction
+	err := o.StreamCSV(file, func(tx Transaction) error {
+		transactions = append(transactions, tx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// StreamCSV reads transactions from a CSV file one row at a time, calling fn
+// for each transaction. It stops at the first error returned by fn. A UTF-8
+// byte order mark, spaces after delimiters, and stray quotes inside fields
+// are tolerated.
+func (o ReadOptions) StreamCSV(file io.Reader, fn func(Transaction) error) error {
+	if err := o.Validate(); err != nil {
+		return err
+	}
+
+	reader := csv.NewReader(skipBOM(file))
+	reader.ReuseRecord = true
+	reader.TrimLeadingSpace = true
+	reader.LazyQuotes = true
+	if o.Delimiter != 0 {
+		reader.Comma = o.Delimiter
+	}
+
+	var columns csvColumns
+	if o.NoHeader {
+		var err error
+		if columns, err = o.csvColumns(nil); err != nil {
+			return err
+		}
+	}
+	for i := 0; ; i++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		// Only malformed rows can be skipped; other errors, such as a
+		// truncated gzip stream, would repeat on every read
+		var parseErr *csv.ParseError
+		if err != nil && !errors.As(err, &parseErr) {
+			return err
+		}
+
+		// Skip header, remembering where optional columns are
+		if i == 0 && !o.NoHeader && err == nil {
+			if columns, err = o.csvColumns(record); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var tx Transaction
+		if err == nil {
+			tx, err = o.parseRecord(record, i+1, columns)
+		}
+		if err != nil {
+			if err := o.invalid(err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+}
+
+// invalid handles a record that cannot be used: with SkipInvalid it is passed
+// to OnInvalid and nil is returned so the read goes on, otherwise err is
+// returned to stop it
+func (o ReadOptions) invalid(err error) error {
+	if !o.SkipInvalid {
+		return err
+	}
+	if o.OnInvalid != nil {
+		o.OnInvalid(err)
+	}
+	return nil
+}
+
+// skipBOM returns a reader of r without its leading UTF-8 byte order mark,
+// which spreadsheet exports often add
+func skipBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	if prefix, err := br.Peek(3); err == nil && string(prefix) == "\xef\xbb\xbf" {
+		br.Discard(3)
+	}
+	return br
+}
+
+// csvColumns maps lowercased header names to column indexes, used to find
+// optional columns such as latitude and longitude
+type csvColumns map[string]int
+
+// newCSVColumns indexes a CSV header row
+func newCSVColumns(header []string) csvColumns {
+	columns := make(csvColumns)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	return columns
+}
+
+// csvColumns locates each field of a CSV file with the given header, which is
+// nil for files without one. Required fields default to their position in
+// requiredColumns and optional ones to the header column of the same name,
+// unless o.Columns maps them elsewhere. With o.FieldsFromHeader and a
+// header, every field is found by its aliases instead, and required fields
+// missing from the header are an error.
+func (o ReadOptions) csvColumns(header []string) (csvColumns, error) {
+	columns := newCSVColumns(header)
+	fromHeader := o.FieldsFromHeader && header != nil
+	if fromHeader {
+		for field, i := range headerFields(header) {
+			columns[field] = i
+		}
+	} else {
+		for i, name := range requiredColumns {
+			columns[name] = i
+		}
+	}
+
+	for field, column := range o.Columns {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !slices.Contains(requiredColumns, field) && !slices.Contains(optionalColumns, field) {
+			return nil, fmt.Errorf("unknown field %q in column mapping (fields: %s, %s)", field, strings.Join(requiredColumns, ", "), strings.Join(optionalColumns, ", "))
+		}
+
+		if i, err := strconv.Atoi(column); err == nil {
+			if i < 0 {
+				return nil, fmt.Errorf("negative column index %d for %s", i, field)
+			}
+			columns[field] = i
+			continue
+		}
+
+		i := slices.IndexFunc(header, func(name string) bool {
+			return strings.EqualFold(strings.TrimSpace(name), strings.TrimSpace(column))
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("no %q column in header for %s", column, field)
+		}
+		columns[field] = i
+	}
+
+	if fromHeader {
+		var missing []string
+		for _, name := range requiredColumns {
+			if _, ok := columns[name]; !ok {
+				missing = append(missing, name)
+			}
+		}
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("no column for %s in header %q", strings.Join(missing, ", "), header)
+		}
+	}
+
+	return columns, nil
+}
+
+// value returns the trimmed value of the named column, or "" when the file
+// has no such column
+func (c csvColumns) value(record []string, name string) string {
+	i, ok := c[name]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// parseOptionalFloat parses value, returning nil for an empty value
+func parseOptionalFloat(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return nil, fmt.Errorf("%q is not a finite number", value)
+	}
+	return &f, nil
+}
+
+// parseRecord converts a CSV record at the given line into a transaction
+func (o ReadOptions) parseRecord(record []string, line int, columns csvColumns) (Transaction, error) {
+	// Required fields default to their position in requiredColumns
+	fields := make([]string, len(requiredColumns))
+	for i, name := range requiredColumns {
+		column, ok := columns[name]
+		if !ok {
+			column = i
+		}
+		if column >= len(record) {
+			return Transaction{}, fmt.Errorf("invalid CSV format at line %d", line)
+		}
+		fields[i] = strings.TrimSpace(record[column])
+	}
+
+	amount, err := o.parseAmount(fields[1])
+	if err != nil {
+		return Transaction{}, fmt.Errorf("invalid amount at line %d: %v", line, err)
+	}
+	if math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return Transaction{}, fmt.Errorf("invalid amount at line %d: %q is not a finite number", line, fields[1])
+	}
+
+	timestamp, err := o.parseTimestamp(fields[2])
+	if err != nil {
+		return Transaction{}, fmt.Errorf("invalid timestamp at line %d: %v", line, err)
+	}
+
+	tx := Transaction{
+		ID:        fields[0],
+		Amount:    amount,
+		Timestamp: timestamp,
+		AccountID: fields[3],
+		Merchant:  fields[4],
+	}
+
+	if tx.Latitude, err = parseOptionalFloat(columns.value(record, "latitude")); err != nil {
+		return Transaction{}, fmt.Errorf("invalid latitude at line %d: %v", line, err)
+	}
+	if tx.Longitude, err = parseOptionalFloat(columns.value(record, "longitude")); err != nil {
+		return Transaction{}, fmt.Errorf("invalid longitude at line %d: %v", line, err)
+	}
+	tx.Currency = strings.ToUpper(columns.value(record, "currency"))
+	tx.Category = columns.value(record, "category")
+	tx.MCC = columns.value(record, "mcc")
+	tx.DeviceID = columns.value(record, "device_id")
+	tx.CardID = columns.value(record, "card_id")
+	tx.Country = strings.ToUpper(columns.value(record, "country"))
+
+	return tx, nil
+}
+
+// parseTimestamp parses a CSV timestamp with the configured layout, or tries
+// the common layouts and Unix epoch seconds when none is set
+func (o ReadOptions) parseTimestamp(value string) (time.Time, error) {
+	if o.TimeFormat != "" {
+		return time.Parse(o.TimeFormat, value)
+	}
+
+	for _, layout := range timeFormats {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized format %q (tried %s, and Unix epoch seconds)", value, strings.Join(timeFormats, ", "))
+}
+
+// ReadJSON reads transactions from a JSON file
+func ReadJSON(file io.Reader) ([]Transaction, error) {
+	return ReadOptions{}.ReadJSON(file)
+}
+
+// StreamJSON reads transactions from a JSON array one element at a time,
+// calling fn for each transaction. It stops at the first error returned by fn.
+func StreamJSON(file io.Reader, fn func(Transaction) error) error {
+	return ReadOptions{}.StreamJSON(file, fn)
+}
+
+// ReadJSONL reads transactions from a JSON Lines (NDJSON) file
+func ReadJSONL(file io.Reader) ([]Transaction, error) {
+	return ReadOptions{}.ReadJSONL(file)
+}
+
+// StreamJSONL reads transactions from a JSON Lines (NDJSON) file, one object
+// per line, calling fn for each transaction. Blank lines are skipped. It stops
+// at the first error returned by fn.
+func StreamJSONL(file io.Reader, fn func(Transaction) error) error {
+	return ReadOptions{}.StreamJSONL(file, fn)
+}
+
+// ReadJSON reads transactions from a JSON file
+func (o ReadOptions) ReadJSON(file io.Reader) ([]Transaction, error) {
+	var transactions []Transaction
+	err := o.StreamJSON(file, func(tx Transaction) error {
+		transactions = append(transactions, tx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// StreamJSON reads transactions from a JSON array one element at a time,
+// calling fn for each transaction. It stops at the first error returned by fn.
+// Elements missing a required field are invalid. With o.JSONPath, the array
+// is found inside wrapper objects first.
+func (o ReadOptions) StreamJSON(file io.Reader, fn func(Transaction) error) error {
+	decoder := json.NewDecoder(file)
+
+	if o.JSONPath != "" {
+		if err := seekJSONPath(decoder, o.JSONPath); err != nil {
+			return err
+		}
+	}
+
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected JSON array of transactions")
+	}
+
+	for i := 0; decoder.More(); i++ {
+		var tx Transaction
+		if err := decoder.Decode(&tx); err != nil {
+			return err
+		}
+		if missing := missingFields(tx); len(missing) > 0 {
+			if err := o.invalid(fmt.Errorf("transaction at index %d is missing %s", i, strings.Join(missing, ", "))); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+
+	_, err = decoder.Token()
+	return err
+}
+
+// seekJSONPath advances decoder through nested objects, following the keys of
+// a dot-separated path, to the start of the value at its end. Values of other
+// keys are skipped without being decoded into transactions.
+func seekJSONPath(decoder *json.Decoder, path string) error {
+	keys := strings.Split(path, ".")
+	for depth, key := range keys {
+		at := strings.Join(keys[:depth], ".")
+		if at == "" {
+			at = "top level"
+		}
+
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '{' {
+			return fmt.Errorf("expected JSON object at %s in JSON path %s", at, path)
+		}
+
+		for {
+			if !decoder.More() {
+				return fmt.Errorf("no %q key at %s in JSON path %s", key, at, path)
+			}
+			token, err := decoder.Token()
+			if err != nil {
+				return err
+			}
+			if token == key {
+				break
+			}
+			var skipped json.RawMessage
+			if err := decoder.Decode(&skipped); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReadJSONL reads transactions from a JSON Lines (NDJSON) file
+func (o ReadOptions) ReadJSONL(file io.Reader) ([]Transaction, error) {
+	var transactions []Transaction
+	err := o.StreamJSONL(file, func(tx Transaction) error {
+		transactions = append(transactions, tx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// StreamJSONL reads transactions from a JSON Lines (NDJSON) file, one object
+// per line, calling fn for each transaction. Blank lines are skipped. It stops
+// at the first error returned by fn. Objects missing a required field are
+// invalid.
+func (o ReadOptions) StreamJSONL(file io.Reader, fn func(Transaction) error) error {
+	decoder := json.NewDecoder(file)
+
+	for i := 1; ; i++ {
+		var tx Transaction
+		err := decoder.Decode(&tx)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid JSON at record %d: %v", i, err)
+		}
+		if missing := missingFields(tx); len(missing) > 0 {
+			if err := o.invalid(fmt.Errorf("record %d is missing %s", i, strings.Join(missing, ", "))); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+}
+
+// UnmarshalTransaction parses a single JSON transaction object, such as one
+// line of a JSON Lines feed or a message, checking its required fields as
+// the JSON readers do
+func UnmarshalTransaction(data []byte) (Transaction, error) {
+	var tx Transaction
+	if err := json.Unmarshal(data, &tx); err != nil {
+		return Transaction{}, err
+	}
+	if missing := missingFields(tx); len(missing) > 0 {
+		return Transaction{}, fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+	return tx, nil
+}
+
+// missingFields returns the JSON names of the required fields that tx lacks.
+// An absent amount cannot be told from zero, so amounts are not checked.
+func missingFields(tx Transaction) []string {
+	var missing []string
+	if tx.ID == "" {
+		missing = append(missing, "id")
+	}
+	if tx.Timestamp.IsZero() {
+		missing = append(missing, "timestamp")
+	}
+	if tx.AccountID == "" {
+		missing = append(missing, "account_id")
+	}
+	return missing
+}
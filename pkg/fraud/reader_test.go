@@ -0,0 +1,428 @@
+package fraud
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadCSVSkipInvalid(t *testing.T) {
+	input := `id,amount,timestamp,account_id,merchant
+1,10.00,2024-01-01T10:00:00Z,A,Shop
+2,abc,2024-01-01T10:01:00Z,A,Shop
+3,30.00,not a time,A,Shop
+5,50.00,2024-01-01T10:04:00Z,B,Shop
+4,"40.00,2024-01-01T10:03:00Z,A,Shop
+`
+
+	var skipped []error
+	opts := ReadOptions{SkipInvalid: true, OnInvalid: func(err error) { skipped = append(skipped, err) }}
+	transactions, err := opts.ReadCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadCSV: %v", err)
+	}
+
+	var ids []string
+	for _, tx := range transactions {
+		ids = append(ids, tx.ID)
+	}
+	if got := strings.Join(ids, ","); got != "1,5" {
+		t.Errorf("valid IDs = %s, want 1,5", got)
+	}
+	if len(skipped) != 3 {
+		t.Errorf("skipped %d rows, want 3: %v", len(skipped), skipped)
+	}
+	if len(skipped) > 0 && !strings.Contains(skipped[0].Error(), "line 3") {
+		t.Errorf("first skipped row error %q does not name line 3", skipped[0])
+	}
+}
+
+func TestReadCSVFailFast(t *testing.T) {
+	input := `id,amount,timestamp,account_id,merchant
+1,10.00,2024-01-01T10:00:00Z,A,Shop
+2,abc,2024-01-01T10:01:00Z,A,Shop
+`
+	if _, err := ReadCSV(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("ReadCSV error = %v, want invalid amount at line 3", err)
+	}
+}
+
+func TestReadCSVSkipInvalidReturnsReadErrors(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	io.WriteString(gz, "id,amount,timestamp,account_id,merchant\n")
+	for i := 0; i < 100; i++ {
+		io.WriteString(gz, "1,10.00,2024-01-01T10:00:00Z,A,Shop\n")
+	}
+	gz.Close()
+	truncated := buf.Bytes()[:buf.Len()/2]
+
+	r, err := gzip.NewReader(bytes.NewReader(truncated))
+	if err != nil {
+		t.Fatalf("gzip.NewReader: %v", err)
+	}
+	opts := ReadOptions{SkipInvalid: true}
+	if _, err := opts.ReadCSV(r); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadCSV error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+// messyCSV are real-world quirks the CSV reader tolerates, used as tests and
+// as the seed corpus of FuzzReadCSV
+var messyCSV = []struct {
+	name     string
+	input    string
+	merchant string
+	amount   float64
+}{
+	{"bom", "\ufeffid,amount,timestamp,account_id,merchant\n1,10.50,2024-01-01T10:00:00Z,A,Shop\n", "Shop", 10.50},
+	{"quoted comma", "id,amount,timestamp,account_id,merchant\n1,10.50,2024-01-01T10:00:00Z,A,\"Smith, Jones & Co\"\n", "Smith, Jones & Co", 10.50},
+	{"padded amount", "id,amount,timestamp,account_id,merchant\n1,  10.50  ,2024-01-01T10:00:00Z,A,Shop\n", "Shop", 10.50},
+	{"stray quote", "id,amount,timestamp,account_id,merchant\n1,10.50,2024-01-01T10:00:00Z,A,Joe\"s Diner\n", "Joe\"s Diner", 10.50},
+	{"crlf", "id,amount,timestamp,account_id,merchant\r\n1,10.50,2024-01-01T10:00:00Z,A,Shop\r\n", "Shop", 10.50},
+}
+
+func TestReadCSVMessyInput(t *testing.T) {
+	for _, tt := range messyCSV {
+		t.Run(tt.name, func(t *testing.T) {
+			transactions, err := ReadCSV(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatalf("ReadCSV: %v", err)
+			}
+			if len(transactions) != 1 {
+				t.Fatalf("got %d transactions, want 1", len(transactions))
+			}
+			tx := transactions[0]
+			if tx.ID != "1" || tx.Merchant != tt.merchant || tx.Amount != tt.amount {
+				t.Errorf("got ID %q, merchant %q, amount %v; want 1, %q, %v", tx.ID, tx.Merchant, tx.Amount, tt.merchant, tt.amount)
+			}
+		})
+	}
+}
+
+func FuzzReadCSV(f *testing.F) {
+	for _, tt := range messyCSV {
+		f.Add([]byte(tt.input))
+	}
+	f.Add([]byte("id,amount,timestamp,account_id,merchant\n1,NaN,2024-01-01T10:00:00Z,A,Shop\n"))
+	f.Add([]byte("id,amount,timestamp,account_id,merchant\n1,10\n"))
+	f.Add([]byte("id,amount\n\"unterminated"))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		transactions, err := ReadCSV(bytes.NewReader(data))
+		if err != nil {
+			if err.Error() == "" {
+				t.Error("empty error message")
+			}
+			transactions = nil
+		}
+		for _, tx := range transactions {
+			if math.IsNaN(tx.Amount) || math.IsInf(tx.Amount, 0) {
+				t.Errorf("transaction %q has non-finite amount %v", tx.ID, tx.Amount)
+			}
+		}
+
+		// Skipping invalid rows keeps every row a strict read accepts
+		var skipped int
+		opts := ReadOptions{SkipInvalid: true, OnInvalid: func(error) { skipped++ }}
+		lenient, lenientErr := opts.ReadCSV(bytes.NewReader(data))
+		if err == nil {
+			if lenientErr != nil || len(lenient) != len(transactions) || skipped != 0 {
+				t.Errorf("strict read returned %d transactions, lenient read %d with %d skipped and error %v",
+					len(transactions), len(lenient), skipped, lenientErr)
+			}
+		}
+	})
+}
+
+func TestUnmarshalTransaction(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`{"id": "1", "amount": 10, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A", "merchant": "Shop"}`, ""},
+		{`{"id": "1", "amount": 10, "timestamp": "2024-01-01T10:00:00Z", "merchant": "Shop"}`, "missing account_id"},
+		{`{"amount": 10, "merchant": "Shop"}`, "missing id, timestamp, account_id"},
+		{`{"id": "1",`, "unexpected end of JSON input"},
+	}
+	for _, tt := range tests {
+		tx, err := UnmarshalTransaction([]byte(tt.input))
+		if tt.err == "" {
+			if err != nil || tx.ID != "1" || tx.AccountID != "A" {
+				t.Errorf("UnmarshalTransaction(%s) = %+v, %v", tt.input, tx, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("UnmarshalTransaction(%s) error = %v, want %q", tt.input, err, tt.err)
+		}
+	}
+}
+
+func TestReadJSONL(t *testing.T) {
+	input := `{"id": "1", "amount": 10, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A", "merchant": "Shop"}
+
+{"id": "2", "amount": 20.5, "timestamp": "2024-01-01T10:01:00Z", "account_id": "A", "merchant": "Shop"}
+{"id": "3", "amount": 30, "timestamp": "2024-01-01T10:02:00Z", "account_id": "B", "merchant": "Cafe"}
+`
+	transactions, err := ReadJSONL(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadJSONL: %v", err)
+	}
+	if len(transactions) != 3 {
+		t.Fatalf("got %d transactions, want 3", len(transactions))
+	}
+	if tx := transactions[1]; tx.ID != "2" || tx.Amount != 20.5 || tx.AccountID != "A" {
+		t.Errorf("second transaction = %+v", tx)
+	}
+	if tx := transactions[2]; tx.Merchant != "Cafe" || !tx.Timestamp.Equal(time.Date(2024, 1, 1, 10, 2, 0, 0, time.UTC)) {
+		t.Errorf("third transaction = %+v", tx)
+	}
+}
+
+func TestReadJSONLInvalidRecord(t *testing.T) {
+	input := `{"id": "1", "amount": 10, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A", "merchant": "Shop"}
+{"id": "2", "amount": "ten"}
+`
+	if _, err := ReadJSONL(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), "record 2") {
+		t.Errorf("ReadJSONL error = %v, want one naming record 2", err)
+	}
+}
+
+func TestReadJSONMissingFields(t *testing.T) {
+	input := `[
+		{"id": "1", "amount": 10, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A", "merchant": "Shop"},
+		{"id": "2", "amount": 10, "merchant": "Shop"}
+	]`
+	if _, err := ReadJSON(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), "index 1 is missing timestamp, account_id") {
+		t.Errorf("ReadJSON error = %v, want one naming the missing fields", err)
+	}
+
+	var invalid []error
+	opts := ReadOptions{SkipInvalid: true, OnInvalid: func(err error) { invalid = append(invalid, err) }}
+	transactions, err := opts.ReadJSON(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadJSON with SkipInvalid: %v", err)
+	}
+	if len(transactions) != 1 || transactions[0].ID != "1" || len(invalid) != 1 {
+		t.Errorf("got %v, skipped %v; want transaction 1 and one skipped", transactions, invalid)
+	}
+}
+
+func TestParseTimestamp(t *testing.T) {
+	want := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		options ReadOptions
+		value   string
+	}{
+		{"rfc3339", ReadOptions{}, "2024-01-15T10:30:00Z"},
+		{"datetime", ReadOptions{}, "2024-01-15 10:30:00"},
+		{"epoch", ReadOptions{}, "1705314600"},
+		{"layout", ReadOptions{TimeFormat: "02/01/2006 15:04"}, "15/01/2024 10:30"},
+	}
+	for _, tt := range tests {
+		got, err := tt.options.parseTimestamp(tt.value)
+		if err != nil {
+			t.Errorf("%s: parseTimestamp(%q): %v", tt.name, tt.value, err)
+			continue
+		}
+		if !got.Equal(want) {
+			t.Errorf("%s: parseTimestamp(%q) = %v, want %v", tt.name, tt.value, got, want)
+		}
+	}
+}
+
+func TestParseTimestampUnrecognized(t *testing.T) {
+	_, err := ReadOptions{}.parseTimestamp("next tuesday")
+	if err == nil {
+		t.Fatal("parseTimestamp accepted an unrecognized value")
+	}
+	for _, layout := range append(timeFormats[:len(timeFormats):len(timeFormats)], "Unix epoch seconds") {
+		if !strings.Contains(err.Error(), layout) {
+			t.Errorf("error %q does not list %q", err, layout)
+		}
+	}
+
+	csv := "id,amount,timestamp,account_id,merchant\n1,10,next tuesday,A,Shop\n"
+	if _, err := ReadCSV(strings.NewReader(csv)); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ReadCSV error = %v, want one naming line 2", err)
+	}
+}
+
+func TestReadCSVLayouts(t *testing.T) {
+	want := []Transaction{
+		{ID: "1", Amount: 10, Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), AccountID: "A", Merchant: "Shop"},
+		{ID: "2", Amount: 2500, Timestamp: time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC), AccountID: "B", Merchant: "Smith, Jones"},
+	}
+	tests := []struct {
+		name    string
+		options ReadOptions
+		input   string
+	}{
+		{
+			"tsv",
+			ReadOptions{Delimiter: '\t'},
+			"id\tamount\ttimestamp\taccount_id\tmerchant\n1\t10\t2024-01-01T10:00:00Z\tA\tShop\n2\t2500\t2024-01-01T10:01:00Z\tB\tSmith, Jones\n",
+		},
+		{
+			"reordered by index",
+			ReadOptions{Columns: map[string]string{"merchant": "0", "account_id": "1", "amount": "2", "timestamp": "3", "id": "4"}},
+			"merchant,account,amount,time,ref\nShop,A,10,2024-01-01T10:00:00Z,1\n\"Smith, Jones\",B,2500,2024-01-01T10:01:00Z,2\n",
+		},
+		{
+			"reordered by header",
+			ReadOptions{Columns: map[string]string{"amount": "Amount USD", "id": "Ref", "timestamp": "When", "account_id": "Account", "merchant": "Payee"}},
+			"When,Payee,Ref,Account,Amount USD\n2024-01-01T10:00:00Z,Shop,1,A,10\n2024-01-01T10:01:00Z,\"Smith, Jones\",2,B,2500\n",
+		},
+		{
+			"no header",
+			ReadOptions{NoHeader: true, Delimiter: ';'},
+			"1;10;2024-01-01T10:00:00Z;A;Shop\n2;2500;2024-01-01T10:01:00Z;B;Smith, Jones\n",
+		},
+	}
+	for _, tt := range tests {
+		got, err := tt.options.ReadCSV(strings.NewReader(tt.input))
+		if err != nil {
+			t.Errorf("%s: ReadCSV: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: read %+v, want %+v", tt.name, got, want)
+		}
+	}
+}
+
+func TestReadCSVNumberFormat(t *testing.T) {
+	opts := ReadOptions{Delimiter: ';', DecimalSeparator: ',', ThousandsSeparator: '.'}
+	input := "id;amount;timestamp;account_id;merchant\n1;1.234,56;2024-01-01T10:00:00Z;A;Shop\n2;-12,5;2024-01-01T10:01:00Z;A;Shop\n"
+	transactions, err := opts.ReadCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadCSV: %v", err)
+	}
+	if len(transactions) != 2 || transactions[0].Amount != 1234.56 || transactions[1].Amount != -12.5 {
+		t.Errorf("read %+v, want amounts 1234.56 and -12.5", transactions)
+	}
+
+	tests := []struct {
+		opts  ReadOptions
+		value string
+		want  float64
+	}{
+		{ReadOptions{}, "1234.56", 1234.56},
+		{ReadOptions{ThousandsSeparator: ','}, "1,234.56", 1234.56},
+		{ReadOptions{DecimalSeparator: ',', ThousandsSeparator: ' '}, "1 234,56", 1234.56},
+		{ReadOptions{DecimalSeparator: ','}, "1234,56", 1234.56},
+	}
+	for _, tt := range tests {
+		if got, err := tt.opts.parseAmount(tt.value); err != nil || got != tt.want {
+			t.Errorf("parseAmount(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+	// A period is not silently read as the decimal point
+	if _, err := (ReadOptions{DecimalSeparator: ','}).parseAmount("1.234"); err == nil {
+		t.Error(`parseAmount("1.234") with decimal separator ',' accepted the period`)
+	}
+}
+
+func TestReadOptionsValidate(t *testing.T) {
+	for _, opts := range []ReadOptions{{}, {DecimalSeparator: ',', ThousandsSeparator: '.'}, {ThousandsSeparator: ','}} {
+		if err := opts.Validate(); err != nil {
+			t.Errorf("Validate(%+v) = %v, want nil", opts, err)
+		}
+	}
+	for _, opts := range []ReadOptions{
+		{ThousandsSeparator: '.'},
+		{DecimalSeparator: ',', ThousandsSeparator: ','},
+		{DecimalSeparator: '1'},
+		{ThousandsSeparator: '-'},
+		{DecimalSeparator: 'e'},
+	} {
+		if err := opts.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted conflicting separators", opts)
+		}
+	}
+}
+
+func TestReadCSVFieldsFromHeader(t *testing.T) {
+	opts := ReadOptions{FieldsFromHeader: true}
+	input := "Merchant Name,AMT,Notes,Txn-ID,created_at,ACCT,Card\n" +
+		"Shop,10,first,1,2024-01-01T10:00:00Z,A,c1\n" +
+		"\"Smith, Jones\",2500,,2,2024-01-01T10:01:00Z,B,\n"
+	got, err := opts.ReadCSV(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadCSV: %v", err)
+	}
+	want := []Transaction{
+		{ID: "1", Amount: 10, Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), AccountID: "A", Merchant: "Shop", CardID: "c1"},
+		{ID: "2", Amount: 2500, Timestamp: time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC), AccountID: "B", Merchant: "Smith, Jones"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read %+v, want %+v", got, want)
+	}
+
+	// A preferred name wins over a later alias of the same field
+	if fields := headerFields([]string{"Description", "Merchant"}); fields["merchant"] != 1 {
+		t.Errorf("merchant found in column %d, want 1", fields["merchant"])
+	}
+
+	_, err = opts.ReadCSV(strings.NewReader("amt,txn_id,when,acct,payee\n10,1,2024-01-01T10:00:00Z,A,Shop\n"))
+	if err == nil || !strings.Contains(err.Error(), "no column for timestamp") {
+		t.Errorf("header without a timestamp: error = %v, want one naming timestamp", err)
+	}
+
+	// Without a header, fields are read by position
+	opts.NoHeader = true
+	got, err = opts.ReadCSV(strings.NewReader("1,10,2024-01-01T10:00:00Z,A,Shop\n"))
+	if err != nil || len(got) != 1 || got[0].ID != "1" || got[0].Amount != 10 {
+		t.Errorf("positional read = %+v, %v", got, err)
+	}
+}
+
+func TestReadJSONPath(t *testing.T) {
+	wrapped := `{
+		"meta": {"page": 1, "transactions": "not these"},
+		"data": {
+			"count": 2,
+			"transactions": [
+				{"id": "1", "amount": 10, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A", "merchant": "Shop"},
+				{"id": "2", "amount": 2500, "timestamp": "2024-01-01T10:01:00Z", "account_id": "B", "merchant": "Smith, Jones"}
+			]
+		}
+	}`
+	got, err := ReadOptions{JSONPath: "data.transactions"}.ReadJSON(strings.NewReader(wrapped))
+	if err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	want := []Transaction{
+		{ID: "1", Amount: 10, Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), AccountID: "A", Merchant: "Shop"},
+		{ID: "2", Amount: 2500, Timestamp: time.Date(2024, 1, 1, 10, 1, 0, 0, time.UTC), AccountID: "B", Merchant: "Smith, Jones"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read %+v, want %+v", got, want)
+	}
+
+	tests := []struct {
+		path, want string
+	}{
+		{"data.missing", `no "missing" key at data in JSON path data.missing`},
+		{"data.count.transactions", "expected JSON object at data.count in JSON path data.count.transactions"},
+		{"meta.transactions", "expected JSON array of transactions"},
+	}
+	for _, tt := range tests {
+		_, err := ReadOptions{JSONPath: tt.path}.ReadJSON(strings.NewReader(wrapped))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("path %s: error = %v, want one containing %q", tt.path, err, tt.want)
+		}
+	}
+	if _, err := (ReadOptions{JSONPath: "data"}).ReadJSON(strings.NewReader(`[]`)); err == nil || !strings.Contains(err.Error(), "at top level") {
+		t.Errorf("path into an array: error = %v, want one naming the top level", err)
+	}
+}
@@ -0,0 +1,141 @@
+package fraud
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule is a fraud detection rule. Evaluate is called once per account with
+// the account's transactions sorted by timestamp.
+type Rule interface {
+	Name() string
+	Evaluate(account []Transaction, config Config) []FraudResult
+}
+
+// accountRule is a built-in rule that looks at an account's whole history
+type accountRule struct {
+	name   string
+	detect func(account []Transaction, config Config) []FraudResult
+}
+
+func (r accountRule) Name() string { return r.name }
+
+func (r accountRule) Evaluate(account []Transaction, config Config) []FraudResult {
+	return r.detect(account, config)
+}
+
+// transactionRule is a built-in rule that needs only the transaction itself
+type transactionRule struct {
+	name  string
+	check func(tx Transaction, config Config) []FraudResult
+}
+
+func (r transactionRule) Name() string { return r.name }
+
+func (r transactionRule) Evaluate(account []Transaction, config Config) []FraudResult {
+	var results []FraudResult
+	for _, tx := range account {
+		results = append(results, r.check(tx, config)...)
+	}
+	return results
+}
+
+// Built-in rules, numbered as in the README
+var (
+	highAmountRule       = transactionRule{"high-amount", checkHighAmount}          // Rule 1
+	rapidRule            = accountRule{"rapid-succession", detectRapid}             // Rule 2
+	velocityRule         = accountRule{"velocity", detectVelocity}                  // Rule 3
+	duplicateRule        = accountRule{"duplicate", detectDuplicates}               // Rule 4
+	blocklistRule        = transactionRule{"blocklist", checkBlocklist}             // Rule 5
+	offHoursRule         = transactionRule{"off-hours", checkOffHours}              // Rule 6
+	roundAmountRule      = transactionRule{"round-amount", checkRoundAmount}        // Rule 7
+	dailyLimitRule       = accountRule{"daily-limit", detectDailyLimit}             // Rule 8
+	impossibleTravelRule = accountRule{"impossible-travel", detectImpossibleTravel} // Rule 9
+	outlierRule          = accountRule{"outlier", detectOutliers}                   // Rule 10
+	newMerchantRule      = accountRule{"new-merchant", detectNewMerchants}          // Rule 11
+	minAmountRule        = transactionRule{"min-amount", checkMinAmount}            // Rule 12
+	zeroAmountRule       = transactionRule{"zero-amount", checkZeroAmount}          // Rule 13
+	structuringRule      = accountRule{"structuring", detectStructuring}            // Rule 14
+	highRiskMCCRule      = transactionRule{"high-risk-mcc", checkHighRiskMCC}       // Rule 15
+	newAccountRule       = accountRule{"new-account-burst", detectNewAccountBurst}  // Rule 16
+	cardTestingRule      = accountRule{"card-testing", detectCardTesting}           // Rule 17
+	sharedDeviceRule     = accountRule{"shared-device", detectSharedDevices}        // Rule 18
+	multiCardRule        = accountRule{"multi-card", detectMultipleCards}           // Rule 19
+	countryRule          = transactionRule{"blocked-country", checkCountry}         // Rule 20
+	weekendHolidayRule   = transactionRule{"weekend-holiday", checkWeekendHoliday}  // Rule 21
+)
+
+// BuiltinRules returns the built-in rules in the order they are applied
+func BuiltinRules() []Rule {
+	return []Rule{
+		highAmountRule,
+		rapidRule,
+		velocityRule,
+		duplicateRule,
+		blocklistRule,
+		offHoursRule,
+		roundAmountRule,
+		dailyLimitRule,
+		impossibleTravelRule,
+		outlierRule,
+		newMerchantRule,
+		minAmountRule,
+		zeroAmountRule,
+		structuringRule,
+		highRiskMCCRule,
+		newAccountRule,
+		cardTestingRule,
+		sharedDeviceRule,
+		multiCardRule,
+		countryRule,
+		weekendHolidayRule,
+	}
+}
+
+// LookupRules returns the built-in rules with the given names, in the given
+// order
+func LookupRules(names []string) ([]Rule, error) {
+	builtin := BuiltinRules()
+	rules := make([]Rule, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, rule := range builtin {
+			if rule.Name() == name {
+				rules = append(rules, rule)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown rule %q (available: %s)", name, strings.Join(RuleNames(), ", "))
+		}
+	}
+	return rules, nil
+}
+
+// RuleNames returns the names of the built-in rules
+func RuleNames() []string {
+	var names []string
+	for _, rule := range BuiltinRules() {
+		names = append(names, rule.Name())
+	}
+	return names
+}
+
+// rules returns the configured rules, defaulting to every built-in rule
+func (c Config) rules() []Rule {
+	if c.Rules == nil {
+		return BuiltinRules()
+	}
+	return c.Rules
+}
+
+// enabled reports whether the named rule is among the configured rules
+func (c Config) enabled(rule Rule) bool {
+	for _, r := range c.rules() {
+		if r.Name() == rule.Name() {
+			return true
+		}
+	}
+	return false
+}
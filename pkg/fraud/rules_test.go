@@ -0,0 +1,162 @@
+package fraud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRulesInIsolation(t *testing.T) {
+	account := []Transaction{
+		testTx("1", "A", 0, 5000, "Shop"),
+		testTx("2", "A", 30*time.Second, 10, "Shop"),
+		testTx("3", "A", time.Hour, 20, "Shop"),
+	}
+	config := Config{HighAmountThreshold: 1000, TimeWindow: time.Minute}
+
+	tests := []struct {
+		rule Rule
+		want []string
+	}{
+		{highAmountRule, []string{"1"}},
+		{rapidRule, []string{"1", "2"}},
+		{duplicateRule, nil},
+	}
+	for _, tt := range tests {
+		if got := flaggedIDs(tt.rule.Evaluate(account, config), ""); !slices.Equal(got, tt.want) {
+			t.Errorf("%s flagged %v, want %v", tt.rule.Name(), got, tt.want)
+		}
+	}
+}
+
+func TestLookupRules(t *testing.T) {
+	rules, err := LookupRules([]string{"velocity", "high-amount"})
+	if err != nil {
+		t.Fatalf("LookupRules: %v", err)
+	}
+	if len(rules) != 2 || rules[0].Name() != "velocity" || rules[1].Name() != "high-amount" {
+		t.Errorf("LookupRules returned %v, want velocity then high-amount", rules)
+	}
+
+	if _, err := LookupRules([]string{"high-amount", "typo"}); err == nil || !strings.Contains(err.Error(), `unknown rule "typo"`) {
+		t.Errorf("LookupRules error = %v, want one naming the unknown rule", err)
+	}
+}
+
+// memoRule flags transactions whose merchant is "MEMO"
+type memoRule struct{}
+
+func (memoRule) Name() string { return "memo" }
+
+func (memoRule) Evaluate(account []Transaction, config Config) []FraudResult {
+	var results []FraudResult
+	for _, tx := range account {
+		if tx.Merchant == "MEMO" {
+			results = append(results, FraudResult{Transaction: tx, Reason: "Memo merchant"})
+		}
+	}
+	return results
+}
+
+func TestCustomRule(t *testing.T) {
+	transactions := []Transaction{
+		testTx("1", "A", 0, 5000, "MEMO"),
+		testTx("2", "B", 0, 10, "Shop"),
+	}
+	// Only the configured rules run, so the high amount is not flagged
+	results := Detect(transactions, Config{HighAmountThreshold: 1000, Rules: []Rule{memoRule{}}})
+	if len(results) != 1 || results[0].Reason != "Memo merchant" || !slices.Equal(results[0].Rules, []string{"memo"}) {
+		t.Errorf("results = %+v, want transaction 1 flagged by memo alone", results)
+	}
+}
+
+// slowRule flags every transaction, taking delay per account
+type slowRule struct {
+	delay time.Duration
+}
+
+func (slowRule) Name() string { return "slow" }
+
+func (r slowRule) Evaluate(account []Transaction, config Config) []FraudResult {
+	time.Sleep(r.delay)
+	return []FraudResult{{Transaction: account[0], Reason: "Slow"}}
+}
+
+func TestDetectContextCanceled(t *testing.T) {
+	// Uncanceled, the run would take 1000 accounts * 10ms / 2 workers = 5s
+	var transactions []Transaction
+	for i := 0; i < 1000; i++ {
+		transactions = append(transactions, testTx(fmt.Sprint(i), fmt.Sprintf("A%d", i), 0, 10, "Shop"))
+	}
+	config := Config{Rules: []Rule{slowRule{10 * time.Millisecond}}, BatchSize: 1, Concurrency: 2}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	results, err := DetectContext(ctx, transactions, config)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("DetectContext returned %v after cancellation", elapsed)
+	}
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("DetectContext error = %v, want %v", err, context.Canceled)
+	}
+	if len(results) == 0 || len(results) == len(transactions) {
+		t.Errorf("got %d results, want a partial result", len(results))
+	}
+}
+
+// countingRule flags transactions at the "MATCH" merchant, counting the
+// accounts it evaluates
+type countingRule struct {
+	evaluated *atomic.Int64
+}
+
+func (countingRule) Name() string { return "counting" }
+
+func (r countingRule) Evaluate(account []Transaction, config Config) []FraudResult {
+	r.evaluated.Add(1)
+	var results []FraudResult
+	for _, tx := range account {
+		if tx.Merchant == "MATCH" {
+			results = append(results, FraudResult{Transaction: tx, Reason: "Match", Severity: SeverityHigh})
+		}
+	}
+	return results
+}
+
+func TestStopOnMatch(t *testing.T) {
+	// The first of 10000 accounts has the only match
+	transactions := []Transaction{testTx("match", "A0", 0, 10, "MATCH")}
+	for i := 1; i < 10000; i++ {
+		transactions = append(transactions, testTx(fmt.Sprint(i), fmt.Sprintf("A%d", i), 0, 10, "Shop"))
+	}
+
+	for _, batchSize := range []int{1, 100} {
+		var evaluated atomic.Int64
+		config := Config{Rules: []Rule{countingRule{&evaluated}}, BatchSize: batchSize, Concurrency: 2, StopOnMatch: true}
+		results, err := DetectContext(context.Background(), transactions, config)
+		if err != nil {
+			t.Fatalf("DetectContext: %v", err)
+		}
+		if len(results) != 1 || results[0].Transaction.ID != "match" {
+			t.Errorf("batch size %d: results = %+v, want the match", batchSize, results)
+		}
+		if n := evaluated.Load(); n > int64(len(transactions)/10) {
+			t.Errorf("batch size %d: evaluated %d of %d accounts, want an early stop", batchSize, n, len(transactions))
+		}
+
+		// Without StopOnMatch every account is evaluated
+		evaluated.Store(0)
+		config.StopOnMatch = false
+		Detect(transactions, config)
+		if n := evaluated.Load(); n != int64(len(transactions)) {
+			t.Errorf("batch size %d without StopOnMatch: evaluated %d accounts, want %d", batchSize, n, len(transactions))
+		}
+	}
+}
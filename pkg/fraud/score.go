@@ -0,0 +1,31 @@
+package fraud
+
+import "slices"
+
+// severityWeights are the risk weights of rules without one in
+// Config.RuleWeights, by the rule's severity
+var severityWeights = map[Severity]float64{
+	SeverityLow:    10,
+	SeverityMedium: 25,
+	SeverityHigh:   50,
+}
+
+// RuleWeight returns the risk score a match of the named rule adds: its
+// weight in c.RuleWeights, else a default for its severity
+func (c Config) RuleWeight(rule string) float64 {
+	if weight, ok := c.RuleWeights[rule]; ok {
+		return weight
+	}
+	return severityWeights[RuleSeverity(rule)]
+}
+
+// FilterRisk drops merged results whose score is not above c.RiskThreshold.
+// A zero threshold keeps every result.
+func (c Config) FilterRisk(results []FraudResult) []FraudResult {
+	if c.RiskThreshold == 0 {
+		return results
+	}
+	return slices.DeleteFunc(results, func(result FraudResult) bool {
+		return result.Score <= c.RiskThreshold
+	})
+}
@@ -0,0 +1,62 @@
+package fraud
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRiskThreshold(t *testing.T) {
+	night := testStart.Add(-7 * time.Hour) // 03:00, off hours
+	transactions := []Transaction{
+		// One weak signal each: a high amount by day, a small amount at night
+		testTx("high", "A", 0, 5000, "Shop"),
+		{ID: "night", Amount: 10, Timestamp: night, AccountID: "B", Merchant: "Shop"},
+		// Two signals, together only at the threshold
+		{ID: "high-night", Amount: 5000, Timestamp: night, AccountID: "C", Merchant: "Shop"},
+		// A high amount at night in a rapid pair, whose first half is weaker
+		{ID: "burst1", Amount: 10, Timestamp: night, AccountID: "D", Merchant: "Shop"},
+		{ID: "burst2", Amount: 5000, Timestamp: night.Add(time.Minute), AccountID: "D", Merchant: "Shop"},
+	}
+	config := Config{
+		HighAmountThreshold: 1000,
+		TimeWindow:          5 * time.Minute,
+		OffHoursStart:       2,
+		OffHoursEnd:         5,
+		RuleWeights:         map[string]float64{"high-amount": 40, "rapid-succession": 30, "off-hours": 10},
+		Rules:               onlyRules(t, "high-amount", "rapid-succession", "off-hours"),
+	}
+
+	scores := make(map[string]float64)
+	for _, result := range Detect(transactions, config) {
+		scores[result.Transaction.ID] = result.Score
+	}
+	want := map[string]float64{"high": 40, "night": 10, "high-night": 50, "burst1": 40, "burst2": 80}
+	for id, score := range want {
+		if scores[id] != score {
+			t.Errorf("%s: score = %v, want %v", id, scores[id], score)
+		}
+	}
+
+	config.RiskThreshold = 50
+	if got, want := flaggedIDs(Detect(transactions, config), ""), []string{"burst2"}; !slices.Equal(got, want) {
+		t.Errorf("above %v flagged %v, want %v", config.RiskThreshold, got, want)
+	}
+}
+
+func TestRuleWeight(t *testing.T) {
+	config := Config{RuleWeights: map[string]float64{"high-amount": 40, "off-hours": 0}}
+	tests := []struct {
+		rule string
+		want float64
+	}{
+		{"high-amount", 40},
+		{"off-hours", 0},
+		{"rapid-succession", severityWeights[RuleSeverity("rapid-succession")]},
+	}
+	for _, tt := range tests {
+		if got := config.RuleWeight(tt.rule); got != tt.want {
+			t.Errorf("RuleWeight(%q) = %v, want %v", tt.rule, got, tt.want)
+		}
+	}
+}
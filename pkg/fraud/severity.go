@@ -0,0 +1,126 @@
+package fraud
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Severity is how serious a rule's finding is
+type Severity int
+
+// Severity levels, from least to most serious. The zero value means a
+// result whose rule has not been labeled yet.
+const (
+	SeverityLow Severity = iota + 1
+	SeverityMedium
+	SeverityHigh
+)
+
+// severityNames are the names of the severity levels, by level
+var severityNames = map[Severity]string{
+	SeverityLow:    "low",
+	SeverityMedium: "medium",
+	SeverityHigh:   "high",
+}
+
+// ruleSeverities are the severities of the built-in rules. Other rules are
+// medium.
+var ruleSeverities = map[string]Severity{
+	highAmountRule.name:       SeverityMedium,
+	rapidRule.name:            SeverityLow,
+	velocityRule.name:         SeverityMedium,
+	duplicateRule.name:        SeverityMedium,
+	blocklistRule.name:        SeverityHigh,
+	offHoursRule.name:         SeverityLow,
+	roundAmountRule.name:      SeverityLow,
+	dailyLimitRule.name:       SeverityMedium,
+	impossibleTravelRule.name: SeverityHigh,
+	outlierRule.name:          SeverityMedium,
+	newMerchantRule.name:      SeverityLow,
+	minAmountRule.name:        SeverityMedium,
+	zeroAmountRule.name:       SeverityMedium,
+	structuringRule.name:      SeverityHigh,
+	highRiskMCCRule.name:      SeverityMedium,
+	newAccountRule.name:       SeverityMedium,
+	cardTestingRule.name:      SeverityHigh,
+	sharedDeviceRule.name:     SeverityHigh,
+	multiCardRule.name:        SeverityMedium,
+	countryRule.name:          SeverityHigh,
+	weekendHolidayRule.name:   SeverityLow,
+}
+
+// RuleSeverity returns the severity of a rule's results
+func RuleSeverity(rule string) Severity {
+	if severity, ok := ruleSeverities[rule]; ok {
+		return severity
+	}
+	return SeverityMedium
+}
+
+// ParseSeverity parses a severity name: low, medium, or high
+func ParseSeverity(name string) (Severity, error) {
+	for severity, n := range severityNames {
+		if strings.EqualFold(strings.TrimSpace(name), n) {
+			return severity, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q (use low, medium, or high)", name)
+}
+
+// String returns the severity's name, or "" for the zero value
+func (s Severity) String() string {
+	return severityNames[s]
+}
+
+// MarshalText encodes the severity by name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a severity name, accepting "" as the zero value
+func (s *Severity) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = 0
+		return nil
+	}
+	severity, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = severity
+	return nil
+}
+
+// label sets the severity and risk weight of rule on its results, in place,
+// and rewords them with its reason template
+func (c Config) label(rule Rule, results []FraudResult) []FraudResult {
+	severity, weight := RuleSeverity(rule.Name()), c.RuleWeight(rule.Name())
+	for i := range results {
+		results[i].Severity = severity
+		results[i].Score = weight
+		results[i].Rules = []string{rule.Name()}
+	}
+	return c.reword(rule, results)
+}
+
+// severe drops the results less severe than c.MinSeverity
+func (c Config) severe(results []FraudResult) []FraudResult {
+	if c.MinSeverity == 0 {
+		return results
+	}
+	return slices.DeleteFunc(results, func(result FraudResult) bool {
+		return result.Severity < c.MinSeverity
+	})
+}
+
+// matched reports whether any of the results is severe enough, and with a
+// risk threshold scores high enough, to be kept
+func (c Config) matched(results []FraudResult) bool {
+	if c.RiskThreshold > 0 {
+		return len(c.FilterRisk(MergeResults(c.severe(slices.Clone(results))))) > 0
+	}
+	return slices.ContainsFunc(results, func(result FraudResult) bool {
+		return result.Severity >= c.MinSeverity
+	})
+}
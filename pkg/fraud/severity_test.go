@@ -0,0 +1,60 @@
+package fraud
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestMinSeverity(t *testing.T) {
+	offHours := func(tx Transaction) Transaction {
+		tx.Timestamp = time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+		return tx
+	}
+	transactions := []Transaction{
+		offHours(testTx("night", "A", 0, 10, "Shop")),         // Low
+		testTx("large", "B", 0, 5000, "Shop"),                 // Medium
+		testTx("casino", "C", 0, 10, "Casino"),                // High
+		offHours(testTx("large-night", "D", 0, 5000, "Shop")), // Low and medium
+	}
+	config := Config{
+		HighAmountThreshold: 1000,
+		MerchantBlocklist:   map[string]bool{"casino": true},
+		OffHoursStart:       2,
+		OffHoursEnd:         5,
+		Rules:               onlyRules(t, "high-amount", "blocklist", "off-hours"),
+	}
+
+	tests := []struct {
+		min  Severity
+		want []string
+	}{
+		{0, []string{"casino", "large", "large-night", "night"}},
+		{SeverityLow, []string{"casino", "large", "large-night", "night"}},
+		{SeverityMedium, []string{"casino", "large", "large-night"}},
+		{SeverityHigh, []string{"casino"}},
+	}
+	for _, tt := range tests {
+		config.MinSeverity = tt.min
+		results := Detect(transactions, config)
+		if got := flaggedIDs(results, ""); !slices.Equal(got, tt.want) {
+			t.Errorf("min severity %q: flagged %v, want %v", tt.min, got, tt.want)
+		}
+		for _, result := range results {
+			if result.Severity < tt.min {
+				t.Errorf("min severity %q: %s has severity %q", tt.min, result.Transaction.ID, result.Severity)
+			}
+		}
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	for name, want := range map[string]Severity{"low": SeverityLow, "Medium": SeverityMedium, " HIGH ": SeverityHigh} {
+		if got, err := ParseSeverity(name); err != nil || got != want {
+			t.Errorf("ParseSeverity(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseSeverity("critical"); err == nil {
+		t.Error("ParseSeverity accepted an unknown level")
+	}
+}
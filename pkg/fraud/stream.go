@@ -0,0 +1,473 @@
+package fraud
+
+import (
+	"fmt"
+	"time"
+)
+
+// sweepInterval is how many transactions StreamDetector processes between
+// sweeps that drop accounts with no recent activity
+const sweepInterval = 10000
+
+// StreamDetector applies the fraud detection rules to transactions one at a
+// time, keeping only a bounded window of recent transactions per account.
+// Idle accounts are dropped, except that the new-account burst rule remembers
+// the ID of each one and the shared device rule the accounts of each device,
+// so with those rules memory grows with the number of distinct accounts.
+// Transactions of each account must arrive in timestamp order. Only the
+// built-in rules in Config.Rules are applied; other Rule implementations need
+// an account's whole history and are ignored.
+type StreamDetector struct {
+	config     Config
+	lookback   time.Duration
+	idle       time.Duration
+	accounts   map[string]*accountWindow
+	latest     time.Time
+	added      int
+	unordered  int
+	overflowed int
+
+	// Accounts seen with each device ID, for the shared device rule
+	devices map[string]map[string]bool
+
+	// Accounts dropped by sweep, whose first activity is long past, for the
+	// new-account burst rule. Kept for the rest of the run, one entry per
+	// idle account.
+	swept map[string]bool
+}
+
+// accountWindow holds an account's transactions that are still within the
+// lookback of the stream detector
+type accountWindow struct {
+	recent []windowEntry
+
+	// Counts of the velocity, card testing, and multiple cards rules
+	velocity  *rollingWindow
+	merchants *rollingWindow
+	cards     *rollingWindow
+
+	// Most recent transaction with coordinates, for the impossible travel rule
+	lastLocated *Transaction
+
+	// Running amount statistics of earlier transactions, for the z-score rule
+	count       int
+	amountSum   float64
+	amountSumSq float64
+
+	// Lowercased merchants the account has used, for the new merchant rule.
+	// They are dropped with the account when it is swept.
+	usedMerchants map[string]bool
+
+	// Running totals of the current day for the daily limit rule, by
+	// currency code
+	day       string
+	dayTotals map[string]*dayTotal
+
+	// Near-limit transactions of the current day for the structuring rule,
+	// kept only until there are enough to flag
+	nearDay     string
+	nearCount   int
+	nearPending []Transaction
+
+	// First transaction time and the transactions soon after it, for the
+	// new-account burst rule, kept only until there are enough to flag.
+	// Accounts returning after a sweep are established and never new.
+	first       time.Time
+	newCount    int
+	newPending  []Transaction
+	established bool
+}
+
+// dayTotal is an account's running total in one currency for the daily
+// limit rule. The day's transactions are kept only until the limit is
+// exceeded.
+type dayTotal struct {
+	total   float64
+	count   int
+	pending []Transaction
+}
+
+// windowEntry is a buffered transaction and whether the velocity, rapid
+// succession, card testing, and multiple cards rules have already reported
+// it
+type windowEntry struct {
+	tx              Transaction
+	velocityFlagged bool
+	rapidFlagged    bool
+	merchantFlagged bool
+	cardFlagged     bool
+	simultaneous    bool // Reported as simultaneous with a later transaction
+}
+
+// NewStreamDetector returns a StreamDetector for the given thresholds
+func NewStreamDetector(config Config) *StreamDetector {
+	return &StreamDetector{
+		config:   config,
+		lookback: config.windowLookback(),
+		idle:     config.Lookback(),
+		accounts: make(map[string]*accountWindow),
+	}
+}
+
+// Add evaluates tx against the recent transactions of its account and returns
+// the results it triggers, which may include earlier transactions
+func (d *StreamDetector) Add(tx Transaction) []FraudResult {
+	// Whitelisted transactions are excluded from every rule
+	if d.config.whitelisted(tx) {
+		return nil
+	}
+
+	// Rules that look at a single transaction
+	results := checkTransaction(tx, d.config)
+
+	window := d.accounts[tx.AccountID]
+	if window == nil {
+		window = &accountWindow{established: d.swept[tx.AccountID]}
+		d.accounts[tx.AccountID] = window
+	}
+	if n := len(window.recent); n > 0 && tx.Timestamp.Before(window.recent[n-1].tx.Timestamp) {
+		d.unordered++
+	}
+	window.evict(tx.Timestamp, d.lookback)
+	if limit := d.config.StreamBuffer; limit > 0 && len(window.recent) >= limit {
+		// Make room for tx, dropping the oldest transactions still in the window
+		n := len(window.recent) - limit + 1
+		window.recent = append(window.recent[:0], window.recent[n:]...)
+		d.overflowed += n
+	}
+
+	rapid, duplicate := d.config.enabled(rapidRule), d.config.enabled(duplicateRule)
+	escalation := d.config.RapidEscalation > 0
+	var rapidResults, duplicateResults []FraudResult
+	var from *Transaction
+	related, simultaneous := 0, 0
+	for i := range window.recent {
+		entry := &window.recent[i]
+		prevTx := entry.tx
+		timeDiff := tx.Timestamp.Sub(prevTx.Timestamp)
+
+		// Rule 2: Rapid succession. Earlier transactions are reported when
+		// their first related transaction arrives. With RapidEscalation,
+		// only pairs whose amount escalates are related. With
+		// FlagSimultaneous, transactions at the same instant are reported
+		// too, as simultaneous.
+		if rapid && d.config.FlagSimultaneous && timeDiff == 0 {
+			simultaneous++
+			if !entry.simultaneous {
+				entry.simultaneous = true
+				rapidResults = append(rapidResults, FraudResult{Transaction: prevTx, Reason: simultaneousReason(2, tx.Timestamp, d.config)})
+			}
+		}
+		if rapid && escalation && timeDiff > 0 && timeDiff < d.config.TimeWindow && d.config.escalates(prevTx, tx) {
+			if from == nil || prevTx.Amount < from.Amount {
+				from = &entry.tx
+			}
+			if !entry.rapidFlagged {
+				entry.rapidFlagged = true
+				rapidResults = append(rapidResults, FraudResult{Transaction: prevTx, Reason: escalationReason(prevTx, tx, d.config)})
+			}
+		} else if rapid && !escalation && timeDiff > 0 && timeDiff < d.config.TimeWindow {
+			related++
+			if !entry.rapidFlagged {
+				entry.rapidFlagged = true
+				rapidResults = append(rapidResults, FraudResult{Transaction: prevTx, Reason: rapidReason(1, d.config)})
+			}
+		}
+
+		// Rule 4: Duplicate charges
+		if duplicate && timeDiff >= 0 && timeDiff < d.config.DuplicateWindow && d.config.CurrencyOf(tx).compareAmounts(prevTx.Amount, tx.Amount) == 0 && d.config.NormalizeMerchant(prevTx.Merchant) == d.config.NormalizeMerchant(tx.Merchant) {
+			reason := fmt.Sprintf("Duplicate charge: %s at %s within %v", d.config.formatAmount(tx, tx.Amount), tx.Merchant, timeDiff)
+			duplicateResults = append(duplicateResults, FraudResult{Transaction: prevTx, Reason: reason})
+			duplicateResults = append(duplicateResults, FraudResult{Transaction: tx, Reason: reason})
+		}
+	}
+
+	if related > 0 {
+		rapidResults = append(rapidResults, FraudResult{Transaction: tx, Reason: rapidReason(related, d.config)})
+	}
+	if from != nil {
+		rapidResults = append(rapidResults, FraudResult{Transaction: tx, Reason: escalationReason(*from, tx, d.config)})
+	}
+	if simultaneous > 0 {
+		rapidResults = append(rapidResults, FraudResult{Transaction: tx, Reason: simultaneousReason(simultaneous+1, tx.Timestamp, d.config)})
+	}
+	results = append(results, d.config.label(rapidRule, rapidResults)...)
+	results = append(results, d.config.label(duplicateRule, duplicateResults)...)
+	window.recent = append(window.recent, windowEntry{tx: tx, rapidFlagged: related > 0 || from != nil})
+
+	// Rule 3: Velocity, reporting each transaction of a burst once
+	if d.config.VelocityCount > 0 && d.config.enabled(velocityRule) {
+		if window.velocity == nil {
+			window.velocity = newRollingWindow(d.config.VelocityWindow, d.config.StreamBuffer, nil)
+		}
+		if n := window.velocity.add(tx); n > d.config.VelocityCount {
+			mark := len(results)
+			burst := window.tail(tx.Timestamp, d.config.VelocityWindow)
+			reason := fmt.Sprintf("Velocity: %d transactions in %v", n, tx.Timestamp.Sub(burst[0].tx.Timestamp))
+			for i := range burst {
+				if !burst[i].velocityFlagged {
+					burst[i].velocityFlagged = true
+					results = append(results, FraudResult{Transaction: burst[i].tx, Reason: reason})
+				}
+			}
+			d.config.label(velocityRule, results[mark:])
+		}
+	}
+
+	// Rule 17: Card testing across many merchants, reporting each
+	// transaction of a burst once
+	if d.config.DistinctMerchants > 0 && d.config.enabled(cardTestingRule) {
+		if window.merchants == nil {
+			window.merchants = newRollingWindow(d.config.MerchantWindow, d.config.StreamBuffer, d.config.merchantKey)
+		}
+		if n := window.merchants.add(tx); n > d.config.DistinctMerchants {
+			mark := len(results)
+			burst := window.tail(tx.Timestamp, d.config.MerchantWindow)
+			reason := cardTestingReason(n, tx.Timestamp.Sub(burst[0].tx.Timestamp))
+			for i := range burst {
+				if !burst[i].merchantFlagged {
+					burst[i].merchantFlagged = true
+					results = append(results, FraudResult{Transaction: burst[i].tx, Reason: reason})
+				}
+			}
+			d.config.label(cardTestingRule, results[mark:])
+		}
+	}
+
+	// Rule 19: Multiple cards, reporting each transaction of a burst once
+	if d.config.MaxCards > 0 && tx.CardID != "" && d.config.enabled(multiCardRule) {
+		if window.cards == nil {
+			window.cards = newRollingWindow(d.config.CardWindow, d.config.StreamBuffer, cardKey)
+		}
+		if n := window.cards.add(tx); n > d.config.MaxCards {
+			mark := len(results)
+			reason := multipleCardsReason(n, d.config)
+			burst := window.tail(tx.Timestamp, d.config.CardWindow)
+			for i := range burst {
+				if burst[i].tx.CardID != "" && !burst[i].cardFlagged {
+					burst[i].cardFlagged = true
+					results = append(results, FraudResult{Transaction: burst[i].tx, Reason: reason})
+				}
+			}
+			d.config.label(multiCardRule, results[mark:])
+		}
+	}
+
+	// Rule 18: Devices shared by many accounts. Only transactions after the
+	// device exceeds the limit are reported.
+	if d.config.MaxAccountsPerDevice > 0 && tx.DeviceID != "" && d.config.enabled(sharedDeviceRule) {
+		if d.devices == nil {
+			d.devices = make(map[string]map[string]bool)
+		}
+		if d.devices[tx.DeviceID] == nil {
+			d.devices[tx.DeviceID] = make(map[string]bool)
+		}
+		d.devices[tx.DeviceID][tx.AccountID] = true
+		if n := len(d.devices[tx.DeviceID]); n > d.config.MaxAccountsPerDevice {
+			results = append(results, d.config.label(sharedDeviceRule, []FraudResult{{Transaction: tx, Reason: sharedDeviceReason(n)}})...)
+		}
+	}
+
+	// Rule 8: Daily spending limit
+	if d.config.DailyLimit > 0 && d.config.enabled(dailyLimitRule) {
+		results = append(results, d.config.label(dailyLimitRule, window.addToDay(tx, d.config))...)
+	}
+
+	// Rule 14: Structuring just below a reporting limit
+	if d.config.StructuringLimit > 0 && d.config.enabled(structuringRule) && d.config.nearLimit(tx) {
+		results = append(results, d.config.label(structuringRule, window.addNearLimit(tx, d.config))...)
+	}
+
+	// Rule 16: Burst of activity right after an account's first transaction
+	if d.config.NewAccountCount > 0 && d.config.enabled(newAccountRule) {
+		results = append(results, d.config.label(newAccountRule, window.addNewAccount(tx, d.config))...)
+	}
+
+	// Rule 9: Impossible travel
+	if d.config.MaxSpeedKmh > 0 && tx.hasLocation() && d.config.enabled(impossibleTravelRule) {
+		if window.lastLocated != nil {
+			results = append(results, d.config.label(impossibleTravelRule, checkTravel(*window.lastLocated, tx, d.config))...)
+		}
+		located := tx
+		window.lastLocated = &located
+	}
+
+	// Rule 10: Statistical outliers, compared with the account's earlier
+	// transactions since later ones are not known yet
+	if d.config.ZScore > 0 && d.config.enabled(outlierRule) && window.count > 0 && window.count+1 >= d.config.MinSamples {
+		n := float64(window.count)
+		if result, ok := checkOutlier(tx, n, window.amountSum, window.amountSumSq, d.config); ok {
+			results = append(results, d.config.label(outlierRule, []FraudResult{result})...)
+		}
+	}
+	// Rule 11: First transaction with a merchant
+	if d.config.HistoryMin > 0 && d.config.enabled(newMerchantRule) {
+		if window.usedMerchants == nil {
+			window.usedMerchants = make(map[string]bool)
+		}
+		if result, ok := checkNewMerchant(tx, window.count, window.usedMerchants, d.config); ok {
+			results = append(results, d.config.label(newMerchantRule, []FraudResult{result})...)
+		}
+	}
+
+	window.count++
+	window.amountSum += tx.Amount
+	window.amountSumSq += tx.Amount * tx.Amount
+
+	d.sweep(tx.Timestamp)
+
+	return d.config.severe(results)
+}
+
+// Unordered returns how many transactions arrived earlier than a previous
+// transaction of their account. Rules comparing transactions may miss
+// detections involving them.
+func (d *StreamDetector) Unordered() int {
+	return d.unordered
+}
+
+// Overflowed returns how many transactions were dropped from full account
+// buffers while still within the lookback. Rules comparing transactions may
+// undercount bursts involving them.
+func (d *StreamDetector) Overflowed() int {
+	return d.overflowed
+}
+
+// addToDay adds tx to the account's running daily total in its currency.
+// When the total first exceeds the limit the day's earlier transactions in
+// that currency are flagged with it; later ones that day are flagged as they
+// arrive.
+func (w *accountWindow) addToDay(tx Transaction, config Config) []FraudResult {
+	if day := dayKey(tx.Timestamp, config); day != w.day {
+		w.day = day
+		w.dayTotals = make(map[string]*dayTotal)
+	}
+
+	currency := config.CurrencyOf(tx)
+	running := w.dayTotals[currency.Code]
+	if running == nil {
+		running = &dayTotal{}
+		w.dayTotals[currency.Code] = running
+	}
+	running.total += tx.Amount
+	running.count++
+	if currency.compareAmounts(running.total, config.DailyLimit) <= 0 {
+		running.pending = append(running.pending, tx)
+		return nil
+	}
+
+	reason := dailyLimitReason(running.total, running.count, currency)
+	results := make([]FraudResult, 0, len(running.pending)+1)
+	for _, pending := range running.pending {
+		results = append(results, FraudResult{Transaction: pending, Reason: reason})
+	}
+	running.pending = nil
+
+	return append(results, FraudResult{Transaction: tx, Reason: reason})
+}
+
+// addNearLimit counts a near-limit transaction toward the account's current
+// day. Once there are enough, the day's earlier near-limit transactions are
+// flagged with it; later ones that day are flagged as they arrive.
+func (w *accountWindow) addNearLimit(tx Transaction, config Config) []FraudResult {
+	if day := dayKey(tx.Timestamp, config); day != w.nearDay {
+		w.nearDay = day
+		w.nearCount = 0
+		w.nearPending = nil
+	}
+
+	w.nearCount++
+	if w.nearCount < config.StructuringCount {
+		w.nearPending = append(w.nearPending, tx)
+		return nil
+	}
+
+	reason := structuringReason(w.nearCount, tx, config)
+	results := make([]FraudResult, 0, len(w.nearPending)+1)
+	for _, pending := range w.nearPending {
+		results = append(results, FraudResult{Transaction: pending, Reason: reason})
+	}
+	w.nearPending = nil
+
+	return append(results, FraudResult{Transaction: tx, Reason: reason})
+}
+
+// addNewAccount counts tx toward the account's first-activity burst when it
+// falls within the new-account window. Once there are more than
+// config.NewAccountCount, the earlier ones are flagged with it; later ones in
+// the window are flagged as they arrive.
+func (w *accountWindow) addNewAccount(tx Transaction, config Config) []FraudResult {
+	if w.established {
+		return nil
+	}
+	if w.count == 0 {
+		w.first = tx.Timestamp
+	}
+	span := tx.Timestamp.Sub(w.first)
+	if span >= config.NewAccountWindow {
+		w.newPending = nil
+		return nil
+	}
+
+	w.newCount++
+	if w.newCount <= config.NewAccountCount {
+		w.newPending = append(w.newPending, tx)
+		return nil
+	}
+
+	reason := newAccountReason(w.newCount, span)
+	results := make([]FraudResult, 0, len(w.newPending)+1)
+	for _, pending := range w.newPending {
+		results = append(results, FraudResult{Transaction: pending, Reason: reason})
+	}
+	w.newPending = nil
+
+	return append(results, FraudResult{Transaction: tx, Reason: reason})
+}
+
+// tail returns the account's recent transactions less than length before
+// now, oldest first
+func (w *accountWindow) tail(now time.Time, length time.Duration) []windowEntry {
+	start := len(w.recent) - 1
+	for start > 0 && now.Sub(w.recent[start-1].tx.Timestamp) < length {
+		start--
+	}
+	return w.recent[start:]
+}
+
+// evict drops buffered transactions that are at least lookback older than now
+func (w *accountWindow) evict(now time.Time, lookback time.Duration) {
+	n := 0
+	for n < len(w.recent) && now.Sub(w.recent[n].tx.Timestamp) >= lookback {
+		n++
+	}
+	if n > 0 {
+		w.recent = append(w.recent[:0], w.recent[n:]...)
+	}
+}
+
+// sweep periodically drops accounts that have been idle longer than any rule
+// looks back, bounding their windows by the number of active accounts
+func (d *StreamDetector) sweep(now time.Time) {
+	if now.After(d.latest) {
+		d.latest = now
+	}
+
+	d.added++
+	if d.added%sweepInterval != 0 {
+		return
+	}
+
+	for accountID, window := range d.accounts {
+		last := window.recent[len(window.recent)-1].tx.Timestamp
+		if d.latest.Sub(last) >= d.idle {
+			delete(d.accounts, accountID)
+			if d.config.NewAccountCount > 0 {
+				if d.swept == nil {
+					d.swept = make(map[string]bool)
+				}
+				d.swept[accountID] = true
+			}
+		}
+	}
+}
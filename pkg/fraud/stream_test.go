@@ -0,0 +1,230 @@
+package fraud
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"reflect"
+	"runtime"
+	"slices"
+	"testing"
+	"time"
+)
+
+// streamResults feeds transactions to a StreamDetector in order, returning
+// the merged results
+func streamResults(transactions []Transaction, config Config) []FraudResult {
+	detector := NewStreamDetector(config)
+	var results []FraudResult
+	for _, tx := range transactions {
+		results = append(results, detector.Add(tx)...)
+	}
+	return MergeResults(results)
+}
+
+func TestNewAccountBurst(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tx := func(id, account string, offset time.Duration) Transaction {
+		return Transaction{ID: id, Amount: 10, Timestamp: start.Add(offset), AccountID: account, Merchant: "Shop"}
+	}
+
+	transactions := []Transaction{
+		// A bursts right after its first transaction; S is steady
+		tx("a1", "A", 0),
+		tx("a2", "A", time.Minute),
+		tx("a3", "A", 2*time.Minute),
+		tx("s1", "S", 0),
+		tx("s2", "S", time.Hour),
+		tx("s3", "S", 2*time.Hour),
+	}
+	// Other accounts keep the stream busy long enough for S to be swept
+	for i := 0; i < sweepInterval; i++ {
+		transactions = append(transactions, tx(fmt.Sprintf("f%d", i), fmt.Sprintf("F%d", i), 2*time.Hour+time.Duration(i)*time.Second))
+	}
+	// S returns with a quick run of transactions, long after its first activity
+	end := 2*time.Hour + sweepInterval*time.Second
+	transactions = append(transactions, tx("s4", "S", end), tx("s5", "S", end+time.Second), tx("s6", "S", end+2*time.Second))
+
+	config := Config{NewAccountCount: 2, NewAccountWindow: 10 * time.Minute}
+	want := []string{"a1", "a2", "a3"}
+	if got := flaggedIDs(Detect(transactions, config), "New-account burst"); !slices.Equal(got, want) {
+		t.Errorf("Detect flagged %v, want %v", got, want)
+	}
+	if got := flaggedIDs(streamResults(transactions, config), "New-account burst"); !slices.Equal(got, want) {
+		t.Errorf("StreamDetector flagged %v, want %v", got, want)
+	}
+}
+
+func TestNewMerchantAfterSweep(t *testing.T) {
+	var transactions []Transaction
+	for i := 0; i < 6; i++ {
+		transactions = append(transactions, testTx(fmt.Sprintf("a%d", i), "A", time.Duration(i)*time.Minute, 10, "Shop"))
+	}
+	// Other accounts keep the stream busy long enough for A to be swept
+	for i := 0; i < sweepInterval; i++ {
+		transactions = append(transactions, testTx(fmt.Sprintf("f%d", i), fmt.Sprintf("F%d", i), time.Hour+time.Duration(i)*time.Second, 10, "Shop"))
+	}
+	// A returns at a new merchant, then goes back to its old one
+	end := time.Hour + sweepInterval*time.Second
+	for i := 0; i < 6; i++ {
+		transactions = append(transactions, testTx(fmt.Sprintf("b%d", i), "A", end+time.Duration(i)*time.Minute, 10, "Cafe"))
+	}
+	transactions = append(transactions, testTx("back", "A", end+time.Hour, 10, "Shop"))
+
+	config := Config{HistoryMin: 5, Rules: onlyRules(t, "new-merchant")}
+	if got, want := flaggedIDs(Detect(transactions, config), ""), []string{"b0"}; !slices.Equal(got, want) {
+		t.Errorf("Detect flagged %v, want %v", got, want)
+	}
+	// A swept account's merchants are forgotten, so the stream sees its
+	// return as a fresh history (see rule 11 in the README)
+	if got, want := flaggedIDs(streamResults(transactions, config), ""), []string{"back"}; !slices.Equal(got, want) {
+		t.Errorf("StreamDetector flagged %v, want %v", got, want)
+	}
+}
+
+// syntheticCSV returns a CSV of n time-ordered transactions across 1,000
+// accounts, generated as it is read so the input itself takes no memory
+func syntheticCSV(n int) io.Reader {
+	r, w := io.Pipe()
+	go func() {
+		bw := bufio.NewWriter(w)
+		bw.WriteString("id,amount,timestamp,account_id,merchant\n")
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i < n; i++ {
+			ts := start.Add(time.Duration(i) * time.Second).Format(time.RFC3339)
+			fmt.Fprintf(bw, "%d,%d.00,%s,A%d,M%d\n", i, 10+i%5000, ts, i%1000, i%50)
+		}
+		bw.Flush()
+		w.Close()
+	}()
+	return r
+}
+
+// BenchmarkStreamMemory streams synthetic files of growing size through a
+// StreamDetector and reports the heap in use at the end, which stays flat
+// as the file grows
+func BenchmarkStreamMemory(b *testing.B) {
+	config := Config{
+		HighAmountThreshold: 4000,
+		TimeWindow:          5 * time.Minute,
+		VelocityCount:       5,
+		VelocityWindow:      10 * time.Minute,
+		DuplicateWindow:     time.Minute,
+	}
+	for _, n := range []int{100000, 1000000} {
+		b.Run(fmt.Sprintf("rows=%d", n), func(b *testing.B) {
+			var heap uint64
+			for i := 0; i < b.N; i++ {
+				detector := NewStreamDetector(config)
+				err := StreamCSV(syntheticCSV(n), func(tx Transaction) error {
+					detector.Add(tx)
+					return nil
+				})
+				if err != nil {
+					b.Fatal(err)
+				}
+
+				runtime.GC()
+				var stats runtime.MemStats
+				runtime.ReadMemStats(&stats)
+				heap = max(heap, stats.HeapInuse)
+				runtime.KeepAlive(detector)
+			}
+			b.ReportMetric(float64(heap)/(1<<20), "heap-MB")
+		})
+	}
+}
+
+func TestShuffledInput(t *testing.T) {
+	var sorted []Transaction
+	for i := 0; i < 40; i++ {
+		sorted = append(sorted, testTx(fmt.Sprint(i), fmt.Sprintf("A%d", i%4), time.Duration(i)*time.Minute, float64(100*(i%7)), "Shop"))
+	}
+	shuffled := slices.Clone(sorted)
+	rand.New(rand.NewSource(1)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+	config := Config{HighAmountThreshold: 500, TimeWindow: 5 * time.Minute, VelocityCount: 2, VelocityWindow: 10 * time.Minute}
+
+	// Detect sorts each account, so the order of the input does not matter
+	want := Detect(sorted, config)
+	if got := Detect(shuffled, config); !reflect.DeepEqual(got, want) {
+		t.Errorf("shuffled input gave %d results, sorted input %d", len(got), len(want))
+	}
+
+	// Streaming cannot sort, so it counts the transactions out of order
+	detector := NewStreamDetector(config)
+	for _, tx := range sorted {
+		detector.Add(tx)
+	}
+	if n := detector.Unordered(); n != 0 {
+		t.Errorf("sorted input: Unordered() = %d, want 0", n)
+	}
+	detector = NewStreamDetector(config)
+	for _, tx := range shuffled {
+		detector.Add(tx)
+	}
+	if n := detector.Unordered(); n == 0 {
+		t.Error("shuffled input: Unordered() = 0")
+	}
+}
+
+func TestStreamRapidBoundedBuffer(t *testing.T) {
+	// Every tenth transaction follows the one before it within the window
+	var transactions []Transaction
+	offset := time.Duration(0)
+	for i := 0; i < 1000; i++ {
+		if i%10 == 0 {
+			offset += time.Minute
+		} else {
+			offset += 10 * time.Minute
+		}
+		transactions = append(transactions, testTx(fmt.Sprint(i), "A", offset, 10, "Shop"))
+	}
+	config := Config{TimeWindow: 5 * time.Minute, StreamBuffer: 3, Rules: onlyRules(t, "rapid-succession")}
+
+	detector := NewStreamDetector(config)
+	var results []FraudResult
+	for _, tx := range transactions {
+		results = append(results, detector.Add(tx)...)
+		window := detector.accounts["A"]
+		if len(window.recent) > config.StreamBuffer {
+			t.Fatalf("after %s the buffer holds %d transactions, more than %d", tx.ID, len(window.recent), config.StreamBuffer)
+		}
+		// Transactions older than the window are evicted as time moves on
+		for _, entry := range window.recent {
+			if age := tx.Timestamp.Sub(entry.tx.Timestamp); age >= detector.lookback {
+				t.Fatalf("after %s the buffer holds %s from %v earlier", tx.ID, entry.tx.ID, age)
+			}
+		}
+	}
+
+	if got, want := flaggedIDs(MergeResults(results), ""), flaggedIDs(Detect(transactions, config), ""); !slices.Equal(got, want) || len(got) != 198 {
+		t.Errorf("stream flagged %d transactions, Detect %d, want 198 from both", len(got), len(want))
+	}
+	if n := detector.Overflowed(); n != 0 {
+		t.Errorf("Overflowed() = %d, want 0 with the window evicting first", n)
+	}
+}
+
+func TestStreamBufferOverflow(t *testing.T) {
+	var transactions []Transaction
+	for i := 0; i < 5; i++ {
+		transactions = append(transactions, testTx(fmt.Sprint(i), "A", time.Duration(i)*time.Second, 10, "Shop"))
+	}
+	config := Config{TimeWindow: 5 * time.Minute, StreamBuffer: 3, Rules: onlyRules(t, "rapid-succession")}
+
+	detector := NewStreamDetector(config)
+	var results []FraudResult
+	for _, tx := range transactions {
+		results = append(results, detector.Add(tx)...)
+	}
+	// The oldest transactions make room for newer ones, after being flagged
+	if got, want := flaggedIDs(MergeResults(results), ""), []string{"0", "1", "2", "3", "4"}; !slices.Equal(got, want) {
+		t.Errorf("flagged %v, want %v", got, want)
+	}
+	if n := detector.Overflowed(); n != 2 {
+		t.Errorf("Overflowed() = %d, want 2", n)
+	}
+}
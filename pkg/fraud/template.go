@@ -0,0 +1,70 @@
+package fraud
+
+import (
+	"strings"
+	"text/template"
+)
+
+// ReasonData is what a reason template in Config.ReasonTemplates is rendered
+// with. The transaction's fields are available directly, e.g. {{.Amount}}.
+type ReasonData struct {
+	Transaction
+	Rule      string  // Name of the rule, e.g. "high-amount"
+	Threshold float64 // Amount limit the rule compared with, or 0 when it has none
+	Reason    string  // The rule's default reason
+}
+
+// ParseReasonTemplate parses a reason template and checks that it renders
+// with ReasonData
+func ParseReasonTemplate(rule, text string) (*template.Template, error) {
+	tmpl, err := template.New(rule).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(new(strings.Builder), ReasonData{Rule: rule}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// reword renders the configured reason template of rule, if any, for each
+// result. A result whose template fails to render keeps its default reason.
+func (c Config) reword(rule Rule, results []FraudResult) []FraudResult {
+	tmpl := c.ReasonTemplates[rule.Name()]
+	if tmpl == nil {
+		return results
+	}
+
+	for i, result := range results {
+		data := ReasonData{
+			Transaction: result.Transaction,
+			Rule:        rule.Name(),
+			Threshold:   c.ruleThreshold(rule, result.Transaction),
+			Reason:      result.Reason,
+		}
+		var reason strings.Builder
+		if err := tmpl.Execute(&reason, data); err == nil {
+			results[i].Reason = reason.String()
+		}
+	}
+	return results
+}
+
+// ruleThreshold returns the amount limit a built-in rule compares tx with, or
+// 0 for rules without one
+func (c Config) ruleThreshold(rule Rule, tx Transaction) float64 {
+	switch rule.Name() {
+	case highAmountRule.name:
+		threshold, _ := c.highAmountThreshold(tx)
+		return threshold
+	case roundAmountRule.name:
+		return c.RoundMultiple
+	case dailyLimitRule.name:
+		return c.DailyLimit
+	case minAmountRule.name:
+		return c.MinAmount
+	case structuringRule.name:
+		return c.StructuringLimit
+	}
+	return 0
+}
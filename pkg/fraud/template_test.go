@@ -0,0 +1,65 @@
+package fraud
+
+import (
+	"slices"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestReasonTemplate(t *testing.T) {
+	tmpl, err := ParseReasonTemplate("high-amount", "{{.Rule}}: {{.Amount}} over {{.Threshold}}")
+	if err != nil {
+		t.Fatalf("ParseReasonTemplate: %v", err)
+	}
+	config := Config{
+		HighAmountThreshold: 1000,
+		ReasonTemplates:     map[string]*template.Template{"high-amount": tmpl},
+	}
+	transactions := []Transaction{
+		{ID: "1", Amount: 5000, Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), AccountID: "A", Merchant: "Shop"},
+	}
+
+	results := Detect(transactions, config)
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1: %v", len(results), results)
+	}
+	if want := "high-amount: 5000 over 1000"; results[0].Reason != want {
+		t.Errorf("reason = %q, want %q", results[0].Reason, want)
+	}
+	if !slices.Equal(results[0].Rules, []string{"high-amount"}) {
+		t.Errorf("rules = %v, want [high-amount]", results[0].Rules)
+	}
+}
+
+func TestParseReasonTemplateUnknownField(t *testing.T) {
+	if _, err := ParseReasonTemplate("high-amount", "{{.Nope}}"); err == nil {
+		t.Error("ParseReasonTemplate accepted an unknown field")
+	}
+}
+
+func TestMergeResultsRules(t *testing.T) {
+	tx := Transaction{ID: "1"}
+	results := MergeResults([]FraudResult{
+		{Transaction: tx, Reason: "big", Severity: SeverityHigh, Score: 3, Rules: []string{"high-amount"}},
+		{Transaction: tx, Reason: "fast", Severity: SeverityMedium, Score: 2, Rules: []string{"rapid-succession"}},
+		{Transaction: tx, Reason: "fast again", Severity: SeverityMedium, Score: 2, Rules: []string{"rapid-succession"}},
+	})
+
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	got := results[0]
+	if !slices.Equal(got.Rules, []string{"high-amount", "rapid-succession"}) {
+		t.Errorf("rules = %v, want [high-amount rapid-succession]", got.Rules)
+	}
+	if got.Score != 5 {
+		t.Errorf("score = %v, want 5", got.Score)
+	}
+	if got.Severity != SeverityHigh {
+		t.Errorf("severity = %v, want %v", got.Severity, SeverityHigh)
+	}
+	if want := "big; fast; fast again"; got.Reason != want {
+		t.Errorf("reason = %q, want %q", got.Reason, want)
+	}
+}
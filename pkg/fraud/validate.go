@@ -0,0 +1,118 @@
+package fraud
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FieldError is one invalid Config field reported by Validate
+type FieldError struct {
+	Field   string // Name of the field, e.g. "TimeWindow"
+	Key     string // Key of the invalid entry of a map field, or ""
+	Problem string // What is wrong with it, e.g. "must be positive (got 0s)"
+}
+
+func (e *FieldError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("%s[%q] %s", e.Field, e.Key, e.Problem)
+	}
+	return e.Field + " " + e.Problem
+}
+
+// Validate reports every invalid threshold in the config as a *FieldError,
+// one per field, or returns nil when the config is usable. Config holds no
+// file names, so callers check that the files they load settings from exist.
+func (c Config) Validate() error {
+	var errs []error
+	invalid := func(field, format string, args ...any) {
+		errs = append(errs, &FieldError{Field: field, Problem: fmt.Sprintf(format, args...)})
+	}
+	nonNegativeEntry := func(field, key string, value float64) {
+		if value < 0 {
+			errs = append(errs, &FieldError{Field: field, Key: key, Problem: fmt.Sprintf("must not be negative (got %g)", value)})
+		}
+	}
+	nonNegative := func(field string, value float64) {
+		nonNegativeEntry(field, "", value)
+	}
+
+	nonNegative("HighAmountThreshold", c.HighAmountThreshold)
+	for category, threshold := range c.CategoryThresholds {
+		nonNegativeEntry("CategoryThresholds", category, threshold)
+	}
+	for account, threshold := range c.AccountThresholds {
+		nonNegativeEntry("AccountThresholds", account, threshold)
+	}
+	if c.TimeWindow <= 0 {
+		invalid("TimeWindow", "must be positive (got %v)", c.TimeWindow)
+	}
+	if c.RapidEscalation != 0 && !(c.RapidEscalation >= 1) {
+		invalid("RapidEscalation", "must be 0 or at least 1 (got %g)", c.RapidEscalation)
+	}
+	nonNegative("VelocityCount", float64(c.VelocityCount))
+	if c.VelocityCount > 0 && c.VelocityWindow <= 0 {
+		invalid("VelocityWindow", "must be positive when a velocity count is set (got %v)", c.VelocityWindow)
+	}
+	if c.DuplicateWindow < 0 {
+		invalid("DuplicateWindow", "must not be negative (got %v)", c.DuplicateWindow)
+	}
+	nonNegative("BatchSize", float64(c.BatchSize))
+	nonNegative("StreamBuffer", float64(c.StreamBuffer))
+	if c.StreamBuffer > 0 && c.VelocityCount >= c.StreamBuffer {
+		invalid("StreamBuffer", "must exceed the velocity count so bursts can be counted (got %d, velocity count %d)", c.StreamBuffer, c.VelocityCount)
+	}
+	if c.StreamBuffer > 0 && c.DistinctMerchants >= c.StreamBuffer {
+		invalid("StreamBuffer", "must exceed the distinct merchant count so bursts can be counted (got %d, distinct merchants %d)", c.StreamBuffer, c.DistinctMerchants)
+	}
+	if c.StreamBuffer > 0 && c.MaxCards >= c.StreamBuffer {
+		invalid("StreamBuffer", "must exceed the maximum card count so bursts can be counted (got %d, max cards %d)", c.StreamBuffer, c.MaxCards)
+	}
+	nonNegative("Concurrency", float64(c.Concurrency))
+	if c.OffHoursStart < 0 || c.OffHoursStart > 23 {
+		invalid("OffHoursStart", "must be an hour between 0 and 23 (got %d)", c.OffHoursStart)
+	}
+	if c.OffHoursEnd < 0 || c.OffHoursEnd > 23 {
+		invalid("OffHoursEnd", "must be an hour between 0 and 23 (got %d)", c.OffHoursEnd)
+	}
+	nonNegative("RoundMultiple", c.RoundMultiple)
+	nonNegative("RoundMin", c.RoundMin)
+	nonNegative("DailyLimit", c.DailyLimit)
+	nonNegative("MaxSpeedKmh", c.MaxSpeedKmh)
+	nonNegative("ZScore", c.ZScore)
+	nonNegative("MinSamples", float64(c.MinSamples))
+	nonNegative("HistoryMin", float64(c.HistoryMin))
+	nonNegative("StructuringLimit", c.StructuringLimit)
+	nonNegative("NewAccountCount", float64(c.NewAccountCount))
+	nonNegative("DistinctMerchants", float64(c.DistinctMerchants))
+	nonNegative("MaxAccountsPerDevice", float64(c.MaxAccountsPerDevice))
+	nonNegative("MaxCards", float64(c.MaxCards))
+	for rule, weight := range c.RuleWeights {
+		nonNegativeEntry("RuleWeights", rule, weight)
+	}
+	nonNegative("RiskThreshold", c.RiskThreshold)
+	if c.MaxCards > 0 && c.CardWindow <= 0 {
+		invalid("CardWindow", "must be positive when a maximum card count is set (got %v)", c.CardWindow)
+	}
+	if c.DistinctMerchants > 0 && c.MerchantWindow <= 0 {
+		invalid("MerchantWindow", "must be positive when a distinct merchant count is set (got %v)", c.MerchantWindow)
+	}
+	if c.NewAccountCount > 0 && c.NewAccountWindow <= 0 {
+		invalid("NewAccountWindow", "must be positive when a new account count is set (got %v)", c.NewAccountWindow)
+	}
+	if c.MinSeverity < 0 || c.MinSeverity > SeverityHigh {
+		invalid("MinSeverity", "must be a severity level or 0 (got %d)", c.MinSeverity)
+	}
+	if c.StructuringBand < 0 || c.StructuringBand >= 1 {
+		invalid("StructuringBand", "must be a fraction from 0 up to 1 (got %g)", c.StructuringBand)
+	}
+	if c.StructuringLimit > 0 && c.StructuringCount < 1 {
+		invalid("StructuringCount", "must be at least 1 when a structuring limit is set (got %d)", c.StructuringCount)
+	}
+	if c.Currency != "" {
+		if _, err := LookupCurrency(c.Currency); err != nil {
+			invalid("Currency", "is not supported: %v", err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
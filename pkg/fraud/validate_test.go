@@ -0,0 +1,88 @@
+package fraud
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig returns a config that passes Validate with most rules enabled
+func validConfig() Config {
+	return Config{
+		HighAmountThreshold: 1000,
+		TimeWindow:          5 * time.Minute,
+		VelocityCount:       5,
+		VelocityWindow:      time.Hour,
+		StreamBuffer:        100,
+		OffHoursStart:       22,
+		OffHoursEnd:         5,
+		MaxCards:            3,
+		CardWindow:          time.Hour,
+		StructuringLimit:    10000,
+		StructuringBand:     0.1,
+		StructuringCount:    2,
+		Currency:            "EUR",
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Errorf("valid config: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(*Config)
+		want   string
+	}{
+		{"negative amount", func(c *Config) { c.HighAmountThreshold = -100 }, "HighAmountThreshold must not be negative (got -100)"},
+		{"negative category", func(c *Config) { c.CategoryThresholds = map[string]float64{"atm": -1} }, `CategoryThresholds["atm"] must not be negative`},
+		{"negative account", func(c *Config) { c.AccountThresholds = map[string]float64{"a": -1} }, `AccountThresholds["a"] must not be negative`},
+		{"zero window", func(c *Config) { c.TimeWindow = 0 }, "TimeWindow must be positive"},
+		{"negative window", func(c *Config) { c.TimeWindow = -5 * time.Minute }, "TimeWindow must be positive (got -5m0s)"},
+		{"escalation below 1", func(c *Config) { c.RapidEscalation = 0.5 }, "RapidEscalation must be 0 or at least 1"},
+		{"velocity without window", func(c *Config) { c.VelocityWindow = 0 }, "VelocityWindow must be positive"},
+		{"negative duplicate window", func(c *Config) { c.DuplicateWindow = -time.Second }, "DuplicateWindow must not be negative"},
+		{"small stream buffer", func(c *Config) { c.StreamBuffer = 5 }, "StreamBuffer must exceed the velocity count"},
+		{"off-hours hour", func(c *Config) { c.OffHoursEnd = 24 }, "OffHoursEnd must be an hour between 0 and 23"},
+		{"negative z-score", func(c *Config) { c.ZScore = -1 }, "ZScore must not be negative"},
+		{"cards without window", func(c *Config) { c.CardWindow = 0 }, "CardWindow must be positive when a maximum card count is set"},
+		{"severity", func(c *Config) { c.MinSeverity = SeverityHigh + 1 }, "MinSeverity must be a severity level"},
+		{"structuring band", func(c *Config) { c.StructuringBand = 1 }, "StructuringBand must be a fraction"},
+		{"structuring count", func(c *Config) { c.StructuringCount = 0 }, "StructuringCount must be at least 1"},
+		{"negative weight", func(c *Config) { c.RuleWeights = map[string]float64{"velocity": -1} }, `RuleWeights["velocity"] must not be negative`},
+		{"currency", func(c *Config) { c.Currency = "XYZ" }, `Currency is not supported: unknown currency "XYZ"`},
+	}
+	for _, tt := range tests {
+		config := validConfig()
+		tt.modify(&config)
+		if err := config.Validate(); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: Validate() = %v, want an error containing %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestValidateReportsEveryField(t *testing.T) {
+	config := validConfig()
+	config.HighAmountThreshold = -1
+	config.TimeWindow = 0
+	err := config.Validate()
+	if err == nil {
+		t.Fatal("Validate accepted two invalid fields")
+	}
+	errs := err.(interface{ Unwrap() []error }).Unwrap()
+	if len(errs) != 2 {
+		t.Fatalf("got %d errors, want 2: %v", len(errs), err)
+	}
+	var fieldErr *FieldError
+	if !errors.As(errs[1], &fieldErr) || fieldErr.Field != "TimeWindow" || fieldErr.Problem != "must be positive (got 0s)" {
+		t.Errorf("second error = %#v, want a TimeWindow FieldError", errs[1])
+	}
+
+	config = validConfig()
+	config.CategoryThresholds = map[string]float64{"atm": -1}
+	if !errors.As(config.Validate(), &fieldErr) || fieldErr.Field != "CategoryThresholds" || fieldErr.Key != "atm" {
+		t.Errorf("negative category threshold reported as %#v", fieldErr)
+	}
+}
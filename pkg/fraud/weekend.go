@@ -0,0 +1,27 @@
+package fraud
+
+import (
+	"fmt"
+	"time"
+)
+
+// HolidayLayout is the date format of Config.Holidays keys
+const HolidayLayout = "2006-01-02"
+
+// checkWeekendHoliday flags transactions on a listed holiday, or on a
+// Saturday or Sunday when config.FlagWeekends is set, in config.Location
+func checkWeekendHoliday(tx Transaction, config Config) []FraudResult {
+	local := tx.Timestamp.In(config.location())
+	if date := local.Format(HolidayLayout); config.Holidays[date] {
+		return []FraudResult{{Transaction: tx, Reason: fmt.Sprintf("Holiday transaction: %s", date)}}
+	}
+	if config.FlagWeekends && isWeekend(local) {
+		return []FraudResult{{Transaction: tx, Reason: fmt.Sprintf("Weekend transaction: %s", local.Weekday())}}
+	}
+	return nil
+}
+
+// isWeekend reports whether t falls on a Saturday or Sunday
+func isWeekend(t time.Time) bool {
+	return t.Weekday() == time.Saturday || t.Weekday() == time.Sunday
+}
@@ -0,0 +1,45 @@
+package fraud
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWeekendHoliday(t *testing.T) {
+	at := func(year int, month time.Month, day, hour int) Transaction {
+		return Transaction{ID: "1", Amount: 10, Timestamp: time.Date(year, month, day, hour, 0, 0, 0, time.UTC), AccountID: "A", Merchant: "Shop"}
+	}
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("loading time zone: %v", err)
+	}
+	holidays := map[string]bool{"2024-01-01": true, "2024-12-25": true}
+
+	tests := []struct {
+		name   string
+		config Config
+		tx     Transaction
+		want   string
+	}{
+		{"saturday", Config{FlagWeekends: true}, at(2024, 1, 6, 12), "Weekend transaction: Saturday"},
+		{"sunday", Config{FlagWeekends: true}, at(2024, 1, 7, 12), "Weekend transaction: Sunday"},
+		{"weekday", Config{FlagWeekends: true}, at(2024, 1, 5, 12), ""},
+		{"weekends off", Config{}, at(2024, 1, 6, 12), ""},
+		{"holiday", Config{Holidays: holidays}, at(2024, 1, 1, 12), "Holiday transaction: 2024-01-01"},
+		{"holiday on a weekend", Config{FlagWeekends: true, Holidays: map[string]bool{"2024-01-06": true}}, at(2024, 1, 6, 12), "Holiday transaction: 2024-01-06"},
+		{"not a holiday", Config{Holidays: holidays}, at(2024, 1, 2, 12), ""},
+		// Saturday 02:00 UTC is still Friday evening in New York, and
+		// 2024-12-26 03:00 UTC still Christmas Day there
+		{"friday in location", Config{FlagWeekends: true, Location: newYork}, at(2024, 1, 6, 2), ""},
+		{"holiday in location", Config{Holidays: holidays, Location: newYork}, at(2024, 12, 26, 3), "Holiday transaction: 2024-12-25"},
+	}
+	for _, tt := range tests {
+		var got string
+		if results := checkWeekendHoliday(tt.tx, tt.config); len(results) > 0 {
+			got = results[0].Reason
+		}
+		if got != tt.want {
+			t.Errorf("%s: reason = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
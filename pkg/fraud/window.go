@@ -0,0 +1,98 @@
+package fraud
+
+import "time"
+
+// rollingWindow counts an account's transactions within a rolling time
+// window, or their distinct keys when key is set. It is shared by the
+// velocity, card testing, and multiple cards rules in both Detect and
+// StreamDetector. Transactions are added in timestamp order.
+type rollingWindow struct {
+	length   time.Duration
+	key      func(Transaction) string // Nil counts every transaction
+	capacity int                      // Most transactions kept; 0 keeps all within length
+
+	entries []windowKey
+	keys    map[string]int
+}
+
+// windowKey is the time and key of a transaction in a rollingWindow
+type windowKey struct {
+	time time.Time
+	key  string
+}
+
+// newRollingWindow returns a window of the given length, keeping at most
+// capacity transactions unless it is 0, that counts distinct keys, or every
+// transaction when key is nil
+func newRollingWindow(length time.Duration, capacity int, key func(Transaction) string) *rollingWindow {
+	return &rollingWindow{length: length, capacity: capacity, key: key}
+}
+
+// add adds tx, drops the transactions at least length before it, and returns
+// the count of those left
+func (w *rollingWindow) add(tx Transaction) int {
+	entry := windowKey{time: tx.Timestamp}
+	if w.key != nil {
+		entry.key = w.key(tx)
+		if w.keys == nil {
+			w.keys = make(map[string]int)
+		}
+		w.keys[entry.key]++
+	}
+	w.entries = append(w.entries, entry)
+
+	n := 0
+	for n < len(w.entries)-1 && (tx.Timestamp.Sub(w.entries[n].time) >= w.length || w.capacity > 0 && len(w.entries)-n > w.capacity) {
+		if w.key != nil {
+			if w.keys[w.entries[n].key]--; w.keys[w.entries[n].key] == 0 {
+				delete(w.keys, w.entries[n].key)
+			}
+		}
+		n++
+	}
+	w.entries = w.entries[n:]
+
+	return w.count()
+}
+
+// count returns the transactions in the window, or their distinct keys
+func (w *rollingWindow) count() int {
+	if w.key != nil {
+		return len(w.keys)
+	}
+	return len(w.entries)
+}
+
+// len returns the number of transactions in the window
+func (w *rollingWindow) len() int {
+	return len(w.entries)
+}
+
+// windowBursts slides a window of length over transactions, which must be
+// sorted by timestamp, and returns the bursts during which it counts more
+// than limit transactions, or distinct keys when key is set. Overlapping
+// windows over the limit merge into one burst, so each transaction is in at
+// most one.
+func windowBursts(transactions []Transaction, length time.Duration, limit int, key func(Transaction) string) [][]Transaction {
+	var bursts [][]Transaction
+	window := newRollingWindow(length, 0, key)
+	burstStart, burstEnd := -1, -1
+	for end, tx := range transactions {
+		if window.add(tx) <= limit {
+			continue
+		}
+		start := end - window.len() + 1
+		if burstStart >= 0 && start > burstEnd {
+			bursts = append(bursts, transactions[burstStart:burstEnd+1])
+			burstStart = -1
+		}
+		if burstStart < 0 {
+			burstStart = start
+		}
+		burstEnd = end
+	}
+	if burstStart >= 0 {
+		bursts = append(bursts, transactions[burstStart:burstEnd+1])
+	}
+	return bursts
+}
@@ -0,0 +1,115 @@
+package fraud
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWindowBursts(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tx := func(id string, minutes int, merchant string) Transaction {
+		return Transaction{ID: id, Timestamp: start.Add(time.Duration(minutes) * time.Minute), AccountID: "A", Merchant: merchant}
+	}
+	transactions := []Transaction{
+		tx("1", 0, "X"),
+		tx("2", 1, "X"),
+		tx("3", 2, "Y"),
+		tx("4", 4, "Z"),
+		tx("5", 20, "X"),
+		tx("6", 30, "X"),
+		tx("7", 40, "Y"),
+		tx("8", 45, "Z"),
+	}
+	merchant := func(tx Transaction) string { return tx.Merchant }
+
+	tests := []struct {
+		name   string
+		length time.Duration
+		limit  int
+		key    func(Transaction) string
+		want   [][]string
+	}{
+		{"count", 5 * time.Minute, 2, nil, [][]string{{"1", "2", "3", "4"}}},
+		{"window excludes its length", 2 * time.Minute, 2, nil, nil},
+		{"repeats counted", 3 * time.Minute, 2, nil, [][]string{{"1", "2", "3"}}},
+		{"repeat keys counted once", 3 * time.Minute, 2, merchant, nil},
+		{"separate bursts", 16 * time.Minute, 2, merchant, [][]string{{"1", "2", "3", "4"}, {"6", "7", "8"}}},
+		{"overlapping windows merge", 20 * time.Minute, 1, merchant, [][]string{{"1", "2", "3", "4", "5"}, {"6", "7", "8"}}},
+		{"under the limit", time.Hour, 8, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]string
+			for _, burst := range windowBursts(transactions, tt.length, tt.limit, tt.key) {
+				var ids []string
+				for _, tx := range burst {
+					ids = append(ids, tx.ID)
+				}
+				got = append(got, ids)
+			}
+			if !slices.EqualFunc(got, tt.want, slices.Equal) {
+				t.Errorf("windowBursts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRollingWindowCapacity(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	window := newRollingWindow(time.Hour, 2, cardKey)
+	var counts []int
+	for i, card := range []string{"C1", "C2", "C3", "C3"} {
+		counts = append(counts, window.add(Transaction{Timestamp: start.Add(time.Duration(i) * time.Minute), CardID: card}))
+	}
+	if want := []int{1, 2, 2, 1}; !slices.Equal(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+}
+
+// TestWindowRulesStreamMatchesDetect checks that the rules sharing
+// rollingWindow flag the same transactions in Detect and StreamDetector
+func TestWindowRulesStreamMatchesDetect(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var transactions []Transaction
+	for i := 0; i < 200; i++ {
+		transactions = append(transactions, Transaction{
+			ID:        fmt.Sprint(i),
+			Amount:    10,
+			Timestamp: start.Add(time.Duration(i*i%97) * time.Minute),
+			AccountID: []string{"A", "B", "C"}[i%3],
+			Merchant:  []string{"M1", "M2", "M3", "M4"}[i*7%4],
+			CardID:    []string{"", "C1", "C2", "C3"}[i*5%4],
+		})
+	}
+	slices.SortStableFunc(transactions, func(a, b Transaction) int { return a.Timestamp.Compare(b.Timestamp) })
+
+	tests := []struct {
+		rule   string
+		reason string
+		config Config
+	}{
+		{"velocity", "Velocity", Config{VelocityCount: 3, VelocityWindow: 5 * time.Minute}},
+		{"card-testing", "Card testing", Config{DistinctMerchants: 2, MerchantWindow: 5 * time.Minute}},
+		{"multi-card", "Multiple instruments", Config{MaxCards: 1, CardWindow: 5 * time.Minute}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rules, err := LookupRules([]string{tt.rule})
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.config.Rules = rules
+
+			batch := flaggedIDs(Detect(transactions, tt.config), tt.reason)
+			stream := flaggedIDs(streamResults(transactions, tt.config), tt.reason)
+			if len(batch) == 0 {
+				t.Fatal("Detect flagged nothing")
+			}
+			if !slices.Equal(batch, stream) {
+				t.Errorf("Detect flagged %v, StreamDetector %v", batch, stream)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package fraud
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxRecordColumns locates the optional columns in the records StreamXLSX
+// builds, which hold the required columns followed by these optional ones
+var xlsxRecordColumns = csvColumns{"latitude": 5, "longitude": 6, "currency": 7, "category": 8, "mcc": 9, "device_id": 10, "card_id": 11, "country": 12}
+
+// maxExcelSerial is the date serial number of 9999-12-31, the last date
+// Excel can represent. Larger numeric timestamps are taken as Unix seconds.
+const maxExcelSerial = 2958465
+
+// ReadXLSX reads transactions from the first worksheet of an Excel workbook
+func ReadXLSX(file io.Reader) ([]Transaction, error) {
+	return ReadOptions{}.ReadXLSX(file)
+}
+
+// StreamXLSX reads transactions from the first worksheet of an Excel
+// workbook one row at a time, calling fn for each transaction. It stops at
+// the first error returned by fn.
+func StreamXLSX(file io.Reader, fn func(Transaction) error) error {
+	return ReadOptions{}.StreamXLSX(file, fn)
+}
+
+// ReadXLSX reads transactions from the first worksheet of an Excel workbook
+func (o ReadOptions) ReadXLSX(file io.Reader) ([]Transaction, error) {
+	var transactions []Transaction
+	err := o.StreamXLSX(file, func(tx Transaction) error {
+		transactions = append(transactions, tx)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// StreamXLSX reads transactions from the first worksheet of an Excel
+// workbook one row at a time, calling fn for each transaction. Columns are
+// located by header name in any order, and date cells in the timestamp
+// column are converted from Excel serial numbers. It stops at the first
+// error returned by fn.
+func (o ReadOptions) StreamXLSX(file io.Reader, fn func(Transaction) error) error {
+	// Excel formats numbers itself, so CSV number separators do not apply
+	o.DecimalSeparator, o.ThousandsSeparator = 0, 0
+
+	workbook, err := excelize.OpenReader(file)
+	if err != nil {
+		return err
+	}
+	defer workbook.Close()
+
+	rows, err := workbook.Rows(workbook.GetSheetName(0))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Read raw values so dates arrive as serial numbers rather than in the
+	// cell's display format
+	raw := excelize.Options{RawCellValue: true}
+
+	var columns csvColumns
+	for i := 0; rows.Next(); i++ {
+		row, err := rows.Columns(raw)
+		if err != nil {
+			return err
+		}
+
+		if i == 0 {
+			columns = newCSVColumns(row)
+			if o.FieldsFromHeader {
+				for field, i := range headerFields(row) {
+					columns[field] = i
+				}
+			}
+			for _, name := range requiredColumns {
+				if _, ok := columns[name]; !ok {
+					return fmt.Errorf("missing %q column in worksheet header", name)
+				}
+			}
+			continue
+		}
+
+		record := make([]string, len(requiredColumns)+len(xlsxRecordColumns))
+		for i, name := range requiredColumns {
+			record[i] = columns.value(row, name)
+		}
+		for name, i := range xlsxRecordColumns {
+			record[i] = columns.value(row, name)
+		}
+		record[2] = excelTimestamp(record[2])
+
+		tx, err := o.parseRecord(record, i+1, xlsxRecordColumns)
+		if err != nil {
+			if err := o.invalid(err); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := fn(tx); err != nil {
+			return err
+		}
+	}
+
+	return rows.Error()
+}
+
+// excelTimestamp converts an Excel date serial number to RFC 3339, leaving
+// text timestamps and Unix epoch seconds unchanged
+func excelTimestamp(value string) string {
+	serial, err := strconv.ParseFloat(value, 64)
+	if err != nil || serial < 0 || serial > maxExcelSerial {
+		return value
+	}
+
+	t, err := excelize.ExcelDateToTime(serial, false)
+	if err != nil {
+		return value
+	}
+	return t.Round(time.Second).Format(time.RFC3339)
+}
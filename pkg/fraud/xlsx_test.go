@@ -0,0 +1,88 @@
+package fraud
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// workbook returns an Excel workbook of the given rows
+func workbook(t *testing.T, rows ...[]any) *bytes.Buffer {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	sheet := f.GetSheetName(0)
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			t.Fatalf("writing row %d: %v", i+1, err)
+		}
+	}
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatalf("writing workbook: %v", err)
+	}
+	return buf
+}
+
+func TestReadXLSX(t *testing.T) {
+	date := time.Date(2024, 3, 5, 14, 30, 0, 0, time.UTC)
+	input := workbook(t,
+		[]any{"merchant", "account_id", "id", "timestamp", "amount"},
+		[]any{"Shop", "A", "1", date, 12.5},
+		[]any{"Cafe", "B", "2", "2024-03-05T15:00:00Z", 3},
+	)
+
+	transactions, err := ReadXLSX(input)
+	if err != nil {
+		t.Fatalf("ReadXLSX: %v", err)
+	}
+	want := []Transaction{
+		{ID: "1", Amount: 12.5, Timestamp: date, AccountID: "A", Merchant: "Shop"},
+		{ID: "2", Amount: 3, Timestamp: date.Add(30 * time.Minute), AccountID: "B", Merchant: "Cafe"},
+	}
+	if len(transactions) != len(want) {
+		t.Fatalf("got %d transactions, want %d", len(transactions), len(want))
+	}
+	for i := range want {
+		got := transactions[i]
+		if got.ID != want[i].ID || got.Amount != want[i].Amount || !got.Timestamp.Equal(want[i].Timestamp) || got.AccountID != want[i].AccountID || got.Merchant != want[i].Merchant {
+			t.Errorf("transaction %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+}
+
+func TestReadXLSXMissingColumn(t *testing.T) {
+	input := workbook(t, []any{"id", "amount", "timestamp", "merchant"}, []any{"1", 10, "2024-01-01T10:00:00Z", "Shop"})
+	if _, err := ReadXLSX(input); err == nil || !strings.Contains(err.Error(), `missing "account_id" column`) {
+		t.Errorf("ReadXLSX error = %v, want one naming the account_id column", err)
+	}
+}
+
+func TestReadXLSXSkipInvalid(t *testing.T) {
+	rows := [][]any{
+		{"id", "amount", "timestamp", "account_id", "merchant"},
+		{"1", 10, "2024-01-01T10:00:00Z", "A", "Shop"},
+		{"2", "ten", "2024-01-01T11:00:00Z", "A", "Shop"},
+		{"3", 30, "2024-01-01T12:00:00Z", "A", "Shop"},
+	}
+	if _, err := ReadXLSX(workbook(t, rows...)); err == nil {
+		t.Error("ReadXLSX accepted an invalid amount without SkipInvalid")
+	}
+
+	var skipped []error
+	opts := ReadOptions{SkipInvalid: true, OnInvalid: func(err error) { skipped = append(skipped, err) }}
+	transactions, err := opts.ReadXLSX(workbook(t, rows...))
+	if err != nil {
+		t.Fatalf("ReadXLSX: %v", err)
+	}
+	if len(transactions) != 2 || transactions[0].ID != "1" || transactions[1].ID != "3" {
+		t.Errorf("got transactions %+v, want 1 and 3", transactions)
+	}
+	if len(skipped) != 1 {
+		t.Errorf("reported %d invalid rows, want 1: %v", len(skipped), skipped)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+	"runtime/pprof"
+)
+
+// exitHooks run, most recent first, when the process ends through exit or
+// main returns, e.g. to flush profiles
+var exitHooks []func()
+
+// exit runs the exit hooks and ends the process with the given status. Use it
+// instead of os.Exit so profiles are written on error paths too.
+func exit(code int) {
+	runExitHooks()
+	os.Exit(code)
+}
+
+// runExitHooks runs the exit hooks once
+func runExitHooks() {
+	hooks := exitHooks
+	exitHooks = nil
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i]()
+	}
+}
+
+// startProfiles starts a CPU profile written to cpuPath and arranges for a
+// heap profile to be written to memPath at exit. Empty paths are skipped.
+func startProfiles(cpuPath, memPath string) error {
+	if cpuPath != "" {
+		file, err := os.Create(cpuPath)
+		if err != nil {
+			return err
+		}
+		if err := pprof.StartCPUProfile(file); err != nil {
+			file.Close()
+			return fmt.Errorf("starting CPU profile: %v", err)
+		}
+		exitHooks = append(exitHooks, func() {
+			pprof.StopCPUProfile()
+			if err := file.Close(); err != nil {
+				slog.Error("writing CPU profile", "file", cpuPath, "error", err)
+			}
+		})
+	}
+
+	if memPath != "" {
+		exitHooks = append(exitHooks, func() {
+			if err := writeHeapProfile(memPath); err != nil {
+				slog.Error("writing memory profile", "file", memPath, "error", err)
+			}
+		})
+	}
+	return nil
+}
+
+// writeHeapProfile writes a heap profile of the live objects after a garbage
+// collection
+func writeHeapProfile(filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	runtime.GC()
+	if err := pprof.WriteHeapProfile(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
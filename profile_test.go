@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfiles(t *testing.T) {
+	dir := t.TempDir()
+	input := writeTemp(t, "transactions.csv", testCSV)
+	profiles := func(name string) {
+		t.Helper()
+		for _, file := range []string{name + ".cpu", name + ".mem"} {
+			info, err := os.Stat(filepath.Join(dir, file))
+			if err != nil {
+				t.Errorf("%s: %v", file, err)
+			} else if info.Size() == 0 {
+				t.Errorf("%s is empty", file)
+			}
+		}
+	}
+
+	_, stderr, status := runCLI(t, dir, "-input", input, "-cpuprofile", "ok.cpu", "-memprofile", "ok.mem")
+	if status != 0 {
+		t.Fatalf("exited %d: %s", status, stderr)
+	}
+	profiles("ok")
+
+	// Profiles are written when the run fails too
+	_, _, status = runCLI(t, dir, "-input", "missing.csv", "-cpuprofile", "failed.cpu", "-memprofile", "failed.mem")
+	if status != 1 {
+		t.Fatalf("missing input exited %d, want 1", status)
+	}
+	profiles("failed")
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// progressInterval is how often the progress line is redrawn
+const progressInterval = 500 * time.Millisecond
+
+// progress periodically reports rows read and batches processed on a single
+// redrawn line. A nil *progress is valid and reports nothing, so callers need
+// not check whether progress is enabled.
+type progress struct {
+	w            io.Writer
+	start        time.Time
+	rows         atomic.Int64
+	batchesDone  atomic.Int64
+	batchesTotal atomic.Int64
+	quit         chan struct{}
+	finished     chan struct{}
+}
+
+// startProgress begins reporting progress to w until stop is called
+func startProgress(w io.Writer) *progress {
+	p := &progress{
+		w:        w,
+		start:    time.Now(),
+		quit:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.finished)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.print()
+			case <-p.quit:
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// rowRead counts one transaction read
+func (p *progress) rowRead() {
+	if p != nil {
+		p.rows.Add(1)
+	}
+}
+
+// batchDone records detection progress; it matches fraud.Config.OnBatchDone
+func (p *progress) batchDone(done, total int) {
+	if p != nil {
+		p.batchesDone.Store(int64(done))
+		p.batchesTotal.Store(int64(total))
+	}
+}
+
+// stop prints the final progress line and ends the report. It is safe to
+// call more than once.
+func (p *progress) stop() {
+	if p == nil {
+		return
+	}
+	select {
+	case <-p.quit:
+		return
+	default:
+	}
+	close(p.quit)
+	<-p.finished
+	p.print()
+	fmt.Fprintln(p.w)
+}
+
+// print redraws the progress line
+func (p *progress) print() {
+	elapsed := time.Since(p.start)
+	rows := p.rows.Load()
+	rate := float64(rows) / elapsed.Seconds()
+
+	line := fmt.Sprintf("%d rows read (%.0f rows/s)", rows, rate)
+	if total := p.batchesTotal.Load(); total > 0 {
+		line += fmt.Sprintf(", %d/%d batches", p.batchesDone.Load(), total)
+	}
+	fmt.Fprintf(p.w, "\r%s, %s elapsed\x1b[K", line, elapsed.Round(time.Second))
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// redactVisible is how many trailing characters a masked value keeps
+const redactVisible = 4
+
+// redactor masks or hashes sensitive fields of results as they are shown or
+// exported. Detection, counts, and grouping always use the real values. A nil
+// redactor leaves values unchanged.
+type redactor struct {
+	account  bool
+	merchant bool
+	hash     bool // Replace values with a short SHA-256 digest instead of masking them
+}
+
+// parseRedactFields parses a comma-separated list of fields to redact:
+// account and merchant
+func parseRedactFields(value string, hash bool) (redactor, error) {
+	r := redactor{hash: hash}
+	for _, field := range strings.Split(value, ",") {
+		switch strings.ToLower(strings.TrimSpace(field)) {
+		case "account":
+			r.account = true
+		case "merchant":
+			r.merchant = true
+		default:
+			return r, fmt.Errorf("unknown field %q (use account or merchant)", field)
+		}
+	}
+	return r, nil
+}
+
+// redact returns the value masked to its last characters, e.g. "****1234",
+// or replaced by its digest
+func (r *redactor) redact(value string) string {
+	if r.hash {
+		sum := sha256.Sum256([]byte(value))
+		return hex.EncodeToString(sum[:6])
+	}
+
+	runes := []rune(value)
+	keep := redactVisible
+	if len(runes) <= keep {
+		keep = 0
+	}
+	return strings.Repeat("*", len(runes)-keep) + string(runes[len(runes)-keep:])
+}
+
+// shownAccount returns an account ID as shown
+func (r *redactor) shownAccount(id string) string {
+	if r == nil || !r.account || id == "" {
+		return id
+	}
+	return r.redact(id)
+}
+
+// shownMerchant returns a merchant name as shown
+func (r *redactor) shownMerchant(name string) string {
+	if r == nil || !r.merchant || name == "" {
+		return name
+	}
+	return r.redact(name)
+}
+
+// merchantReasons are the parts of built-in reasons that name the merchant.
+// Only these are redacted in a reason, so an amount or another word that
+// happens to contain the merchant's name is left alone.
+var merchantReasons = []string{
+	"Blocklisted merchant: %s",           // blocklist
+	" at %s within ",                     // duplicate, and its -explain detail
+	"First transaction with merchant %s", // new-merchant
+	"merchant %s not blocklisted",        // blocklist -explain detail
+	"merchant %s seen before ",           // new-merchant -explain detail
+}
+
+// result returns a copy of result with the redacted fields replaced, in its
+// transaction and where its reason names the merchant
+func (r *redactor) result(result fraud.FraudResult) fraud.FraudResult {
+	if r == nil {
+		return result
+	}
+	tx := &result.Transaction
+	if r.account && tx.AccountID != "" {
+		tx.AccountID = r.redact(tx.AccountID)
+	}
+	if r.merchant && tx.Merchant != "" {
+		result.Reason = r.reason(result.Reason, tx.Merchant)
+		tx.Merchant = r.redact(tx.Merchant)
+	}
+	return result
+}
+
+// reason returns reason with the places it names merchant redacted
+func (r *redactor) reason(reason, merchant string) string {
+	if r == nil || !r.merchant || merchant == "" {
+		return reason
+	}
+	redacted := r.redact(merchant)
+	for _, format := range merchantReasons {
+		reason = strings.ReplaceAll(reason, fmt.Sprintf(format, merchant), fmt.Sprintf(format, redacted))
+	}
+	return reason
+}
+
+// results returns redacted copies of the results
+func (r *redactor) results(results []fraud.FraudResult) []fraud.FraudResult {
+	if r == nil {
+		return results
+	}
+	redacted := make([]fraud.FraudResult, len(results))
+	for i, result := range results {
+		redacted[i] = r.result(result)
+	}
+	return redacted
+}
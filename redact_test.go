@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// redactTransactions are two accounts whose masked IDs are equal
+var redactTransactions = []fraud.Transaction{
+	{ID: "1", Amount: 5000, Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), AccountID: "A0001234", Merchant: "Corner Shop"},
+	{ID: "2", Amount: 6000, Timestamp: time.Date(2024, 1, 1, 11, 0, 0, 0, time.UTC), AccountID: "B0001234", Merchant: "Corner Shop"},
+}
+
+func TestRedactFormat(t *testing.T) {
+	tests := []struct {
+		name  string
+		r     redactor
+		value string
+		want  string
+	}{
+		{"mask", redactor{account: true}, "A0001234", "****1234"},
+		{"short value", redactor{account: true}, "1234", "****"},
+		{"hash", redactor{account: true, hash: true}, "A0001234", "b500da3e06ea"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.redact(tt.value); got != tt.want {
+				t.Errorf("redact(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactOutputKeepsDetection(t *testing.T) {
+	config := fraud.Config{HighAmountThreshold: 1000}
+	results := fraud.Detect(redactTransactions, config)
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+
+	redactions := &redactor{account: true, merchant: true}
+	for _, result := range redactions.results(results) {
+		if result.Transaction.AccountID != "****1234" {
+			t.Errorf("account shown as %q, want ****1234", result.Transaction.AccountID)
+		}
+		if result.Transaction.Merchant != "*******Shop" {
+			t.Errorf("merchant shown as %q, want *******Shop", result.Transaction.Merchant)
+		}
+	}
+	if results[0].Transaction.AccountID != "A0001234" {
+		t.Errorf("redaction changed the detected result's account to %q", results[0].Transaction.AccountID)
+	}
+
+	if stats := summarize(results, config, len(redactTransactions)); stats.Accounts != 2 {
+		t.Errorf("summary counts %d accounts, want 2", stats.Accounts)
+	}
+
+	var buf bytes.Buffer
+	if err := writeGroupedJSON(&buf, results, config, redactions); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "0001234") {
+		t.Errorf("grouped JSON shows a real account ID:\n%s", buf.String())
+	}
+	var grouped map[string][]fraud.FraudResult
+	if err := json.Unmarshal(buf.Bytes(), &grouped); err != nil {
+		t.Fatal(err)
+	}
+	if len(grouped) != 2 || len(grouped["****1234"]) != 1 || len(grouped["****1234 (2)"]) != 1 {
+		t.Errorf("grouped JSON keys do not keep the accounts apart: %v", grouped)
+	}
+}
+
+func TestRedactReasonOnlyWhereNamed(t *testing.T) {
+	result := fraud.FraudResult{
+		Transaction: fraud.Transaction{ID: "1", Amount: 1500, AccountID: "1", Merchant: "Shop"},
+		Reason:      "High amount: $1,500.00 exceeds $1,000.00; Round amount: $1,500.00 (multiple of 100); Blocklisted merchant: Shop",
+	}
+	redactions := &redactor{account: true, merchant: true}
+	shown := redactions.result(result)
+
+	want := "High amount: $1,500.00 exceeds $1,000.00; Round amount: $1,500.00 (multiple of 100); Blocklisted merchant: ****"
+	if shown.Reason != want {
+		t.Errorf("reason shown as %q, want %q", shown.Reason, want)
+	}
+	if shown.Transaction.AccountID != "*" || shown.Transaction.Merchant != "****" {
+		t.Errorf("shown as account %q, merchant %q; want *, ****", shown.Transaction.AccountID, shown.Transaction.Merchant)
+	}
+
+	result.Transaction.AccountID = "100"
+	result.Reason = "Duplicate charge: $100.00 at Shop within 2m0s"
+	want = "Duplicate charge: $100.00 at **** within 2m0s"
+	if shown := redactions.result(result); shown.Reason != want {
+		t.Errorf("reason shown as %q, want %q", shown.Reason, want)
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// isURL reports whether an input path is an HTTP or HTTPS URL
+func isURL(path string) bool {
+	return strings.HasPrefix(path, "http://") || strings.HasPrefix(path, "https://")
+}
+
+// inputPath returns the path part of a URL input, for matching its
+// extension, or the input path itself for files
+func inputPath(path string) string {
+	if !isURL(path) {
+		return path
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return path
+	}
+	return u.Path
+}
+
+// openURL fetches an input over HTTP(S) with the given request headers,
+// returning the response body. The request, body included, is abandoned
+// when ctx is done.
+func openURL(ctx context.Context, rawURL string, header http.Header) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = header.Clone()
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("fetching %s: %s", rawURL, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// headerList is a flag.Value collecting "Name: value" request headers from
+// repeated flags
+type headerList struct {
+	header http.Header
+}
+
+// String lists the header names only, since values are often credentials
+func (l *headerList) String() string {
+	if l == nil {
+		return ""
+	}
+	names := make([]string, 0, len(l.header))
+	for name := range l.header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+func (l *headerList) Set(value string) error {
+	name, v, ok := strings.Cut(value, ":")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return fmt.Errorf("expected \"Name: value\", got %q", value)
+	}
+	if l.header == nil {
+		l.header = make(http.Header)
+	}
+	l.header.Add(name, strings.TrimSpace(v))
+	return nil
+}
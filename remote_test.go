@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestReadURLCanceled(t *testing.T) {
+	stall := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("id,amount,timestamp,account_id,merchant\n"))
+		w.(http.Flusher).Flush()
+		<-stall
+	}))
+	defer srv.Close()
+	defer close(stall)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- streamTransactions(ctx, srv.URL+"/export.csv", "csv", false, nil, fraud.ReadOptions{}, func(fraud.Transaction) error { return nil })
+	}()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("error = %v, want the context's deadline", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("reading a stalled URL did not stop when the context was done")
+	}
+}
+
+func TestReadURL(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer TOKEN" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(testCSV))
+	}))
+	defer srv.Close()
+
+	headers := &headerList{}
+	if err := headers.Set("Authorization: Bearer TOKEN"); err != nil {
+		t.Fatal(err)
+	}
+	want := readInput(t, writeTemp(t, "transactions.csv", testCSV), "csv")
+
+	// The extension is taken from the URL path, ignoring the query
+	for _, fileType := range []string{"csv", "auto"} {
+		var got []fraud.Transaction
+		err := streamTransactions(context.Background(), srv.URL+"/export.csv?day=1", fileType, false, headers.header, fraud.ReadOptions{}, func(tx fraud.Transaction) error {
+			got = append(got, tx)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("-type %s: %v", fileType, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("-type %s read %+v, want %+v", fileType, got, want)
+		}
+	}
+
+	err := streamTransactions(context.Background(), srv.URL+"/export.csv", "csv", false, nil, fraud.ReadOptions{}, func(fraud.Transaction) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "401 Unauthorized") {
+		t.Errorf("unauthenticated error = %v, want a 401", err)
+	}
+}
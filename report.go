@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"html/template"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// reportTemplate is the standalone HTML report written by the html output
+// format. html/template escapes every field.
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Fraud Detection Report</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #222; }
+h1 { font-size: 1.5em; margin-bottom: 0.2em; }
+.generated { color: #666; margin-top: 0; }
+table { border-collapse: collapse; margin: 1em 0; }
+th, td { border: 1px solid #ddd; padding: 0.4em 0.8em; text-align: left; vertical-align: top; }
+th { background: #f4f4f4; }
+tr:nth-child(even) td { background: #fafafa; }
+td.amount { text-align: right; white-space: nowrap; }
+.stats td:first-child, .settings td:first-child { font-weight: 600; }
+details { margin: 1em 0; }
+</style>
+</head>
+<body>
+<h1>Fraud Detection Report</h1>
+<p class="generated">Generated {{.Generated.Format "2006-01-02 15:04:05 MST"}}</p>
+
+<h2>Summary</h2>
+<table class="stats">
+<tr><td>Transactions scanned</td><td>{{.Summary.Scanned}}</td></tr>
+<tr><td>Transactions flagged</td><td>{{.Summary.Flagged}}</td></tr>
+<tr><td>Amount flagged</td><td>{{.Summary.Amount}}</td></tr>
+<tr><td>Accounts involved</td><td>{{.Summary.Accounts}}</td></tr>
+{{- range .Summary.ByRule}}
+<tr><td>{{.Rule}}</td><td>{{.Count}}</td></tr>
+{{- end}}
+</table>
+
+<h2>Flagged Transactions</h2>
+{{- if .Rows}}
+<table class="results">
+<tr><th>ID</th><th>Account</th><th>Merchant</th><th>Amount</th><th>Timestamp</th><th>Severity</th><th>Reason</th></tr>
+{{- range .Rows}}
+<tr><td>{{.ID}}</td><td>{{.AccountID}}</td><td>{{.Merchant}}</td><td class="amount">{{.Amount}}</td><td>{{.Timestamp}}</td><td>{{.Severity}}</td><td>{{.Reason}}</td></tr>
+{{- end}}
+</table>
+{{- else}}
+<p>No fraudulent transactions detected.</p>
+{{- end}}
+
+<details>
+<summary>Settings</summary>
+<table class="settings">
+{{- range .Settings}}
+<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{- end}}
+</table>
+</details>
+</body>
+</html>
+`))
+
+// reportRow is a flagged transaction formatted for the HTML report
+type reportRow struct {
+	ID, AccountID, Merchant, Amount, Timestamp, Severity, Reason string
+}
+
+// reportSetting is a command line setting shown in the HTML report
+type reportSetting struct {
+	Name, Value string
+}
+
+// writeResultsHTML writes the fraud results as a standalone HTML page with
+// summary statistics, a results table, and the settings used
+func writeResultsHTML(w io.Writer, results []fraud.FraudResult, config fraud.Config, scanned int, generated time.Time, redactions *redactor) error {
+	rows := make([]reportRow, len(results))
+	for i, result := range redactions.results(results) {
+		tx := result.Transaction
+		rows[i] = reportRow{
+			ID:        tx.ID,
+			AccountID: tx.AccountID,
+			Merchant:  tx.Merchant,
+			Amount:    config.FormatAmount(config.CurrencyOf(tx), tx.Amount),
+			Timestamp: tx.Timestamp.Format(time.RFC3339),
+			Severity:  result.Severity.String(),
+			Reason:    result.Reason,
+		}
+	}
+
+	return reportTemplate.Execute(w, struct {
+		Generated time.Time
+		Summary   summary
+		Rows      []reportRow
+		Settings  []reportSetting
+	}{generated, summarize(results, config, scanned), rows, reportSettings(flag.CommandLine)})
+}
+
+// secretFlags are flags whose values may hold credentials, such as bearer
+// tokens in request headers or the token in a webhook URL
+var secretFlags = map[string]bool{"header": true, "webhook": true}
+
+// reportSettings lists the flags of fs for the HTML report, hiding the
+// values of secretFlags and the credentials and query of input URLs
+func reportSettings(fs *flag.FlagSet) []reportSetting {
+	var settings []reportSetting
+	fs.VisitAll(func(f *flag.Flag) {
+		value := f.Value.String()
+		switch {
+		case secretFlags[f.Name] && value != "":
+			value = "(hidden)"
+		case f.Name == "input":
+			paths := strings.Split(value, ",")
+			for i, path := range paths {
+				paths[i] = redactURL(path)
+			}
+			value = strings.Join(paths, ",")
+		}
+		settings = append(settings, reportSetting{f.Name, value})
+	})
+	return settings
+}
+
+// redactURL removes the user info and query, which may carry credentials,
+// from a URL input, leaving file paths unchanged
+func redactURL(path string) string {
+	if !isURL(path) {
+		return path
+	}
+	u, err := url.Parse(path)
+	if err != nil {
+		return "(hidden)"
+	}
+	u.User = nil
+	if u.RawQuery != "" {
+		u.RawQuery = "(hidden)"
+	}
+	return u.String()
+}
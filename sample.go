@@ -0,0 +1,34 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// sampleInput returns a random fraction of the transactions, rounded
+// up, chosen by reservoir sampling with rng. The sample keeps input order.
+func sampleInput(transactions []fraud.Transaction, fraction float64, rng *rand.Rand) []fraud.Transaction {
+	size := int(math.Ceil(fraction * float64(len(transactions))))
+	if size >= len(transactions) {
+		return transactions
+	}
+
+	reservoir := make([]int, size)
+	for i := range transactions {
+		if i < size {
+			reservoir[i] = i
+		} else if j := rng.Intn(i + 1); j < size {
+			reservoir[j] = i
+		}
+	}
+	slices.Sort(reservoir)
+
+	sample := make([]fraud.Transaction, size)
+	for i, index := range reservoir {
+		sample[i] = transactions[index]
+	}
+	return sample
+}
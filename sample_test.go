@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestSampleInput(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var transactions []fraud.Transaction
+	for i := 0; i < 1000; i++ {
+		transactions = append(transactions, fraud.Transaction{
+			ID:        fmt.Sprintf("%04d", i),
+			Amount:    10,
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+			AccountID: "A",
+			Merchant:  "Shop",
+		})
+	}
+	sample := func(fraction float64, seed int64) []string {
+		var ids []string
+		for _, tx := range sampleInput(transactions, fraction, rand.New(rand.NewSource(seed))) {
+			ids = append(ids, tx.ID)
+		}
+		return ids
+	}
+
+	// The fraction is rounded up, and the whole input is a sample of itself
+	for _, tt := range []struct {
+		fraction float64
+		want     int
+	}{{0.01, 10}, {0.0015, 2}, {0.5, 500}, {1, 1000}} {
+		if got := len(sample(tt.fraction, 1)); got != tt.want {
+			t.Errorf("sample of %v has %d transactions, want %d", tt.fraction, got, tt.want)
+		}
+	}
+
+	first := sample(0.01, 42)
+	if again := sample(0.01, 42); !reflect.DeepEqual(first, again) {
+		t.Errorf("seed 42 sampled %v, then %v", first, again)
+	}
+	if other := sample(0.01, 43); reflect.DeepEqual(first, other) {
+		t.Errorf("seeds 42 and 43 sampled the same %v", first)
+	}
+	// Samples keep input order and have no repeats
+	if !slices.IsSorted(first) || len(slices.Compact(slices.Clone(first))) != len(first) {
+		t.Errorf("sample %v is not in input order without repeats", first)
+	}
+}
+
+func TestSampleSummary(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("id,amount,timestamp,account_id,merchant\n")
+	for i := 0; i < 100; i++ {
+		fmt.Fprintf(&input, "%d,10.00,2024-01-01T10:00:00Z,A%d,Shop\n", i, i)
+	}
+	path := writeTemp(t, "transactions.csv", input.String())
+
+	stdout, stderr, status := runCLI(t, t.TempDir(), "-input", path, "-sample", "0.1", "-seed", "7")
+	if status != 0 {
+		t.Fatalf("exited %d: %s", status, stderr)
+	}
+	if !strings.Contains(stdout, "Sampled from:") || !strings.Contains(stdout, " 100 (results are from a random sample)") {
+		t.Errorf("summary does not note the sample:\n%s", stdout)
+	}
+	if !strings.Contains(stderr, "sample=10 of=100 seed=7") {
+		t.Errorf("log does not report the sample: %s", stderr)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// newServer returns the HTTP handler for -serve mode. POST /detect accepts a
+// JSON array of transactions and responds with the flagged results.
+// Detection stops when the client disconnects or, if timeout is positive,
+// when the timeout elapses. Requests with more than maxTransactions
+// transactions are rejected without reading the rest, unless it is 0. The
+// fields in redactions are masked in responses. GET /metrics serves
+// Prometheus metrics.
+func newServer(config fraud.Config, timeout time.Duration, maxTransactions int, redactions *redactor) http.Handler {
+	metrics := newServerMetrics()
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.handler())
+	mux.Handle("/detect", metrics.instrument("/detect", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var transactions []fraud.Transaction
+		err := fraud.StreamJSON(r.Body, limitTransactions(maxTransactions, func(tx fraud.Transaction) error {
+			transactions = append(transactions, tx)
+			return nil
+		}))
+		if errors.Is(err, errTooManyTransactions) {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid transactions: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		ctx := r.Context()
+		if timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, timeout)
+			defer cancel()
+		}
+
+		results, err := fraud.DetectContext(ctx, transactions, config)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("detection stopped: %v", err), http.StatusServiceUnavailable)
+			return
+		}
+		metrics.observe(len(transactions), results)
+		if results == nil {
+			results = []fraud.FraudResult{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(redactions.results(results))
+	})))
+	return mux
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestServerDetect(t *testing.T) {
+	config := fraud.Config{HighAmountThreshold: 1000}
+	srv := httptest.NewServer(newServer(config, 0, 0, nil))
+	defer srv.Close()
+
+	body := `[
+		{"id": "1", "amount": 5000, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A", "merchant": "Shop"},
+		{"id": "2", "amount": 10, "timestamp": "2024-01-01T11:00:00Z", "account_id": "B", "merchant": "Shop"}
+	]`
+	resp, err := http.Post(srv.URL+"/detect", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /detect: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /detect status = %d, want 200", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	var results []fraud.FraudResult
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(results) != 1 || results[0].Transaction.ID != "1" || !strings.Contains(results[0].Reason, "High amount") {
+		t.Errorf("results = %+v, want transaction 1 flagged for its high amount", results)
+	}
+}
+
+func TestServerDetectRedacted(t *testing.T) {
+	config := fraud.Config{HighAmountThreshold: 1000, MerchantBlocklist: map[string]bool{"corner shop": true}}
+	redactions := &redactor{account: true, merchant: true}
+	srv := httptest.NewServer(newServer(config, 0, 0, redactions))
+	defer srv.Close()
+
+	body := `[{"id": "1", "amount": 5000, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A0001234", "merchant": "Corner Shop"}]`
+	resp, err := http.Post(srv.URL+"/detect", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /detect: %v", err)
+	}
+	defer resp.Body.Close()
+	response, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading response: %v", err)
+	}
+	if strings.Contains(string(response), "0001234") || strings.Contains(string(response), "Corner") {
+		t.Errorf("response shows a real account or merchant:\n%s", response)
+	}
+
+	var results []fraud.FraudResult
+	if err := json.Unmarshal(response, &results); err != nil {
+		t.Fatalf("decoding response: %v", err)
+	}
+	if len(results) != 1 || results[0].Transaction.AccountID != "****1234" || results[0].Transaction.Merchant != "*******Shop" {
+		t.Errorf("results = %+v, want transaction 1 with its account and merchant masked", results)
+	}
+}
+
+func TestServerDetectErrors(t *testing.T) {
+	srv := httptest.NewServer(newServer(fraud.Config{}, 0, 0, nil))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/detect")
+	if err != nil {
+		t.Fatalf("GET /detect: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Errorf("GET /detect status = %d, want 405", resp.StatusCode)
+	}
+
+	resp, err = http.Post(srv.URL+"/detect", "application/json", strings.NewReader(`{"id": "1"}`))
+	if err != nil {
+		t.Fatalf("POST /detect: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("POST /detect of an object status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestServerMaxTransactions(t *testing.T) {
+	srv := httptest.NewServer(newServer(fraud.Config{}, 0, 1, nil))
+	defer srv.Close()
+
+	body := `[
+		{"id": "1", "amount": 10, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A", "merchant": "Shop"},
+		{"id": "2", "amount": 10, "timestamp": "2024-01-01T11:00:00Z", "account_id": "B", "merchant": "Shop"}
+	]`
+	resp, err := http.Post(srv.URL+"/detect", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /detect: %v", err)
+	}
+	defer resp.Body.Close()
+	message, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusRequestEntityTooLarge || !strings.Contains(string(message), "more than 1 (-max-transactions)") {
+		t.Errorf("POST /detect over the limit: status %d, body %q, want 413", resp.StatusCode, message)
+	}
+}
+
+func TestServerMetrics(t *testing.T) {
+	config := fraud.Config{HighAmountThreshold: 1000, TimeWindow: time.Minute}
+	srv := httptest.NewServer(newServer(config, 0, 0, nil))
+	defer srv.Close()
+
+	body := `[
+		{"id": "1", "amount": 5000, "timestamp": "2024-01-01T10:00:00Z", "account_id": "A", "merchant": "Shop"},
+		{"id": "2", "amount": 10, "timestamp": "2024-01-01T10:00:10Z", "account_id": "A", "merchant": "Shop"},
+		{"id": "3", "amount": 10, "timestamp": "2024-01-01T12:00:00Z", "account_id": "B", "merchant": "Shop"}
+	]`
+	resp, err := http.Post(srv.URL+"/detect", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /detect: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /detect status = %d, want 200", resp.StatusCode)
+	}
+
+	resp, err = http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics: %v", err)
+	}
+	defer resp.Body.Close()
+	metrics, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading /metrics: %v", err)
+	}
+
+	for _, want := range []string{
+		`fraud_detector_requests_total{code="200",handler="/detect",method="post"} 1`,
+		`fraud_detector_request_duration_seconds_count{code="200",handler="/detect",method="post"} 1`,
+		`fraud_detector_transactions_scored_total 3`,
+		`fraud_detector_flagged_total{rule="high-amount"} 1`,
+		`fraud_detector_flagged_total{rule="rapid-succession"} 2`,
+	} {
+		if !strings.Contains(string(metrics), want) {
+			t.Errorf("/metrics is missing %s", want)
+		}
+	}
+}
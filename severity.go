@@ -0,0 +1,74 @@
+package main
+
+import (
+	"math"
+	"sort"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// defaultSeverityWeight is the severity a rule adds when -severity-weights
+// does not list it
+const defaultSeverityWeight = 100
+
+// severity scores how serious a result is for -top. A high amount adds the
+// transaction's amount, so larger charges rank higher; every other rule
+// matched adds its weight.
+func severity(result fraud.FraudResult, weights map[string]float64) float64 {
+	score := 0.0
+	for _, rule := range result.Rules {
+		if rule == "high-amount" {
+			score += math.Abs(result.Transaction.Amount)
+		} else if weight, ok := weights[rule]; ok {
+			score += weight
+		} else {
+			score += defaultSeverityWeight
+		}
+	}
+	return score
+}
+
+// topResults returns the n most severe results, most severe first, keeping
+// the existing order among equally severe ones
+func topResults(results []fraud.FraudResult, n int, weights map[string]float64) []fraud.FraudResult {
+	scores := make([]float64, len(results))
+	for i, result := range results {
+		scores[i] = severity(result, weights)
+	}
+	return firstResults(results, n, func(a, b int) bool {
+		return scores[a] > scores[b]
+	})
+}
+
+// topResultsByScore returns the n riskiest results by score, highest first.
+// Among equal scores larger amounts come first, then the existing order.
+func topResultsByScore(results []fraud.FraudResult, n int) []fraud.FraudResult {
+	return firstResults(results, n, func(a, b int) bool {
+		ra, rb := results[a], results[b]
+		if ra.Score != rb.Score {
+			return ra.Score > rb.Score
+		}
+		return math.Abs(ra.Transaction.Amount) > math.Abs(rb.Transaction.Amount)
+	})
+}
+
+// firstResults returns the first n results when ordered by before, which
+// compares result indexes, leaving results itself unchanged
+func firstResults(results []fraud.FraudResult, n int, before func(a, b int) bool) []fraud.FraudResult {
+	order := make([]int, len(results))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return before(order[a], order[b])
+	})
+
+	if n > len(order) {
+		n = len(order)
+	}
+	top := make([]fraud.FraudResult, n)
+	for i := range top {
+		top[i] = results[order[i]]
+	}
+	return top
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestTopResults(t *testing.T) {
+	flagged := func(id string, amount float64, reason string, rules ...string) fraud.FraudResult {
+		return fraud.FraudResult{Transaction: fraud.Transaction{ID: id, Amount: amount}, Reason: reason, Rules: rules}
+	}
+	results := []fraud.FraudResult{
+		flagged("0", 2000, "High amount: $2000.00", "high-amount"),
+		flagged("1", 10, "Velocity: 6 transactions within 1h", "velocity"),
+		flagged("2", 10, "Blocklisted merchant: Casino", "blocklist"),
+		flagged("3", 10, "Rapid: 1 related transaction within 5m", "rapid-succession"),
+		flagged("4", 3000, "High amount: $3000.00; Rapid: 1 related transaction within 5m", "high-amount", "rapid-succession"),
+		flagged("5", 10, "Duplicate charge: $10.00 at Shop within 1m0s", "duplicate"),
+	}
+	original := slices.Clone(results)
+	weights := map[string]float64{"blocklist": 5000, "velocity": 50}
+
+	ids := func(results []fraud.FraudResult) []string {
+		var ids []string
+		for _, result := range results {
+			ids = append(ids, result.Transaction.ID)
+		}
+		return ids
+	}
+	if got, want := ids(topResults(results, 3, weights)), []string{"2", "4", "0"}; !slices.Equal(got, want) {
+		t.Errorf("top 3 = %v, want %v", got, want)
+	}
+	// Equally severe results keep their order, and n may exceed the results
+	if got, want := ids(topResults(results, 10, weights)), []string{"2", "4", "0", "3", "5", "1"}; !slices.Equal(got, want) {
+		t.Errorf("top 10 = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(results, original) {
+		t.Error("topResults reordered its input")
+	}
+}
+
+func TestTopResultsByScore(t *testing.T) {
+	results := []fraud.FraudResult{
+		{Transaction: fraud.Transaction{ID: "low", Amount: 50000}, Score: 10},
+		{Transaction: fraud.Transaction{ID: "small", Amount: 10}, Score: 50},
+		{Transaction: fraud.Transaction{ID: "refund", Amount: -900}, Score: 50},
+		{Transaction: fraud.Transaction{ID: "high", Amount: 20}, Score: 80},
+	}
+
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{2, []string{"high", "refund"}},
+		{3, []string{"high", "refund", "small"}},
+		{10, []string{"high", "refund", "small", "low"}},
+	}
+	for _, tt := range tests {
+		var ids []string
+		for _, result := range topResultsByScore(results, tt.n) {
+			ids = append(ids, result.Transaction.ID)
+		}
+		if !slices.Equal(ids, tt.want) {
+			t.Errorf("topResultsByScore(%d) = %v, want %v", tt.n, ids, tt.want)
+		}
+	}
+}
+
+func TestTopResultsByScoreFollowsRiskWeights(t *testing.T) {
+	config := fraud.Config{
+		HighAmountThreshold: 1000,
+		MerchantBlocklist:   map[string]bool{"casino": true},
+		RuleWeights:         map[string]float64{"blocklist": 90, "high-amount": 5},
+	}
+	transactions := []fraud.Transaction{
+		{ID: "big", Amount: 9000, AccountID: "A", Merchant: "Shop"},
+		{ID: "casino", Amount: 10, AccountID: "B", Merchant: "Casino"},
+	}
+
+	top := topResultsByScore(fraud.Detect(transactions, config), 1)
+	if len(top) != 1 || top[0].Transaction.ID != "casino" {
+		t.Errorf("top result = %v, want casino", top)
+	}
+}
+
+func TestTopThreeOfManyByScore(t *testing.T) {
+	var results []fraud.FraudResult
+	for i, score := range []float64{20, 90, 10, 40, 90, 70, 30, 60, 50, 80} {
+		results = append(results, fraud.FraudResult{
+			Transaction: fraud.Transaction{ID: fmt.Sprint(i), Amount: float64(100 * i)},
+			Score:       score,
+		})
+	}
+	original := slices.Clone(results)
+
+	var ids []string
+	for _, result := range topResultsByScore(results, 3) {
+		ids = append(ids, result.Transaction.ID)
+	}
+	// 1 and 4 tie on score, so the larger amount ranks first
+	if want := []string{"4", "1", "9"}; !slices.Equal(ids, want) {
+		t.Errorf("top 3 = %v, want %v", ids, want)
+	}
+	if !reflect.DeepEqual(results, original) {
+		t.Error("topResultsByScore reordered its input")
+	}
+}
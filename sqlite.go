@@ -0,0 +1,128 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// createResultsTable creates the table that writeResultsSQLite inserts into,
+// as first released; resultsMigrations add the later columns
+const createResultsTable = `CREATE TABLE IF NOT EXISTS fraud_results (
+	id         TEXT NOT NULL,
+	account_id TEXT NOT NULL,
+	merchant   TEXT NOT NULL,
+	amount     REAL NOT NULL,
+	currency   TEXT NOT NULL,
+	timestamp  TEXT NOT NULL,
+	latitude   REAL,
+	longitude  REAL,
+	category   TEXT,
+	mcc        TEXT,
+	reason     TEXT NOT NULL,
+	run_at     TEXT NOT NULL
+)`
+
+// resultsMigrations update the fraud_results table, in order. The database's
+// user_version is the number applied, so each runs once per database,
+// whether it was created by this version or an earlier one.
+var resultsMigrations = []string{
+	`ALTER TABLE fraud_results ADD COLUMN severity TEXT`,
+	`ALTER TABLE fraud_results ADD COLUMN score REAL`,
+	`ALTER TABLE fraud_results ADD COLUMN device_id TEXT`,
+	`ALTER TABLE fraud_results ADD COLUMN card_id TEXT`,
+	`ALTER TABLE fraud_results ADD COLUMN country TEXT`,
+}
+
+// migrateResultsTable creates the fraud_results table when missing and
+// applies the migrations it lacks
+func migrateResultsTable(tx *sql.Tx) error {
+	if _, err := tx.Exec(createResultsTable); err != nil {
+		return err
+	}
+
+	var version int
+	if err := tx.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version > len(resultsMigrations) {
+		return fmt.Errorf("database schema version %d is newer than this program's (%d)", version, len(resultsMigrations))
+	}
+	for _, migration := range resultsMigrations[version:] {
+		if _, err := tx.Exec(migration); err != nil {
+			return err
+		}
+	}
+	// PRAGMA does not take parameters
+	_, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, len(resultsMigrations)))
+	return err
+}
+
+// writeResultsSQLite inserts the fraud results into the fraud_results table
+// of a SQLite database, creating both when missing. Every row of a run shares
+// its run_at time, and the rows are inserted in one transaction so a failed
+// run leaves no partial results.
+func writeResultsSQLite(dbPath string, results []fraud.FraudResult, config fraud.Config, runAt time.Time) error {
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := migrateResultsTable(tx); err != nil {
+		return err
+	}
+
+	insert, err := tx.Prepare(`INSERT INTO fraud_results
+		(id, account_id, merchant, amount, currency, timestamp, latitude, longitude, category, mcc,
+		device_id, card_id, country, severity, score, reason, run_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer insert.Close()
+
+	run := runAt.UTC().Format(time.RFC3339)
+	for _, result := range results {
+		t := result.Transaction
+		_, err := insert.Exec(
+			t.ID,
+			t.AccountID,
+			t.Merchant,
+			t.Amount,
+			config.CurrencyOf(t).Code,
+			t.Timestamp.Format(time.RFC3339),
+			t.Latitude,
+			t.Longitude,
+			nullString(t.Category),
+			nullString(t.MCC),
+			nullString(t.DeviceID),
+			nullString(t.CardID),
+			nullString(t.Country),
+			result.Severity.String(),
+			result.Score,
+			result.Reason,
+			run,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// nullString stores an empty string as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
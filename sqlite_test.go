@@ -0,0 +1,101 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func sqliteResults() []fraud.FraudResult {
+	return []fraud.FraudResult{{
+		Transaction: fraud.Transaction{
+			ID:        "1",
+			Amount:    5000,
+			Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			AccountID: "A",
+			Merchant:  "Shop",
+			DeviceID:  "dev-1",
+			CardID:    "card-1",
+			Country:   "FR",
+		},
+		Reason:   "High amount: $5000.00",
+		Severity: fraud.SeverityHigh,
+		Score:    50,
+		Rules:    []string{"high-amount"},
+	}}
+}
+
+// checkSQLiteRow checks the newest row for transaction 1 in the database
+func checkSQLiteRow(t *testing.T, db *sql.DB) {
+	t.Helper()
+	var severity, deviceID, cardID, country string
+	var score float64
+	err := db.QueryRow(`SELECT severity, score, device_id, card_id, country FROM fraud_results
+		WHERE id = '1' AND severity IS NOT NULL ORDER BY rowid DESC LIMIT 1`).
+		Scan(&severity, &score, &deviceID, &cardID, &country)
+	if err != nil {
+		t.Fatalf("querying results: %v", err)
+	}
+	if severity != "high" || score != 50 || deviceID != "dev-1" || cardID != "card-1" || country != "FR" {
+		t.Errorf("row = %s, %v, %s, %s, %s; want high, 50, dev-1, card-1, FR", severity, score, deviceID, cardID, country)
+	}
+}
+
+func TestWriteResultsSQLite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.db")
+	for i := 0; i < 2; i++ {
+		if err := writeResultsSQLite(path, sqliteResults(), fraud.Config{}, time.Now()); err != nil {
+			t.Fatalf("run %d: writeResultsSQLite: %v", i+1, err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	checkSQLiteRow(t, db)
+
+	var rows, version int
+	db.QueryRow(`SELECT count(*) FROM fraud_results`).Scan(&rows)
+	db.QueryRow(`PRAGMA user_version`).Scan(&version)
+	if rows != 2 || version != len(resultsMigrations) {
+		t.Errorf("got %d rows at schema version %d, want 2 at %d", rows, version, len(resultsMigrations))
+	}
+}
+
+func TestWriteResultsSQLiteMigrates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "flags.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// A database written before the severity and other columns were added
+	if _, err := db.Exec(createResultsTable); err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`INSERT INTO fraud_results (id, account_id, merchant, amount, currency, timestamp, reason, run_at)
+		VALUES ('0', 'A', 'Shop', 10, 'USD', '2023-12-31T10:00:00Z', 'Old reason', '2024-01-01T00:00:00Z')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeResultsSQLite(path, sqliteResults(), fraud.Config{}, time.Now()); err != nil {
+		t.Fatalf("writeResultsSQLite: %v", err)
+	}
+	checkSQLiteRow(t, db)
+
+	var reason string
+	var severity sql.NullString
+	if err := db.QueryRow(`SELECT reason, severity FROM fraud_results WHERE id = '0'`).Scan(&reason, &severity); err != nil {
+		t.Fatalf("querying old row: %v", err)
+	}
+	if reason != "Old reason" || severity.Valid {
+		t.Errorf("old row = %q, %v; want its reason and no severity", reason, severity)
+	}
+}
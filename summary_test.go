@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+func TestSummarizeByRuleWithReasonTemplate(t *testing.T) {
+	tmpl, err := fraud.ParseReasonTemplate("high-amount", "AMT {{.Amount}}")
+	if err != nil {
+		t.Fatalf("ParseReasonTemplate: %v", err)
+	}
+	config := fraud.Config{
+		HighAmountThreshold: 1000,
+		TimeWindow:          time.Minute,
+		ReasonTemplates:     map[string]*template.Template{"high-amount": tmpl},
+	}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	transactions := []fraud.Transaction{
+		{ID: "1", Amount: 5000, Timestamp: start, AccountID: "A", Merchant: "Shop"},
+		{ID: "2", Amount: 10, Timestamp: start.Add(10 * time.Second), AccountID: "A", Merchant: "Shop"},
+	}
+
+	stats := summarize(fraud.Detect(transactions, config), config, len(transactions))
+	counts := make(map[string]int)
+	for _, rc := range stats.ByRule {
+		counts[rc.Rule] = rc.Count
+	}
+	if counts["high-amount"] != 1 || counts["rapid-succession"] != 2 || len(counts) != 2 {
+		t.Errorf("by rule = %v, want high-amount: 1, rapid-succession: 2", stats.ByRule)
+	}
+}
+
+func TestRowColor(t *testing.T) {
+	tests := []struct {
+		rules []string
+		want  int
+	}{
+		{nil, 0},
+		{[]string{"off-hours"}, tablewriter.FgCyanColor},
+		{[]string{"off-hours", "high-amount"}, tablewriter.FgRedColor},
+		{[]string{"custom"}, 0},
+	}
+	for _, tt := range tests {
+		if got := rowColor(tt.rules); got != tt.want {
+			t.Errorf("rowColor(%v) = %d, want %d", tt.rules, got, tt.want)
+		}
+	}
+}
+
+func TestRankMerchants(t *testing.T) {
+	flagged := func(id, merchant string, amount float64) fraud.FraudResult {
+		return fraud.FraudResult{Transaction: fraud.Transaction{ID: id, Amount: amount, AccountID: "A", Merchant: merchant}}
+	}
+	results := []fraud.FraudResult{
+		flagged("1", "Shop", 100),
+		flagged("2", "ATM", 9000),
+		flagged("3", "Casino", 2000),
+		flagged("4", " shop", 200), // The same merchant as Shop
+		flagged("5", "Casino", 3000),
+	}
+
+	var got []string
+	for _, m := range rankMerchants(results, fraud.Config{}) {
+		got = append(got, fmt.Sprintf("%s %d %s", m.Merchant, m.Count, formatTotals(m.Amounts, fraud.Config{})))
+	}
+	want := []string{"Casino 2 $5000.00", "Shop 2 $300.00", "ATM 1 $9000.00"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ranking = %q, want %q", got, want)
+	}
+}
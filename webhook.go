@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// webhookBatchSize is the most results posted to a webhook in one request
+const webhookBatchSize = 100
+
+// webhookAttempts is how many times a batch is posted before giving up
+const webhookAttempts = 4
+
+// webhookBackoff is the delay before the first retry, doubled for each
+// later one
+var webhookBackoff = time.Second
+
+// postWebhook posts the results to url as JSON arrays of up to
+// webhookBatchSize results. Batches that fail with a network error or a 5xx
+// response are retried with exponential backoff. It returns how many results
+// were delivered and the first error of a batch that could not be.
+func postWebhook(url string, results []fraud.FraudResult, timeout time.Duration) (int, error) {
+	client := &http.Client{Timeout: timeout}
+
+	delivered := 0
+	var firstErr error
+	for start := 0; start < len(results); start += webhookBatchSize {
+		batch := results[start:min(start+webhookBatchSize, len(results))]
+		body, err := json.Marshal(batch)
+		if err != nil {
+			return delivered, err
+		}
+
+		if err := postWithRetry(client, url, body); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		delivered += len(batch)
+	}
+	return delivered, firstErr
+}
+
+// postWithRetry posts body to url, retrying network errors and 5xx
+// responses. Other error responses are not retried.
+func postWithRetry(client *http.Client, url string, body []byte) error {
+	backoff := webhookBackoff
+	var err error
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		if attempt > 1 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		var resp *http.Response
+		resp, err = client.Post(url, "application/json", bytes.NewReader(body))
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+
+		switch {
+		case resp.StatusCode < 300:
+			return nil
+		case resp.StatusCode >= 500:
+			err = fmt.Errorf("webhook responded %s", resp.Status)
+		default:
+			return fmt.Errorf("webhook responded %s", resp.Status)
+		}
+	}
+	return fmt.Errorf("after %d attempts: %w", webhookAttempts, err)
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go-frauddetector-cli/pkg/fraud"
+)
+
+// webhookRecorder is a webhook endpoint recording the batches posted to it,
+// failing the first requests with the given statuses
+type webhookRecorder struct {
+	mu       sync.Mutex
+	statuses []int
+	requests int
+	batches  [][]fraud.FraudResult
+}
+
+func (rec *webhookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.requests++
+	if len(rec.statuses) > 0 {
+		status := rec.statuses[0]
+		rec.statuses = rec.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+
+	var batch []fraud.FraudResult
+	if r.Header.Get("Content-Type") != "application/json" || json.NewDecoder(r.Body).Decode(&batch) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	rec.batches = append(rec.batches, batch)
+}
+
+func fastWebhookRetries(t *testing.T) {
+	saved := webhookBackoff
+	webhookBackoff = time.Millisecond
+	t.Cleanup(func() { webhookBackoff = saved })
+}
+
+func TestPostWebhook(t *testing.T) {
+	fastWebhookRetries(t)
+	rec := &webhookRecorder{}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+
+	results := flaggedResults(webhookBatchSize + 50)
+	delivered, err := postWebhook(srv.URL, results, time.Second)
+	if err != nil || delivered != len(results) {
+		t.Fatalf("postWebhook = %d, %v; want %d delivered", delivered, err, len(results))
+	}
+	if len(rec.batches) != 2 || len(rec.batches[0]) != webhookBatchSize || len(rec.batches[1]) != 50 {
+		t.Fatalf("got %d batches, want 2 of %d and 50", len(rec.batches), webhookBatchSize)
+	}
+	got := rec.batches[1][0]
+	if want := results[webhookBatchSize]; got.Transaction.ID != want.Transaction.ID || got.Reason != want.Reason || got.Severity != want.Severity {
+		t.Errorf("posted %+v, want %+v", got, want)
+	}
+}
+
+func TestPostWebhookRetries(t *testing.T) {
+	fastWebhookRetries(t)
+
+	// Server errors are retried until the batch is accepted
+	rec := &webhookRecorder{statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway}}
+	srv := httptest.NewServer(rec)
+	defer srv.Close()
+	if delivered, err := postWebhook(srv.URL, flaggedResults(3), time.Second); err != nil || delivered != 3 {
+		t.Errorf("after two 5xx responses: postWebhook = %d, %v; want 3 delivered", delivered, err)
+	}
+	if rec.requests != 3 {
+		t.Errorf("made %d requests, want 3", rec.requests)
+	}
+
+	// Client errors are not
+	rec = &webhookRecorder{statuses: []int{http.StatusForbidden}}
+	srv = httptest.NewServer(rec)
+	defer srv.Close()
+	delivered, err := postWebhook(srv.URL, flaggedResults(3), time.Second)
+	if delivered != 0 || err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("after a 403: postWebhook = %d, %v; want a 403 error", delivered, err)
+	}
+	if rec.requests != 1 {
+		t.Errorf("made %d requests after a 403, want 1", rec.requests)
+	}
+}